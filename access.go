@@ -0,0 +1,85 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// accessClient translates permission errors from the wrapped client into
+// ErrAccessDenied, naming the operation and the key it was denied on.
+type accessClient struct {
+	S3Client
+}
+
+// accessError wraps err in ErrAccessDenied if S3 refused the request for lack
+// of permission, and returns it unchanged otherwise.
+func accessError(op string, bucket, key *string, err error) error {
+	var apiErr smithy.APIError
+	var respErr interface{ HTTPStatusCode() int }
+	if !(errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied") &&
+		!(errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusForbidden) {
+		return err
+	}
+	return fmt.Errorf("%w: %s on %s/%s: %w", ErrAccessDenied, op, aws.ToString(bucket), aws.ToString(key), err)
+}
+
+func (c accessClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	output, err := c.S3Client.PutObject(ctx, params, optFns...)
+	return output, accessError("PutObject", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	output, err := c.S3Client.GetObject(ctx, params, optFns...)
+	return output, accessError("GetObject", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	output, err := c.S3Client.HeadObject(ctx, params, optFns...)
+	return output, accessError("HeadObject", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	output, err := c.S3Client.ListObjectsV2(ctx, params, optFns...)
+	return output, accessError("ListObjectsV2", params.Bucket, params.Prefix, err)
+}
+
+func (c accessClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	output, err := c.S3Client.CreateMultipartUpload(ctx, params, optFns...)
+	return output, accessError("CreateMultipartUpload", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	output, err := c.S3Client.UploadPart(ctx, params, optFns...)
+	return output, accessError("UploadPart", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	output, err := c.S3Client.CompleteMultipartUpload(ctx, params, optFns...)
+	return output, accessError("CompleteMultipartUpload", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	output, err := c.S3Client.AbortMultipartUpload(ctx, params, optFns...)
+	return output, accessError("AbortMultipartUpload", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	output, err := c.S3Client.CopyObject(ctx, params, optFns...)
+	return output, accessError("CopyObject", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	output, err := c.S3Client.DeleteObject(ctx, params, optFns...)
+	return output, accessError("DeleteObject", params.Bucket, params.Key, err)
+}
+
+func (c accessClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	output, err := c.S3Client.DeleteObjects(ctx, params, optFns...)
+	return output, accessError("DeleteObjects", params.Bucket, nil, err)
+}
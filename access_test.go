@@ -0,0 +1,42 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestAccessDenied(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Read(ctx, 1); err == nil || errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected a not-found error for a missing record, got %v", err)
+	}
+
+	client := s3.NewFromConfig(aws.Config{Region: "us-east-1"}, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String("http://127.0.0.1:9000")
+		o.Credentials = credentials.NewStaticCredentialsProvider("minioadmin", "wrong-secret", "")
+	})
+	denied := NewS3WAL(client, wal.bucketName, wal.prefix)
+
+	_, err := denied.Append(ctx, []byte("denied"))
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected ErrAccessDenied on append, got %v", err)
+	}
+	if key := denied.getObjectKey(1); !strings.Contains(err.Error(), key) {
+		t.Errorf("expected error to name key %s, got %v", key, err)
+	}
+	if _, err := denied.Read(ctx, 1); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied on read, got %v", err)
+	}
+	if _, err := ListWALs(ctx, client, wal.bucketName, ""); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied on ListWALs, got %v", err)
+	}
+}
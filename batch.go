@@ -0,0 +1,292 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// MaxMicroBatchRecords is the largest number of records a single micro-batch
+// object may hold. It matches the page size of ListObjectsV2 so that a single
+// list request is always enough to locate the batch containing an offset.
+const MaxMicroBatchRecords = 1000
+
+// metaMicroBatch is the user metadata key marking an object as a micro-batch.
+// Its value is the number of records in the batch.
+const metaMicroBatch = "s3log-micro-batch"
+
+// batchIndexEntryLen is the size of one offset->byteRange index entry:
+// offset, start and length, each a big-endian uint64.
+const batchIndexEntryLen = 24
+
+// encodeMicroBatch lays out the records back to back, followed by an index
+// mapping each offset to its byte range and a trailing uint32 record count.
+func encodeMicroBatch(first uint64, records [][]byte) []byte {
+	size := 4 + len(records)*batchIndexEntryLen
+	for _, data := range records {
+		size += len(data)
+	}
+	buf := make([]byte, 0, size)
+	for _, data := range records {
+		buf = append(buf, data...)
+	}
+	var start uint64
+	for i, data := range records {
+		buf = binary.BigEndian.AppendUint64(buf, first+uint64(i))
+		buf = binary.BigEndian.AppendUint64(buf, start)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(len(data)))
+		start += uint64(len(data))
+	}
+	return binary.BigEndian.AppendUint32(buf, uint32(len(records)))
+}
+
+func decodeMicroBatch(first uint64, payload []byte) ([]Record, error) {
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("%w: micro-batch is missing its record count", ErrInvalidRecord)
+	}
+	count := uint64(binary.BigEndian.Uint32(payload[len(payload)-4:]))
+	if count == 0 || count > MaxMicroBatchRecords {
+		return nil, fmt.Errorf("%w: micro-batch has bad record count %d", ErrInvalidRecord, count)
+	}
+	indexLen := count * batchIndexEntryLen
+	if uint64(len(payload)-4) < indexLen {
+		return nil, fmt.Errorf("%w: micro-batch index is truncated", ErrInvalidRecord)
+	}
+	dataLen := uint64(len(payload)-4) - indexLen
+	index := payload[dataLen : len(payload)-4]
+
+	records := make([]Record, count)
+	for i := range records {
+		entry := index[uint64(i)*batchIndexEntryLen:]
+		offset := binary.BigEndian.Uint64(entry[0:8])
+		start := binary.BigEndian.Uint64(entry[8:16])
+		length := binary.BigEndian.Uint64(entry[16:24])
+		if offset != first+uint64(i) {
+			return nil, fmt.Errorf("%w: micro-batch has unexpected offset %d at index %d", ErrInvalidRecord, offset, i)
+		}
+		if start > dataLen || length > dataLen-start {
+			return nil, fmt.Errorf("%w: micro-batch byte range is out of bounds for offset %d", ErrInvalidRecord, offset)
+		}
+		records[i] = Record{
+			Offset: offset,
+			Data:   payload[start : start+length],
+		}
+	}
+	return records, nil
+}
+
+// AppendMicroBatch writes up to MaxMicroBatchRecords records as a single S3
+// object keyed by the first offset, and returns the offsets assigned to them.
+//
+// Read maps an arbitrary offset to its batch object as follows: it first tries
+// the offset's own key, and on NoSuchKey lists at most MaxMicroBatchRecords
+// keys starting just after offset-MaxMicroBatchRecords. The greatest key below
+// the offset is the only object that can contain it; once a HEAD has shown
+// that it does, it is downloaded and the record is extracted using the index
+// at the end of the object. The first micro-batch of a log also writes a
+// marker object under its prefix, and logs without one skip the listing.
+//
+// The object is framed like a single record: WithTimestamps stamps it, and
+// every record of the batch reads back with that timestamp, and WithChecksum
+// and ChecksumLeading apply to its checksum. It keeps its offset header even
+// with WithoutOffsetHeader. Each record counts as one append in the metrics,
+// and the object is passed to the append sink. With WithHashChain it fails,
+// since a micro-batch cannot link its records into the chain.
+func (w *S3WAL) AppendMicroBatch(ctx context.Context, records [][]byte) ([]uint64, error) {
+	start := time.Now()
+	offsets, err := w.appendMicroBatch(ctx, records)
+	for range records {
+		w.observe(MetricAppends, MetricAppendErrors, MetricAppendLatency, start, err)
+	}
+	if len(offsets) > 0 {
+		w.evictAfterAppend(ctx)
+	}
+	return offsets, err
+}
+
+func (w *S3WAL) appendMicroBatch(ctx context.Context, records [][]byte) ([]uint64, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("micro-batch must contain at least one record")
+	}
+	if len(records) > MaxMicroBatchRecords {
+		return nil, fmt.Errorf("micro-batch of %d records exceeds the maximum of %d", len(records), MaxMicroBatchRecords)
+	}
+	if err := w.checkOpen(); err != nil {
+		return nil, err
+	}
+	if err := w.checkSchema(""); err != nil {
+		return nil, err
+	}
+	if w.hashChain {
+		return nil, fmt.Errorf("AppendMicroBatch cannot link records into a hash chain")
+	}
+	for i, data := range records {
+		if err := w.checkSize(len(data)); err != nil {
+			return nil, fmt.Errorf("record %d of micro-batch: %w", i, err)
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkOpen(); err != nil {
+		return nil, err
+	}
+	if w.spillPending() {
+		return nil, fmt.Errorf("%w: drain them before appending a micro-batch", ErrSpillPending)
+	}
+	first, err := w.nextOffset()
+	if err != nil {
+		return nil, err
+	}
+	h := frameHeader{offset: first, leadingChecksum: w.leadingChecksum, sum: w.checksummer}
+	if w.timestamps {
+		h.timestamp = w.now()
+	}
+
+	buf, err := encodeFrame(h, encodeMicroBatch(first, records))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare object body: %w", err)
+	}
+
+	if err := w.markMicroBatches(ctx); err != nil {
+		return nil, err
+	}
+	key := w.getObjectKey(first)
+	ifNoneMatch, err := w.ifNoneMatch(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(w.bucketName),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(buf),
+		IfNoneMatch: ifNoneMatch,
+		Metadata:    map[string]string{metaMicroBatch: strconv.Itoa(len(records))},
+	}
+	w.applyPutOptions(input)
+
+	if _, err = w.client.PutObject(ctx, input); err != nil {
+		return nil, fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	w.noteStored(first, int64(len(buf)))
+	offsets := make([]uint64, len(records))
+	for i := range offsets {
+		offsets[i] = first + uint64(i)
+	}
+	w.length = offsets[len(offsets)-1]
+	w.updateIndex(ctx, w.length)
+	w.bloomAdd(first, w.length)
+	if err := w.ship(ctx, first, buf); err != nil {
+		return offsets, err
+	}
+	return offsets, nil
+}
+
+// AppendTransaction appends records so that either all of them become
+// visible or none do. They are written as one micro-batch object with a
+// single PutObject, which S3 applies atomically, so readers never observe a
+// partial transaction: until the put succeeds none of the offsets can be
+// read, and afterwards all of them can. The limits of AppendMicroBatch apply.
+func (w *S3WAL) AppendTransaction(ctx context.Context, records [][]byte) ([]uint64, error) {
+	return w.AppendMicroBatch(ctx, records)
+}
+
+// microBatchMarker is the name of the object marking a log as holding
+// micro-batches, stored under the log prefix. It does not end in digits, so
+// listings never take it for a record.
+const microBatchMarker = "micro-batches"
+
+func (w *S3WAL) microBatchKey() string {
+	return w.keyPrefix() + microBatchMarker
+}
+
+// markMicroBatches writes the micro-batch marker before the first micro-batch
+// of the log is written, so that findMicroBatch never misses one.
+func (w *S3WAL) markMicroBatches(ctx context.Context) error {
+	if w.microBatches.Load() {
+		return nil
+	}
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.microBatchKey()),
+		Body:   bytes.NewReader(nil),
+	}
+	if !w.noPreconditions {
+		input.IfNoneMatch = aws.String("*")
+	}
+	w.applyPutOptions(input)
+	if _, err := w.client.PutObject(ctx, input); err != nil && !isPreconditionFailed(err) {
+		return fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	w.microBatches.Store(true)
+	return nil
+}
+
+// hasMicroBatches reports whether micro-batches have been written to the log.
+// Once the marker has been found, later calls return true without a request.
+func (w *S3WAL) hasMicroBatches(ctx context.Context) (bool, error) {
+	if w.microBatches.Load() {
+		return true, nil
+	}
+	exists, err := w.objectExists(ctx, w.microBatchKey())
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		w.microBatches.Store(true)
+	}
+	return exists, nil
+}
+
+// findMicroBatch returns the first offset of the micro-batch containing
+// offset, if any. It costs a single HEAD on logs without micro-batches;
+// otherwise the object before offset is found by listing and checked with a
+// HEAD, so that nothing is downloaded unless it holds offset.
+func (w *S3WAL) findMicroBatch(ctx context.Context, offset uint64) (uint64, bool, error) {
+	if has, err := w.hasMicroBatches(ctx); err != nil || !has {
+		return 0, false, err
+	}
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(w.bucketName),
+		Prefix:  aws.String(w.keyPrefix()),
+		MaxKeys: aws.Int32(MaxMicroBatchRecords),
+	}
+	if offset > MaxMicroBatchRecords {
+		input.StartAfter = aws.String(w.getObjectKey(offset - MaxMicroBatchRecords))
+	}
+	output, err := w.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to list objects from s3: %w", err)
+	}
+	var first uint64
+	var found bool
+	for _, obj := range output.Contents {
+		candidate, err := w.getOffsetFromKey(*obj.Key)
+		if err != nil {
+			continue
+		}
+		if candidate >= offset {
+			break
+		}
+		first, found = candidate, true
+	}
+	if !found {
+		return 0, false, nil
+	}
+	end, err := w.objectEnd(ctx, first)
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return first, end >= offset, nil
+}
@@ -0,0 +1,230 @@
+package s3log
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultAppendConcurrency is the number of PutObject requests BatchAppend
+// keeps in flight without WithConcurrency.
+const defaultAppendConcurrency = 8
+
+// errBatchAborted is reported for the records of a batch that were not
+// uploaded because an earlier upload failed.
+var errBatchAborted = errors.New("batch aborted after an earlier failure")
+
+// WithConcurrency sets the number of PutObject requests BatchAppend keeps in
+// flight at once. It panics if n is not positive.
+func WithConcurrency(n int) Option {
+	if n <= 0 {
+		panic("s3log: append concurrency must be positive")
+	}
+	return func(w *S3WAL) {
+		w.appendConcurrency = n
+	}
+}
+
+// BatchAppend appends records as one object each under consecutive offsets,
+// uploading them concurrently to amortize the PutObject round-trips of
+// calling Append in a loop. Every object is still written with If-None-Match
+// "*", so a batch racing another writer for an offset fails.
+//
+// On failure the returned offsets are those of records[:len(offsets)], the
+// records written before the first failed one, and the error names the index
+// of that record. No uploads are started after a failure, and records written
+// after the failed one are deleted. The log therefore only grows by the
+// contiguous prefix, and retrying records[len(offsets):] resumes at the next
+// offset. Records are passed to the append sink only once the batch is
+// settled, so the sink never receives a record deleted that way.
+//
+// With WithIDGenerator, only the first offset of the batch comes from the
+// generator; the records after it get consecutive offsets, as in a
+// micro-batch.
+//
+// With a hash chain, content keying, a pending local spill or a record that
+// needs chunking, the records are appended one at a time instead.
+func (w *S3WAL) BatchAppend(ctx context.Context, records [][]byte) ([]uint64, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("batch must contain at least one record")
+	}
+	if err := w.checkOpen(); err != nil {
+		return nil, err
+	}
+	if err := w.checkSchema(""); err != nil {
+		return nil, err
+	}
+	sequential := w.hashChain || w.contentKeying || w.spillPending()
+	for i, data := range records {
+		if err := w.checkSize(len(data)); err != nil {
+			return nil, fmt.Errorf("record %d of batch: %w", i, err)
+		}
+		sequential = sequential || w.needsChunking(frameHeader{}, len(data))
+	}
+	if sequential {
+		return w.appendEach(ctx, records)
+	}
+
+	start := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkOpen(); err != nil {
+		return nil, err
+	}
+	h := frameHeader{leadingChecksum: w.leadingChecksum, sum: w.checksummer}
+	if w.timestamps {
+		h.timestamp = w.now()
+	}
+	if w.spillPending() {
+		// a concurrent Append spilled a record after the check above
+		return w.spillEach(start, h, records)
+	}
+	first, err := w.nextOffset()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFrameOffset(first + uint64(len(records)-1)); err != nil {
+		return nil, err
+	}
+
+	frames, errs := w.putEach(ctx, first, h, records)
+	written := len(records)
+	for i, err := range errs {
+		if err != nil {
+			written = i
+			break
+		}
+	}
+	w.discardAfter(ctx, first, written, errs)
+	var sinkErr error
+	for i := range written {
+		if err := w.ship(ctx, first+uint64(i), frames[i]); err != nil {
+			errs[i] = err
+			sinkErr = cmp.Or(sinkErr, err)
+		}
+	}
+	for i := range records {
+		w.observe(MetricAppends, MetricAppendErrors, MetricAppendLatency, start, errs[i])
+	}
+
+	offsets := make([]uint64, written)
+	for i := range offsets {
+		offsets[i] = first + uint64(i)
+		if w.ReadBackSampled(offsets[i]) {
+			w.verifyReadBack(ctx, offsets[i], records[i])
+		}
+	}
+	if written > 0 {
+		w.length = first + uint64(written) - 1
+		w.updateIndex(ctx, w.length)
+		w.bloomAdd(first, w.length)
+		w.evictAfterAppend(ctx)
+	}
+	if written < len(records) {
+		return offsets, fmt.Errorf("record %d of batch: %w", written, errs[written])
+	}
+	return offsets, sinkErr
+}
+
+// putEach writes records[i] at first+i with up to appendConcurrency uploads
+// in flight, without passing them to the append sink, and returns the frame
+// as returned by writeRecord and the error of each upload. Once one fails,
+// uploads in flight are completed but no more are started, and the records
+// not uploaded report errBatchAborted.
+func (w *S3WAL) putEach(ctx context.Context, first uint64, h frameHeader, records [][]byte) ([][]byte, []error) {
+	failed := make(chan struct{})
+	var once sync.Once
+
+	concurrency := w.appendConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultAppendConcurrency
+	}
+	frames := make([][]byte, len(records))
+	errs := make([]error, len(records))
+	work := make(chan int)
+	var workers sync.WaitGroup
+	for range min(concurrency, len(records)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range work {
+				h := h
+				h.offset = first + uint64(i)
+				frame, _, err := w.writeRecord(ctx, h, nil, records[i])
+				if err != nil {
+					once.Do(func() { close(failed) })
+				}
+				frames[i], errs[i] = frame, err
+			}
+		}()
+	}
+	sent := 0
+send:
+	for ; sent < len(records); sent++ {
+		select {
+		case work <- sent:
+		case <-failed:
+			break send
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(work)
+	workers.Wait()
+
+	for i := sent; i < len(records); i++ {
+		errs[i] = cmp.Or(ctx.Err(), errBatchAborted)
+	}
+	return frames, errs
+}
+
+// discardAfter deletes the objects written by a batch after its first
+// failed record, so that the log does not have records past a gap.
+func (w *S3WAL) discardAfter(ctx context.Context, first uint64, written int, errs []error) {
+	for i := written + 1; i < len(errs); i++ {
+		if errs[i] != nil {
+			continue
+		}
+		if err := w.deleteObject(context.WithoutCancel(ctx), first+uint64(i)); err != nil {
+			w.logger.Warn("s3log: failed to delete record written after a failed batch append", "offset", first+uint64(i), "error", err)
+		}
+	}
+}
+
+// spillEach spills records one at a time in order, stopping at the first
+// failure. w.mu must be held.
+func (w *S3WAL) spillEach(start time.Time, h frameHeader, records [][]byte) ([]uint64, error) {
+	offsets := make([]uint64, 0, len(records))
+	for i, data := range records {
+		var err error
+		if h.offset, err = w.nextOffset(); err != nil {
+			return offsets, fmt.Errorf("record %d of batch: %w", i, err)
+		}
+		offset, _, err := w.spill(h, nil, data)
+		w.observe(MetricAppends, MetricAppendErrors, MetricAppendLatency, start, err)
+		if err != nil {
+			return offsets, fmt.Errorf("record %d of batch: %w", i, err)
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets, nil
+}
+
+// appendEach appends records one at a time, stopping at the first failure.
+func (w *S3WAL) appendEach(ctx context.Context, records [][]byte) ([]uint64, error) {
+	offsets := make([]uint64, 0, len(records))
+	var sinkErr error
+	for i, data := range records {
+		offset, err := w.Append(ctx, data)
+		if errors.Is(err, ErrSinkFailed) && sinkErr == nil {
+			sinkErr = err
+		} else if err != nil && !errors.Is(err, ErrSinkFailed) {
+			return offsets, fmt.Errorf("record %d of batch: %w", i, err)
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets, sinkErr
+}
@@ -0,0 +1,128 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// putFailingClient fails the first upload of a chosen key.
+type putFailingClient struct {
+	S3Client
+	mu   sync.Mutex
+	key  string
+	done bool
+}
+
+func (c *putFailingClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.mu.Lock()
+	fail := !c.done && aws.ToString(params.Key) == c.key
+	c.done = c.done || fail
+	c.mu.Unlock()
+	if fail {
+		return nil, errors.New("injected put failure")
+	}
+	return c.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func TestBatchAppend(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithConcurrency(3))
+	records := make([][]byte, 10)
+	for i := range records {
+		records[i] = []byte(fmt.Sprintf("record %d", i))
+	}
+	offsets, err := wal.BatchAppend(ctx, records)
+	if err != nil {
+		t.Fatalf("failed to batch append: %v", err)
+	}
+	for i, offset := range offsets {
+		if offset != uint64(i+1) {
+			t.Fatalf("expected offset %d, got %d", i+1, offset)
+		}
+		record, err := wal.Read(ctx, offset)
+		if err != nil || string(record.Data) != string(records[i]) {
+			t.Errorf("unexpected record at %d: %q (%v)", offset, record.Data, err)
+		}
+	}
+	if wal.length != 10 {
+		t.Errorf("expected length 10, got %d", wal.length)
+	}
+
+	// another writer takes offset 13, so the next batch stops before it
+	other := NewS3WAL(base.client, base.bucketName, base.prefix)
+	other.setLength(12)
+	if _, err := other.Append(ctx, []byte("racer")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	offsets, err = wal.BatchAppend(ctx, records[:5])
+	if err == nil || !strings.Contains(err.Error(), "record 2 of batch") {
+		t.Fatalf("expected record 2 to fail, got %v", err)
+	}
+	if len(offsets) != 2 || wal.length != 12 {
+		t.Errorf("expected 2 offsets and length 12, got %v and %d", offsets, wal.length)
+	}
+	for _, offset := range []uint64{14, 15} {
+		if _, err := wal.Read(ctx, offset); err == nil {
+			t.Errorf("expected record %d written after the failure to be deleted", offset)
+		}
+	}
+}
+
+func TestBatchAppendResumesAfterFailure(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var shipped []uint64
+	sink := func(_ context.Context, offset uint64, _ []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		shipped = append(shipped, offset)
+		return nil
+	}
+	client := &putFailingClient{S3Client: base.client, key: base.getObjectKey(4)}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithConcurrency(4), WithAppendSink(sink, true))
+	records := make([][]byte, 8)
+	for i := range records {
+		records[i] = []byte(fmt.Sprintf("record %d", i))
+	}
+	offsets, err := wal.BatchAppend(ctx, records)
+	if err == nil || !strings.Contains(err.Error(), "record 3 of batch") {
+		t.Fatalf("expected record 3 to fail, got %v", err)
+	}
+	if len(offsets) != 3 {
+		t.Fatalf("expected 3 offsets, got %v", offsets)
+	}
+	// records uploaded after the failed one are deleted without being shipped
+	if fmt.Sprint(shipped) != "[1 2 3]" {
+		t.Errorf("expected offsets 1 to 3 shipped, got %v", shipped)
+	}
+
+	rest, err := wal.BatchAppend(ctx, records[len(offsets):])
+	if err != nil {
+		t.Fatalf("failed to retry batch: %v", err)
+	}
+	if rest[0] != 4 || rest[len(rest)-1] != 8 {
+		t.Errorf("expected retry to resume at offset 4, got %v", rest)
+	}
+	if len(shipped) != len(records) {
+		t.Errorf("expected every record shipped once, got %v", shipped)
+	}
+	for i := range records {
+		record, err := wal.Read(ctx, uint64(i+1))
+		if err != nil || string(record.Data) != string(records[i]) {
+			t.Errorf("unexpected record at %d: %q (%v)", i+1, record.Data, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestAppendMicroBatch(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Append(ctx, []byte("before")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	var batch [][]byte
+	for i := 0; i < 5; i++ {
+		batch = append(batch, []byte(fmt.Sprintf("batched record %d", i)))
+	}
+	batch = append(batch, []byte{})
+
+	offsets, err := wal.AppendMicroBatch(ctx, batch)
+	if err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if len(offsets) != len(batch) || offsets[0] != 2 || offsets[len(offsets)-1] != 7 {
+		t.Fatalf("unexpected offsets: %v", offsets)
+	}
+
+	for i, offset := range offsets {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if record.Offset != offset {
+			t.Errorf("offset mismatch: expected %d, got %d", offset, record.Offset)
+		}
+		if string(record.Data) != string(batch[i]) {
+			t.Errorf("data mismatch at offset %d: expected %q, got %q", offset, batch[i], record.Data)
+		}
+	}
+
+	if _, err := wal.Read(ctx, 8); err == nil {
+		t.Error("expected error when reading past the end of a micro-batch, got nil")
+	}
+
+	record, err := wal.LastRecord(ctx)
+	if err != nil {
+		t.Fatalf("failed to get last record: %v", err)
+	}
+	if record.Offset != 7 {
+		t.Errorf("expected last offset 7, got %d", record.Offset)
+	}
+
+	offset, err := wal.Append(ctx, []byte("after"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if offset != 8 {
+		t.Errorf("expected offset 8 after micro-batch, got %d", offset)
+	}
+}
+
+func TestAppendMicroBatchLimits(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.AppendMicroBatch(ctx, nil); err == nil {
+		t.Error("expected error for empty micro-batch, got nil")
+	}
+	if _, err := wal.AppendMicroBatch(ctx, make([][]byte, MaxMicroBatchRecords+1)); err == nil {
+		t.Error("expected error for oversized micro-batch, got nil")
+	}
+}
+
+func TestDecodeMicroBatchMalformed(t *testing.T) {
+	payload := encodeMicroBatch(10, [][]byte{[]byte("a"), []byte("bc")})
+	if _, err := decodeMicroBatch(11, payload); err == nil {
+		t.Error("expected error for mismatched first offset, got nil")
+	}
+	if _, err := decodeMicroBatch(10, payload[3:]); err == nil {
+		t.Error("expected error for truncated micro-batch, got nil")
+	}
+	if _, err := decodeMicroBatch(10, []byte{0, 0}); err == nil {
+		t.Error("expected error for micro-batch without count, got nil")
+	}
+}
+
+func TestAppendTransaction(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client := &unreachableClient{S3Client: setupMinioClient(), down: true}
+	wal := NewS3WAL(client, base.bucketName, base.prefix)
+	if _, err := wal.AppendTransaction(ctx, [][]byte{[]byte("debit"), []byte("credit")}); err == nil {
+		t.Fatal("expected failed transaction to return an error")
+	}
+	for offset := uint64(1); offset <= 2; offset++ {
+		if _, err := wal.Read(ctx, offset); err == nil {
+			t.Errorf("offset %d of a failed transaction is visible", offset)
+		}
+	}
+
+	client.down = false
+	offsets, err := wal.AppendTransaction(ctx, [][]byte{[]byte("debit"), []byte("credit")})
+	if err != nil {
+		t.Fatalf("failed to append transaction: %v", err)
+	}
+	if len(offsets) != 2 || offsets[0] != 1 {
+		t.Fatalf("unexpected offsets: %v", offsets)
+	}
+	for i, data := range []string{"debit", "credit"} {
+		record, err := wal.Read(ctx, offsets[i])
+		if err != nil || string(record.Data) != data {
+			t.Errorf("offset %d: expected %q, got %q (%v)", offsets[i], data, record.Data, err)
+		}
+	}
+}
+
+func TestAppendMicroBatchOptions(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	metrics := &counters{}
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithChecksum(CRC32CChecksum), WithChecksumPosition(ChecksumLeading), WithTimestamps(), WithoutOffsetHeader(), WithMetrics(metrics))
+	offsets, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")})
+	if err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	for i, offset := range offsets {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if string(record.Data) != string(rune('a'+i)) || record.Timestamp.IsZero() {
+			t.Errorf("offset %d: expected %q with a timestamp, got %q at %v", offset, 'a'+rune(i), record.Data, record.Timestamp)
+		}
+	}
+	if got := metrics.get(MetricAppends); got != 2 {
+		t.Errorf("expected 2 appends counted, got %d", got)
+	}
+
+	chained := NewS3WAL(base.client, base.bucketName, base.prefix, WithHashChain())
+	if _, err := chained.AppendMicroBatch(ctx, [][]byte{[]byte("c")}); err == nil {
+		t.Error("expected error for a micro-batch with a hash chain, got nil")
+	}
+}
+
+func TestReadMissWithoutMicroBatches(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := base.Append(ctx, []byte("plain")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix)
+	if _, err := wal.Read(ctx, 2); err == nil {
+		t.Fatal("expected error for a missing record, got nil")
+	}
+	if usage := wal.UsageStats(); usage.ListRequests != 0 {
+		t.Errorf("expected no LIST for a miss on a log without micro-batches, got %+v", usage)
+	}
+
+	if _, err := base.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if record, err := wal.Read(ctx, 3); err != nil || string(record.Data) != "b" {
+		t.Fatalf("expected %q at offset 3, got %q (%v)", "b", record.Data, err)
+	}
+	// the object before a missing offset is only checked, not downloaded
+	before := wal.UsageStats().GetRequests
+	if _, err := wal.Read(ctx, 5); err == nil {
+		t.Fatal("expected error for a missing record, got nil")
+	}
+	if gets := wal.UsageStats().GetRequests - before; gets > 2 {
+		t.Errorf("expected the micro-batch not to be downloaded for a miss past it, got %d GETs", gets)
+	}
+}
@@ -0,0 +1,60 @@
+package s3log
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/zeebo/blake3"
+)
+
+// BlobHash derives the content address of a payload for content-addressed
+// keys. Its name is embedded in every derived key, so blobs written with one
+// hash are never looked up with another.
+type BlobHash interface {
+	Name() string
+	Sum(data []byte) []byte
+}
+
+var (
+	// SHA256BlobHash addresses blobs by their SHA-256 digest. It is the
+	// default.
+	SHA256BlobHash BlobHash = sha256BlobHash{}
+	// BLAKE3BlobHash addresses blobs by their 256-bit BLAKE3 digest, which
+	// is considerably faster for large payloads.
+	BLAKE3BlobHash BlobHash = blake3BlobHash{}
+)
+
+type sha256BlobHash struct{}
+
+func (sha256BlobHash) Name() string { return "sha256" }
+
+func (sha256BlobHash) Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+type blake3BlobHash struct{}
+
+func (blake3BlobHash) Name() string { return "blake3" }
+
+func (blake3BlobHash) Sum(data []byte) []byte {
+	sum := blake3.Sum256(data)
+	return sum[:]
+}
+
+// WithBlobHash selects the hash used to derive content-addressed blob keys.
+func WithBlobHash(h BlobHash) Option {
+	return func(w *S3WAL) {
+		w.blobHash = h
+	}
+}
+
+// blobKey returns the content-addressed key for data. Blobs live next to the
+// log rather than under it so they never show up as records when listing.
+func (w *S3WAL) blobKey(data []byte) string {
+	h := w.blobHash
+	if h == nil {
+		h = SHA256BlobHash
+	}
+	return w.prefix + ".blobs/" + h.Name() + "/" + hex.EncodeToString(h.Sum(data))
+}
@@ -0,0 +1,43 @@
+package s3log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBlobKey(t *testing.T) {
+	data := []byte("the same payload")
+
+	w := NewS3WAL(nil, "bucket", "log")
+	sha := w.blobKey(data)
+	if !strings.HasPrefix(sha, "log.blobs/sha256/") {
+		t.Errorf("expected default sha256 blob key, got %q", sha)
+	}
+	if sha != w.blobKey(data) {
+		t.Error("blob key is not deterministic")
+	}
+
+	w = NewS3WAL(nil, "bucket", "log", WithBlobHash(BLAKE3BlobHash))
+	b3 := w.blobKey(data)
+	if !strings.HasPrefix(b3, "log.blobs/blake3/") {
+		t.Errorf("expected blake3 blob key, got %q", b3)
+	}
+	if len(b3) != len("log.blobs/blake3/")+64 {
+		t.Errorf("unexpected blake3 blob key length: %q", b3)
+	}
+}
+
+func BenchmarkBlobHash(b *testing.B) {
+	for _, size := range []int{1 << 10, 1 << 20, 16 << 20} {
+		data := make([]byte, size)
+		for _, h := range []BlobHash{SHA256BlobHash, BLAKE3BlobHash} {
+			b.Run(fmt.Sprintf("%s/%dKiB", h.Name(), size>>10), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					h.Sum(data)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WithBloomFilter keeps a Bloom filter of the offsets holding records, sized
+// for expectedN offsets at a false positive rate of fpRate, so that Read and
+// ExistsMany answer for offsets that are definitely absent, such as those
+// removed by Truncate, without a request to S3. Offsets the filter may hold
+// are checked in S3 as usual.
+//
+// The filter is empty and unused until RefreshBloomFilter loads it by listing
+// the log. Appends through this S3WAL, and gaps filled by Repair, are added as
+// they happen. Offsets above the highest one known to the filter are always
+// checked in S3, so records appended by other writers are never hidden.
+// Records written below it by other means, e.g. by another process filling a
+// gap, stay hidden until the next refresh, and deleted offsets keep being
+// checked in S3 until then, since a Bloom filter cannot forget. Logs with
+// other writers or regular deletions should call RefreshBloomFilter
+// periodically.
+func WithBloomFilter(expectedN int, fpRate float64) Option {
+	if expectedN <= 0 {
+		panic("s3log: bloom filter needs a positive expected count")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("s3log: bloom filter false positive rate must be between 0 and 1")
+	}
+	return func(w *S3WAL) {
+		w.bloom = newBloomFilter(expectedN, fpRate)
+	}
+}
+
+// bloomFilter is a Bloom filter of offsets.
+type bloomFilter struct {
+	mu     sync.RWMutex
+	bits   []uint64
+	hashes int
+	// high is the highest offset added.
+	high uint64
+	// loaded is set once the filter holds every offset of the log.
+	loaded bool
+	// next, if set, is the filter being rebuilt by a refresh, which is
+	// added to as well.
+	next *bloomFilter
+}
+
+func newBloomFilter(n int, p float64) *bloomFilter {
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	words := int(math.Ceil(m / 64))
+	hashes := max(1, int(math.Round(float64(words*64)/float64(n)*math.Ln2)))
+	return &bloomFilter{bits: make([]uint64, words), hashes: hashes}
+}
+
+// positions calls fn with the bit positions of offset, derived from two
+// halves of a mixed hash.
+func (f *bloomFilter) positions(offset uint64, fn func(bit uint64)) {
+	h := offset + 0x9e3779b97f4a7c15
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	h ^= h >> 31
+	h1, h2 := h&0xffffffff, h>>32|1
+	m := uint64(len(f.bits)) * 64
+	for i := range uint64(f.hashes) {
+		fn((h1 + i*h2) % m)
+	}
+}
+
+// add adds offsets [first, last]. f.mu must be held.
+func (f *bloomFilter) add(first, last uint64) {
+	for offset := first; offset <= last; offset++ {
+		f.positions(offset, func(bit uint64) {
+			f.bits[bit/64] |= 1 << (bit % 64)
+		})
+	}
+	f.high = max(f.high, last)
+	if f.next != nil {
+		f.next.add(first, last)
+	}
+}
+
+// absent reports whether offset is known not to hold a record.
+func (f *bloomFilter) absent(offset uint64) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if !f.loaded || offset > f.high {
+		return false
+	}
+	missing := false
+	f.positions(offset, func(bit uint64) {
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			missing = true
+		}
+	})
+	return missing
+}
+
+// bloomAdd adds offsets [first, last] to the Bloom filter, if any.
+func (w *S3WAL) bloomAdd(first, last uint64) {
+	if w.bloom == nil {
+		return
+	}
+	w.bloom.mu.Lock()
+	defer w.bloom.mu.Unlock()
+	w.bloom.add(first, last)
+}
+
+// bloomAbsent reports whether the Bloom filter rules out a record at offset.
+func (w *S3WAL) bloomAbsent(offset uint64) bool {
+	return w.bloom != nil && w.bloom.absent(offset)
+}
+
+// errBloomAbsent is returned by Read for offsets ruled out by the Bloom
+// filter, wrapping the NoSuchKey a GetObject would have failed with.
+func errBloomAbsent(offset uint64) error {
+	return fmt.Errorf("failed to get object from s3: %w", &types.NoSuchKey{
+		Message: aws.String(fmt.Sprintf("offset %d is not in the bloom filter", offset)),
+	})
+}
+
+// RefreshBloomFilter rebuilds the filter set with WithBloomFilter from a
+// listing of the log, and starts using it if it was not loaded yet. Only the
+// objects followed by a gap, and the last one, are inspected with HeadObject
+// to learn whether they are micro-batches. Appends made during the refresh are
+// kept.
+func (w *S3WAL) RefreshBloomFilter(ctx context.Context) error {
+	if w.bloom == nil {
+		return fmt.Errorf("bloom filter is not enabled")
+	}
+	f := w.bloom
+	f.mu.Lock()
+	next := &bloomFilter{bits: make([]uint64, len(f.bits)), hashes: f.hashes}
+	f.next = next
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.next = nil
+		f.mu.Unlock()
+	}()
+
+	// each object is added once the next key shows whether a gap follows it
+	var prev uint64
+	held := false
+	addHeld := func(gap bool) error {
+		last := prev
+		if gap {
+			end, err := w.objectEnd(ctx, prev)
+			if err != nil {
+				return err
+			}
+			last = end
+		}
+		f.mu.Lock()
+		next.add(prev, last)
+		f.mu.Unlock()
+		return nil
+	}
+	err := w.forEachObject(ctx, func(offset uint64, _ types.Object) error {
+		if held {
+			if err := addHeld(offset > prev+1); err != nil {
+				return err
+			}
+		}
+		prev, held = offset, true
+		return nil
+	})
+	if err == nil && held {
+		err = addHeld(true)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bits, f.high, f.loaded = next.bits, next.high, true
+	return nil
+}
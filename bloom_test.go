@@ -0,0 +1,72 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 10; i++ {
+		if _, err := base.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := base.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b"), []byte("c")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if _, err := base.Truncate(ctx, 6, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithBloomFilter(1000, 0.001))
+	gets := func() uint64 { return wal.UsageStats().GetRequests }
+	if _, err := wal.Read(ctx, 2); err == nil {
+		t.Fatal("expected error reading a truncated offset")
+	}
+	if gets() == 0 {
+		t.Error("expected an unloaded filter to fall through to S3")
+	}
+
+	if err := wal.RefreshBloomFilter(ctx); err != nil {
+		t.Fatalf("failed to refresh bloom filter: %v", err)
+	}
+	before, heads := gets(), wal.UsageStats().HeadRequests
+	for offset := uint64(1); offset <= 5; offset++ {
+		if _, err := wal.Read(ctx, offset); !errors.Is(err, ErrTruncated) {
+			t.Errorf("offset %d: expected ErrTruncated, got %v", offset, err)
+		}
+	}
+	if exists, err := wal.ExistsMany(ctx, []uint64{1, 2}); err != nil || exists[1] || exists[2] {
+		t.Errorf("expected offsets 1 and 2 to be absent, got %v (%v)", exists, err)
+	}
+	if gets() != before || wal.UsageStats().HeadRequests != heads {
+		t.Errorf("expected ruled out offsets to cost no requests, got %d gets and %d heads", gets()-before, wal.UsageStats().HeadRequests-heads)
+	}
+	for offset, want := range map[uint64]string{6: "record 6", 12: "b", 13: "c"} {
+		if record, err := wal.Read(ctx, offset); err != nil || string(record.Data) != want {
+			t.Errorf("offset %d: expected %q, got %q (%v)", offset, want, record.Data, err)
+		}
+	}
+
+	// appended by another writer, above the highest offset the filter knows
+	if _, err := base.Append(ctx, []byte("record 14")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if record, err := wal.Read(ctx, 14); err != nil || string(record.Data) != "record 14" {
+		t.Errorf("expected record 14, got %q (%v)", record.Data, err)
+	}
+	wal.setLength(14)
+	offset, err := wal.Append(ctx, []byte("record 15"))
+	if err != nil || offset != 15 {
+		t.Fatalf("expected offset 15, got %d (%v)", offset, err)
+	}
+	if record, err := wal.Read(ctx, 15); err != nil || string(record.Data) != "record 15" {
+		t.Errorf("expected record 15, got %q (%v)", record.Data, err)
+	}
+}
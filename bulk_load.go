@@ -0,0 +1,108 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WithBulkLoadSpread starts a bulk-load session in which records are stored
+// under prefix/<bucket>/<offset>, where bucket is one of buckets hash buckets
+// derived from the offset, rather than under sequential keys. Spreading the
+// keys across prefixes lets S3 partition them, avoiding the throttling that
+// sequential keys run into at high request rates.
+//
+// The session covers every record appended through this S3WAL until
+// FinishBulkLoad moves the records to their sequential keys. Until then only
+// this S3WAL can read records appended one at a time; other readers, and
+// reads of micro-batched records, see the records only after FinishBulkLoad.
+// WithBulkLoadSpread panics if buckets is below 2.
+func WithBulkLoadSpread(buckets int) Option {
+	if buckets < 2 {
+		panic(fmt.Sprintf("s3log: bulk load needs at least 2 buckets, got %d", buckets))
+	}
+	return func(w *S3WAL) {
+		w.bulkSpread = buckets
+	}
+}
+
+// bulkBucketPrefix returns the key prefix of a bulk-load hash bucket.
+func (w *S3WAL) bulkBucketPrefix(bucket int) string {
+	width := len(fmt.Sprintf("%x", w.bulkSpread-1))
+	return fmt.Sprintf("%s%0*x/", w.keyPrefix(), width, bucket)
+}
+
+// bulkKey returns the key of offset during a bulk-load session.
+func (w *S3WAL) bulkKey(offset uint64) string {
+	bucket := int(mixOffset(offset) % uint64(w.bulkSpread))
+	return w.bulkBucketPrefix(bucket) + fmt.Sprintf("%020d", offset) + w.keySuffix
+}
+
+// FinishBulkLoad ends the bulk-load session started by WithBulkLoadSpread:
+// it copies every record stored under a hash bucket to its sequential key,
+// deletes the bucket's copy and returns the number of objects moved. Later
+// appends use sequential keys again. Appends wait while it runs. If it fails
+// part way, calling it again moves the remaining objects.
+func (w *S3WAL) FinishBulkLoad(ctx context.Context) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	moved := 0
+	for bucket := range w.bulkSpread {
+		input := &s3.ListObjectsV2Input{
+			Bucket: aws.String(w.bucketName),
+			Prefix: aws.String(w.bulkBucketPrefix(bucket)),
+		}
+		err := w.listPages(ctx, input, func(output *s3.ListObjectsV2Output) error {
+			for _, obj := range output.Contents {
+				offset, err := w.getOffsetFromKey(*obj.Key)
+				if err != nil {
+					continue
+				}
+				if err := w.moveObject(ctx, *obj.Key, w.sequentialKey(offset)); err != nil {
+					return err
+				}
+				moved++
+			}
+			return nil
+		})
+		if err != nil {
+			return moved, err
+		}
+	}
+	w.bulkSpread = 0
+	w.bulkStart.Store(0)
+	return moved, nil
+}
+
+// moveObject copies the object at src to dst, keeping its metadata, and
+// deletes src.
+func (w *S3WAL) moveObject(ctx context.Context, src, dst string) error {
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(w.bucketName),
+		Key:               aws.String(dst),
+		CopySource:        aws.String(w.bucketName + "/" + url.PathEscape(src)),
+		MetadataDirective: types.MetadataDirectiveCopy,
+	}
+	if w.kmsKeyID != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(w.kmsKeyID)
+	}
+	if w.bucketKey {
+		input.BucketKeyEnabled = aws.Bool(true)
+	}
+	if _, err := w.client.CopyObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to copy object %s: %w", src, err)
+	}
+	_, err := w.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(src),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object from S3: %w", err)
+	}
+	return nil
+}
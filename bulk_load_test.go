@@ -0,0 +1,77 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestBulkLoadSpread(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithBulkLoadSpread(4))
+	for i := 1; i <= 6; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record-%d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	output, err := wal.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(wal.bucketName),
+		Prefix:    aws.String(wal.prefix + "/"),
+		Delimiter: aws.String("/"),
+	})
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(output.Contents) != 0 {
+		t.Errorf("expected no sequential keys during the session, got %d", len(output.Contents))
+	}
+	if len(output.CommonPrefixes) < 2 {
+		t.Errorf("expected records spread across buckets, got %d prefixes", len(output.CommonPrefixes))
+	}
+
+	record, err := wal.Read(ctx, 3)
+	if err != nil || !bytes.Equal(record.Data, []byte("record-3")) {
+		t.Fatalf("expected record-3 during the session, got %q (%v)", record.Data, err)
+	}
+
+	moved, err := wal.FinishBulkLoad(ctx)
+	if err != nil || moved != 6 {
+		t.Fatalf("expected 6 objects moved, got %d (%v)", moved, err)
+	}
+
+	reader := NewS3WAL(base.client, base.bucketName, base.prefix)
+	for i := uint64(1); i <= 6; i++ {
+		record, err := reader.Read(ctx, i)
+		if err != nil || !bytes.Equal(record.Data, []byte(fmt.Sprintf("record-%d", i))) {
+			t.Errorf("expected record-%d after the session, got %q (%v)", i, record.Data, err)
+		}
+	}
+
+	offset, err := wal.Append(ctx, []byte("after"))
+	if err != nil || offset != 7 {
+		t.Fatalf("expected offset 7, got %d (%v)", offset, err)
+	}
+	if key := wal.getObjectKey(offset); key != wal.sequentialKey(offset) {
+		t.Errorf("expected sequential key after the session, got %q", key)
+	}
+	if record, err := reader.Read(ctx, offset); err != nil || string(record.Data) != "after" {
+		t.Errorf("expected to read the next append, got %q (%v)", record.Data, err)
+	}
+}
+
+func TestWithBulkLoadSpreadPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a single bucket")
+		}
+	}()
+	WithBulkLoadSpread(1)
+}
@@ -0,0 +1,215 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// metaDependsOn is the user metadata key holding a record's causal
+// dependencies, encoded as a query string mapping log prefixes to offsets.
+const metaDependsOn = "s3log-depends-on"
+
+// Dependency names a record of another log, by its prefix, that a record
+// causally depends on.
+type Dependency struct {
+	Log    string
+	Offset uint64
+}
+
+// AppendWithDependencies appends data recording that it causally depends on
+// deps, e.g. the last record of every shard the writer had seen. Depending on
+// an offset implies depending on every earlier offset of the same log, so
+// only the greatest offset per log is stored, making the stored set a vector
+// clock. The dependencies are kept in the record object's user metadata,
+// returned as Record.DependsOn, and must fit in S3's metadata limit.
+func (w *S3WAL) AppendWithDependencies(ctx context.Context, deps []Dependency, data []byte) (uint64, error) {
+	encoded, err := encodeDependencies(deps)
+	if err != nil {
+		return 0, err
+	}
+	if size := len(metaDependsOn) + len(encoded); size > maxUserMetadataSize {
+		return 0, fmt.Errorf("%w: dependencies of %d bytes exceed the limit of %d", ErrMetadataTooLarge, len(encoded), maxUserMetadataSize-len(metaDependsOn))
+	}
+	offset, _, err := w.append(ctx, frameHeader{}, map[string]string{metaDependsOn: encoded}, data)
+	return offset, err
+}
+
+func encodeDependencies(deps []Dependency) (string, error) {
+	if len(deps) == 0 {
+		return "", fmt.Errorf("no dependencies given")
+	}
+	latest := make(map[string]uint64, len(deps))
+	for _, dep := range deps {
+		if dep.Log == "" || dep.Offset == 0 {
+			return "", fmt.Errorf("dependency needs a log and an offset, got %+v", dep)
+		}
+		latest[dep.Log] = max(latest[dep.Log], dep.Offset)
+	}
+	values := make(url.Values, len(latest))
+	for log, offset := range latest {
+		values.Set(log, strconv.FormatUint(offset, 10))
+	}
+	return values.Encode(), nil
+}
+
+// decodeDependencies returns the dependencies in metadata, sorted by log.
+func decodeDependencies(metadata map[string]string) ([]Dependency, error) {
+	encoded, ok := metadata[metaDependsOn]
+	if !ok {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad dependencies %q", ErrInvalidRecord, encoded)
+	}
+	deps := make([]Dependency, 0, len(values))
+	for log, offsets := range values {
+		offset, err := strconv.ParseUint(offsets[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad dependency offset %q", ErrInvalidRecord, offsets[0])
+		}
+		deps = append(deps, Dependency{Log: log, Offset: offset})
+	}
+	slices.SortFunc(deps, func(a, b Dependency) int { return strings.Compare(a.Log, b.Log) })
+	return deps, nil
+}
+
+// CausalMergeOptions controls MergeCausal.
+type CausalMergeOptions struct {
+	// Follow keeps merging as records are appended, until ctx is cancelled.
+	// Without it, MergeCausal returns once every log has been read to its
+	// end.
+	Follow bool
+	// PollInterval is how long MergeCausal waits before looking for new
+	// records when no record can be delivered. Zero means
+	// defaultPollInterval.
+	PollInterval time.Duration
+}
+
+// mergeCursor tracks the progress of MergeCausal through one log.
+type mergeCursor struct {
+	wal *S3WAL
+	// start is the first offset read; dependencies on earlier offsets are
+	// treated as satisfied, since those records were truncated away.
+	start uint64
+	// delivered is the last offset passed to the handler, or start-1.
+	delivered uint64
+	// head is the next record, read but not yet delivered.
+	head *Record
+}
+
+// MergeCausal calls handler with the records of logs, which are told apart by
+// their prefixes, in an order that respects the dependencies recorded with
+// AppendWithDependencies: a record is delivered only after every record it
+// depends on. Records of the same log are delivered in offset order, starting
+// at each log's first record, and the logs must have no gaps from there on.
+//
+// Each log's next record is read and held as its head. A head is delivered as
+// soon as every dependency is satisfied, i.e. the named log has delivered the
+// named offset, and logs are visited in the order given, so independent
+// records interleave log by log. When no head can be delivered, MergeCausal
+// waits PollInterval for new records, since a dependency may name a record
+// that has not been appended or replicated yet. It fails with
+// ErrUnresolvedDependency when waiting cannot help: a dependency names a log
+// not in logs, every log's head is blocked by another, or, without Follow, a
+// head waits on a record past the end of its log.
+func MergeCausal(ctx context.Context, logs []*S3WAL, handler func(Record) error, opts CausalMergeOptions) error {
+	poll := opts.PollInterval
+	if poll <= 0 {
+		poll = defaultPollInterval
+	}
+	cursors := make(map[string]*mergeCursor, len(logs))
+	order := make([]*mergeCursor, len(logs))
+	for i, w := range logs {
+		if _, dup := cursors[w.prefix]; dup {
+			return fmt.Errorf("log %s is given twice", w.prefix)
+		}
+		start := uint64(1)
+		first, err := w.FirstRecord(ctx)
+		if err == nil {
+			start = first.Offset
+		} else if !errors.Is(err, ErrWALEmpty) {
+			return err
+		}
+		order[i] = &mergeCursor{wal: w, start: start, delivered: start - 1}
+		cursors[w.prefix] = order[i]
+	}
+
+	// blocker returns the first unsatisfied dependency of record
+	blocker := func(record *Record) (Dependency, bool, error) {
+		for _, dep := range record.DependsOn {
+			c, ok := cursors[dep.Log]
+			if !ok {
+				return dep, false, fmt.Errorf("%w: record %d depends on log %s, which is not merged", ErrUnresolvedDependency, record.Offset, dep.Log)
+			}
+			if dep.Offset >= c.start && dep.Offset > c.delivered {
+				return dep, true, nil
+			}
+		}
+		return Dependency{}, false, nil
+	}
+
+	for {
+		progressed := false
+		for _, c := range order {
+			for {
+				if c.head == nil {
+					record, err := c.wal.Read(ctx, c.delivered+1)
+					var nsk *types.NoSuchKey
+					if errors.As(err, &nsk) {
+						break
+					}
+					if err != nil {
+						return err
+					}
+					c.head = &record
+				}
+				if _, blocked, err := blocker(c.head); err != nil || blocked {
+					if err != nil {
+						return err
+					}
+					break
+				}
+				if err := handler(*c.head); err != nil {
+					return fmt.Errorf("handler failed on offset %d of %s: %w", c.head.Offset, c.wal.prefix, err)
+				}
+				c.delivered, c.head = c.head.Offset, nil
+				progressed = true
+			}
+		}
+		if progressed {
+			continue
+		}
+
+		allBlocked, anyBlocked := true, false
+		for _, c := range order {
+			allBlocked = allBlocked && c.head != nil
+			anyBlocked = anyBlocked || c.head != nil
+		}
+		if !anyBlocked && !opts.Follow {
+			return nil
+		}
+		if anyBlocked && (allBlocked || !opts.Follow) {
+			for _, c := range order {
+				if c.head == nil {
+					continue
+				}
+				dep, _, _ := blocker(c.head)
+				return fmt.Errorf("%w: record %d of %s waits on offset %d of %s", ErrUnresolvedDependency, c.head.Offset, c.wal.prefix, dep.Offset, dep.Log)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMergeCausal(t *testing.T) {
+	a, cleanupA := getWAL(t)
+	defer cleanupA()
+	b, cleanupB := getWAL(t)
+	defer cleanupB()
+	ctx := context.Background()
+
+	if _, err := a.Append(ctx, []byte("a1")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	deps := []Dependency{{Log: a.prefix, Offset: 1}, {Log: a.prefix, Offset: 2}}
+	if _, err := b.AppendWithDependencies(ctx, deps, []byte("b1")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := b.Append(ctx, []byte("b2")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	record, err := b.Read(ctx, 1)
+	if err != nil || fmt.Sprint(record.DependsOn) != fmt.Sprintf("[{%s 2}]", a.prefix) {
+		t.Errorf("expected only the latest dependency on %s, got %v (%v)", a.prefix, record.DependsOn, err)
+	}
+
+	merge := func(opts CausalMergeOptions) ([]string, error) {
+		var got []string
+		err := MergeCausal(ctx, []*S3WAL{b, a}, func(record Record) error {
+			got = append(got, string(record.Data))
+			return nil
+		}, opts)
+		return got, err
+	}
+	if _, err := merge(CausalMergeOptions{}); !errors.Is(err, ErrUnresolvedDependency) {
+		t.Errorf("expected ErrUnresolvedDependency while a2 is missing, got %v", err)
+	}
+
+	// a2 shows up while the merge is waiting for it
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if _, err := a.Append(ctx, []byte("a2")); err != nil {
+			t.Errorf("failed to append: %v", err)
+		}
+	}()
+	followCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	var got []string
+	err = MergeCausal(followCtx, []*S3WAL{b, a}, func(record Record) error {
+		got = append(got, string(record.Data))
+		if len(got) == 4 {
+			cancel()
+		}
+		return nil
+	}, CausalMergeOptions{Follow: true, PollInterval: 10 * time.Millisecond})
+	if !errors.Is(err, context.Canceled) || fmt.Sprint(got) != "[a1 a2 b1 b2]" {
+		t.Errorf("expected [a1 a2 b1 b2], got %v (%v)", got, err)
+	}
+	if got, err := merge(CausalMergeOptions{}); err != nil || fmt.Sprint(got) != "[a1 a2 b1 b2]" {
+		t.Errorf("expected [a1 a2 b1 b2], got %v (%v)", got, err)
+	}
+
+	if _, err := a.AppendWithDependencies(ctx, []Dependency{{Log: "elsewhere", Offset: 1}}, []byte("a3")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := merge(CausalMergeOptions{Follow: true}); !errors.Is(err, ErrUnresolvedDependency) {
+		t.Errorf("expected ErrUnresolvedDependency for an unknown log, got %v", err)
+	}
+}
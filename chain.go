@@ -0,0 +1,106 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// chainLink is the offset and stored checksum of the last record appended by
+// this S3WAL, so chained appends need not fetch it again.
+type chainLink struct {
+	offset   uint64
+	checksum []byte
+}
+
+// WithHashChain makes every record written by Append and its variants store
+// the checksum of the record before it in its header, forming a hash chain
+// that ValidateChain can check. The first record of the log references an
+// all-zero checksum. Since each record's own checksum covers that header,
+// modifying or removing a record is detected at the next link unless every
+// later record is rewritten too.
+//
+// AppendMicroBatch and AppendStream, which cannot write the link, fail.
+func WithHashChain() Option {
+	return func(w *S3WAL) {
+		w.hashChain = true
+	}
+}
+
+// chainPrev returns the checksum the next appended record must reference. It
+// must be called with mu held.
+func (w *S3WAL) chainPrev(ctx context.Context) (*[32]byte, error) {
+	var prev [32]byte
+	switch {
+	case w.length == 0:
+	case w.chainTail.offset == w.length:
+		copy(prev[:], w.chainTail.checksum)
+	default:
+		checksum, err := w.leafChecksum(ctx, w.length)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get checksum of record %d: %w", w.length, err)
+		}
+		copy(prev[:], checksum)
+	}
+	return &prev, nil
+}
+
+// ValidateChain checks the hash chain written by WithHashChain across the
+// records in [start, end], in log order. Every record must be chained and,
+// except for the first one in the range, reference the stored checksum of the
+// record before it; the first record of the log must reference the all-zero
+// checksum. Records that fail their own checksum are reported as by Read.
+// Violations of the chain wrap ErrBrokenChain.
+func (w *S3WAL) ValidateChain(ctx context.Context, start, end uint64) error {
+	if start == 0 || end < start {
+		return fmt.Errorf("invalid offset range [%d, %d]", start, end)
+	}
+	input := &s3.ListObjectsV2Input{
+		Bucket:     aws.String(w.bucketName),
+		Prefix:     aws.String(w.keyPrefix()),
+		StartAfter: aws.String(w.getObjectKey(start - 1)),
+	}
+	paginator := s3.NewListObjectsV2Paginator(w.client, input)
+
+	var prev []byte
+	if start == 1 {
+		prev = make([]byte, 32)
+	}
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects from s3: %w", err)
+		}
+		for _, obj := range output.Contents {
+			offset, err := w.getOffsetFromKey(*obj.Key)
+			if err != nil {
+				continue
+			}
+			if offset > end {
+				return nil
+			}
+			data, metadata, err := w.getBytes(ctx, *obj.Key)
+			if err != nil {
+				return err
+			}
+			if _, ok := metadata[metaMicroBatch]; ok {
+				return fmt.Errorf("%w: micro-batch at offset %d is not chained", ErrBrokenChain, offset)
+			}
+			h, _, err := decodeFrame(data, offset)
+			if err != nil {
+				return fmt.Errorf("record %d: %w", offset, err)
+			}
+			if h.prevChecksum == nil {
+				return fmt.Errorf("%w: record %d is not chained", ErrBrokenChain, offset)
+			}
+			if prev != nil && !bytes.Equal(h.prevChecksum[:], prev) {
+				return fmt.Errorf("%w: record %d does not reference the record before it", ErrBrokenChain, offset)
+			}
+			prev = h.storedChecksum(data)
+		}
+	}
+	return nil
+}
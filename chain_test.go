@@ -0,0 +1,80 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestHashChain(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	WithHashChain()(wal)
+
+	for _, data := range []string{"genesis", "second", "third"} {
+		if _, err := wal.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendWithSchema(ctx, "audit.v1", []byte("fourth")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	// a fresh instance fetches the checksum of the tail to continue the chain
+	resumed := NewS3WAL(wal.client, wal.bucketName, wal.prefix, WithHashChain())
+	resumed.length = 4
+	if _, err := resumed.Append(ctx, []byte("fifth")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	for _, r := range [][2]uint64{{1, 5}, {2, 4}, {5, 5}, {1, 100}} {
+		if err := wal.ValidateChain(ctx, r[0], r[1]); err != nil {
+			t.Errorf("ValidateChain(%d, %d): %v", r[0], r[1], err)
+		}
+	}
+	record, err := wal.Read(ctx, 4)
+	if err != nil || string(record.Data) != "fourth" || record.SchemaID != "audit.v1" {
+		t.Errorf("unexpected record %+v (%v)", record, err)
+	}
+
+	// rewriting a record with a valid checksum breaks the next link
+	data, _, err := wal.getBytes(ctx, wal.getObjectKey(3))
+	if err != nil {
+		t.Fatalf("failed to read record: %v", err)
+	}
+	h, _, err := decodeFrame(data, 3)
+	if err != nil {
+		t.Fatalf("failed to decode record: %v", err)
+	}
+	forged, err := encodeFrame(h, []byte("forged"))
+	if err != nil {
+		t.Fatalf("failed to encode record: %v", err)
+	}
+	_, err = wal.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(wal.getObjectKey(3)),
+		Body:   bytes.NewReader(forged),
+	})
+	if err != nil {
+		t.Fatalf("failed to overwrite record: %v", err)
+	}
+	if err := wal.ValidateChain(ctx, 1, 3); err != nil {
+		t.Errorf("expected the chain up to the forged record to be intact, got %v", err)
+	}
+	if err := wal.ValidateChain(ctx, 1, 5); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain, got %v", err)
+	}
+
+	unchained := NewS3WAL(wal.client, wal.bucketName, wal.prefix)
+	unchained.length = 5
+	if _, err := unchained.Append(ctx, []byte("sixth")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if err := wal.ValidateChain(ctx, 4, 6); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain for an unchained record, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+)
+
+// AppendOutcome is the result of appending one payload received by
+// AppendChannel.
+type AppendOutcome struct {
+	Offset uint64
+	Err    error
+}
+
+// AppendChannel drains payloads from in in a background goroutine, appending
+// them one at a time so their offsets follow the order in which they were
+// received, and emits one outcome per payload on the returned channel.
+//
+// It stops when in is closed, ctx is cancelled or the WAL is closed. An
+// append that has already started when ctx is cancelled is still completed
+// and its outcome delivered; payloads not yet received stay in the input
+// channel. The outcome channel is closed once the goroutine exits, and the
+// caller must keep receiving from it until then.
+// The S3WAL must not be appended to by anything else while AppendChannel runs.
+func (w *S3WAL) AppendChannel(ctx context.Context, in <-chan []byte) (<-chan AppendOutcome, error) {
+	if in == nil {
+		return nil, fmt.Errorf("input channel must not be nil")
+	}
+	out := make(chan AppendOutcome)
+	w.workers.Add(1)
+	go func() {
+		defer w.workers.Done()
+		defer close(out)
+		for {
+			var data []byte
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.closing:
+				return
+			case data, ok = <-in:
+				if !ok {
+					return
+				}
+			}
+			offset, err := w.Append(context.WithoutCancel(ctx), data)
+			out <- AppendOutcome{Offset: offset, Err: err}
+		}
+	}()
+	return out, nil
+}
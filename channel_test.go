@@ -0,0 +1,71 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestAppendChannel(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	in := make(chan []byte)
+	out, err := wal.AppendChannel(ctx, in)
+	if err != nil {
+		t.Fatalf("failed to start appending from channel: %v", err)
+	}
+	go func() {
+		for i := 0; i < 5; i++ {
+			in <- []byte(fmt.Sprintf("event %d", i))
+		}
+		close(in)
+	}()
+
+	var offsets []uint64
+	for outcome := range out {
+		if outcome.Err != nil {
+			t.Fatalf("failed to append: %v", outcome.Err)
+		}
+		offsets = append(offsets, outcome.Offset)
+	}
+	if len(offsets) != 5 {
+		t.Fatalf("expected 5 outcomes, got %d", len(offsets))
+	}
+	for i, offset := range offsets {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if string(record.Data) != fmt.Sprintf("event %d", i) {
+			t.Errorf("order not preserved at offset %d: got %q", offset, record.Data)
+		}
+	}
+
+	if _, err := wal.AppendChannel(ctx, nil); err == nil {
+		t.Error("expected error for nil input channel, got nil")
+	}
+}
+
+func TestAppendChannelCancel(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	in := make(chan []byte, 1)
+	out, err := wal.AppendChannel(ctx, in)
+	if err != nil {
+		t.Fatalf("failed to start appending from channel: %v", err)
+	}
+	in <- []byte("before cancel")
+	if outcome := <-out; outcome.Err != nil || outcome.Offset != 1 {
+		t.Fatalf("unexpected outcome: %+v", outcome)
+	}
+	cancel()
+	for outcome := range out {
+		if outcome.Err != nil {
+			t.Errorf("unexpected outcome after cancel: %+v", outcome)
+		}
+	}
+}
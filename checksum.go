@@ -0,0 +1,148 @@
+package s3log
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"sync"
+)
+
+// maxChecksumSize is the largest checksum a Checksummer may produce.
+const maxChecksumSize = 64
+
+// Checksummer computes the checksum stored in record frames. ID names the
+// algorithm in the header of every frame written with it, so that readers
+// validate each record with the algorithm it was written with, and must be
+// unique among the registered checksummers. ID 0 is reserved for SHA-256,
+// whose frames carry no ID.
+type Checksummer interface {
+	ID() byte
+	// Size is the length of the checksums returned by Sum, at most 64.
+	Size() int
+	Sum(data []byte) []byte
+}
+
+var (
+	// SHA256Checksum checksums frames with SHA-256. It is the default.
+	SHA256Checksum Checksummer = sha256Checksum{}
+	// CRC32CChecksum checksums frames with the 4-byte CRC-32C, which is
+	// hardware-accelerated on most CPUs and far cheaper than SHA-256 for
+	// small records, but only detects accidental corruption.
+	CRC32CChecksum Checksummer = crc32cChecksum{}
+)
+
+type sha256Checksum struct{}
+
+func (sha256Checksum) ID() byte  { return 0 }
+func (sha256Checksum) Size() int { return sha256.Size }
+
+func (sha256Checksum) Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type crc32cChecksum struct{}
+
+func (crc32cChecksum) ID() byte  { return 1 }
+func (crc32cChecksum) Size() int { return crc32.Size }
+
+func (crc32cChecksum) Sum(data []byte) []byte {
+	return binary.BigEndian.AppendUint32(nil, crc32.Checksum(data, castagnoli))
+}
+
+// checksummers maps the IDs found in frame headers to their Checksummer. It
+// holds the built-in ones and every one passed to RegisterChecksummer.
+var checksummers sync.Map
+
+func init() {
+	checksummers.Store(CRC32CChecksum.ID(), CRC32CChecksum)
+}
+
+// RegisterChecksummer makes c available to WithChecksum and to every reader
+// of records naming its ID. Registrations last for the life of the process,
+// so it is meant to be called from an init function, like the registration
+// of the built-in checksummers. Registering the same type again is a no-op.
+// It panics if c is invalid, or if its ID is reserved or taken by another
+// Checksummer.
+func RegisterChecksummer(c Checksummer) {
+	if c == nil {
+		panic("s3log: checksummer must not be nil")
+	}
+	if size := c.Size(); size <= 0 || size > maxChecksumSize || len(c.Sum(nil)) != size {
+		panic(fmt.Sprintf("s3log: checksummer must return checksums of 1 to %d bytes matching its size", maxChecksumSize))
+	}
+	if c.ID() == 0 {
+		panic("s3log: checksum id 0 is reserved for SHA-256")
+	}
+	if existing, loaded := checksummers.LoadOrStore(c.ID(), c); loaded && reflect.TypeOf(existing) != reflect.TypeOf(c) {
+		panic(fmt.Sprintf("s3log: checksum id %d is already used by %T", c.ID(), existing))
+	}
+}
+
+// WithChecksum selects the checksum of records written by Append and the
+// other single-record appends. Records are then stored in extended frames,
+// which always carry an offset header and name the algorithm, and Read
+// rejects a record naming another algorithm than the one configured with
+// ErrUnsupportedChecksum rather than a spurious checksum failure, so every
+// reader of the log must be opened with the same WithChecksum. Micro-batches
+// and AppendPrehashed keep using SHA-256 in frames that name no algorithm,
+// which are read under any configuration, and AppendStream fails. It cannot
+// be combined with WithHashChain, whose links are SHA-256 checksums. It
+// panics unless c is SHA256Checksum, CRC32CChecksum or a Checksummer passed
+// to RegisterChecksummer.
+func WithChecksum(c Checksummer) Option {
+	if c == nil {
+		panic("s3log: checksummer must not be nil")
+	}
+	if c.ID() == 0 {
+		if _, ok := c.(sha256Checksum); !ok {
+			panic("s3log: checksum id 0 is reserved for SHA-256")
+		}
+		return func(w *S3WAL) {
+			w.checksummer = nil
+		}
+	}
+	if existing, ok := checksummers.Load(c.ID()); !ok || reflect.TypeOf(existing) != reflect.TypeOf(c) {
+		panic(fmt.Sprintf("s3log: checksum id %d is not registered for %T; call RegisterChecksummer first", c.ID(), c))
+	}
+	return func(w *S3WAL) {
+		w.checksummer = c
+	}
+}
+
+// lookupChecksummer returns the Checksummer with the given ID.
+func lookupChecksummer(id byte) (Checksummer, error) {
+	c, ok := checksummers.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %w: checksum algorithm %d is not known; register it with RegisterChecksummer", ErrInvalidRecord, ErrUnsupportedChecksum, id)
+	}
+	return c.(Checksummer), nil
+}
+
+// checkChecksumAlgorithm returns an ErrUnsupportedChecksum error if h names a
+// checksum algorithm other than the configured one.
+func (w *S3WAL) checkChecksumAlgorithm(h frameHeader) error {
+	if h.sum == nil {
+		return nil
+	}
+	var configured byte
+	if w.checksummer != nil {
+		configured = w.checksummer.ID()
+	}
+	if h.sum.ID() != configured {
+		return fmt.Errorf("%w: record %d was written with checksum algorithm %d, but the WAL is configured with algorithm %d", ErrUnsupportedChecksum, h.offset, h.sum.ID(), configured)
+	}
+	return nil
+}
+
+// checkChecksum panics if the configured checksum conflicts with other
+// options.
+func (w *S3WAL) checkChecksum() {
+	if w.checksummer != nil && w.hashChain {
+		panic("s3log: WithChecksum cannot be combined with WithHashChain")
+	}
+}
@@ -0,0 +1,27 @@
+package s3log
+
+// ChecksumPosition selects where a record's checksum is stored in its frame.
+type ChecksumPosition int
+
+const (
+	// ChecksumTrailing stores the checksum after the data. It is the default.
+	ChecksumTrailing ChecksumPosition = iota
+	// ChecksumLeading stores the checksum right after the frame's magic and
+	// flags, ahead of the offset and data, so that a reader can verify a
+	// record while it streams in rather than after buffering all of it.
+	ChecksumLeading
+)
+
+// WithChecksumPosition sets where appended records store their checksum.
+// ChecksumLeading frames every record as an extended frame flagged with the
+// position, so Read handles logs mixing both placements, and it takes
+// precedence over WithoutOffsetHeader. Segments written by Compact always
+// keep a trailing checksum, and AppendStream fails with ChecksumLeading.
+// Merkle proofs and content keying fetch a record's checksum from where the
+// current position places it, falling back to the other end of the object if
+// the record was written with the other placement.
+func WithChecksumPosition(pos ChecksumPosition) Option {
+	return func(w *S3WAL) {
+		w.leadingChecksum = pos == ChecksumLeading
+	}
+}
@@ -0,0 +1,68 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestChecksumLeading(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Append(ctx, []byte("trailing")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	WithChecksumPosition(ChecksumLeading)(wal)
+	WithHashChain()(wal)
+	offset, checksum, err := wal.AppendWithChecksum(ctx, []byte("leading record"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	data := []byte("prehashed")
+	if _, err := wal.AppendPrehashed(ctx, data, sha256.Sum256(data)); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	frame, err := wal.ReadRaw(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read raw: %v", err)
+	}
+	rest := append([]byte{frame[0], frame[1]}, frame[34:]...)
+	if sum := sha256.Sum256(rest); frame[0] != frameMagic || !bytes.Equal(frame[2:34], sum[:]) || !bytes.Equal(checksum, sum[:]) {
+		t.Errorf("expected the checksum of the rest of the frame at its start")
+	}
+	for offset, want := range map[uint64]string{1: "trailing", 2: "leading record", 3: "prehashed"} {
+		record, err := wal.Read(ctx, offset)
+		if err != nil || string(record.Data) != want {
+			t.Errorf("offset %d: expected %q, got %q (%v)", offset, want, record.Data, err)
+		}
+	}
+	if tail, err := wal.ReadTail(ctx, offset, 6); err != nil || string(tail) != "record" {
+		t.Errorf("expected tail %q, got %q (%v)", "record", tail, err)
+	}
+	if err := wal.ValidateChain(ctx, 2, 3); err != nil {
+		t.Errorf("failed to validate chain: %v", err)
+	}
+	if proof, err := wal.InclusionProof(ctx, 1, 1, 3); err != nil || len(proof) == 0 {
+		t.Errorf("failed to prove inclusion of a trailing record: %v", err)
+	}
+
+	frame[len(frame)-1] ^= 1
+	if _, err := ValidateFrame(frame, offset); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	frame[len(frame)-1] ^= 1
+
+	dst, cleanupDst := getWAL(t)
+	defer cleanupDst()
+	if _, err := dst.AppendFramed(ctx, frame); err != nil {
+		t.Fatalf("failed to append framed: %v", err)
+	}
+	if raw, err := dst.ReadRaw(ctx, 1); err != nil || raw[1]&byte(flagLeadingChecksum) != 0 {
+		t.Errorf("expected the forwarded record to follow the destination's placement (%v)", err)
+	}
+}
@@ -0,0 +1,136 @@
+package s3log
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+	"time"
+)
+
+func TestWithChecksumCRC32C(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := base.Append(ctx, []byte("sha256")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithChecksum(CRC32CChecksum))
+	wal.setLength(1)
+	offset, err := wal.Append(ctx, []byte("crc32c"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	frame, err := wal.ReadRaw(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read raw: %v", err)
+	}
+	if len(frame) != wal.FramingOverhead()+len("crc32c") || frame[2] != CRC32CChecksum.ID() {
+		t.Errorf("expected a %d byte frame naming CRC-32C, got %d bytes", wal.FramingOverhead()+len("crc32c"), len(frame))
+	}
+	body := frame[:len(frame)-crc32.Size]
+	if binary.BigEndian.Uint32(frame[len(body):]) != crc32.Checksum(body, castagnoli) {
+		t.Error("expected a trailing CRC-32C of the rest of the frame")
+	}
+
+	// plain SHA-256 frames name no algorithm and are read under any configuration
+	for offset, want := range map[uint64]string{1: "sha256", 2: "crc32c"} {
+		record, err := wal.Read(ctx, offset)
+		if err != nil || string(record.Data) != want {
+			t.Errorf("offset %d: expected %q, got %q (%v)", offset, want, record.Data, err)
+		}
+	}
+	if _, err := base.Read(ctx, offset); !errors.Is(err, ErrUnsupportedChecksum) {
+		t.Errorf("expected a reader without the option to fail with ErrUnsupportedChecksum, got %v", err)
+	}
+	if tail, err := wal.ReadTail(ctx, offset, 3); err != nil || string(tail) != "32c" {
+		t.Errorf("expected tail %q, got %q (%v)", "32c", tail, err)
+	}
+
+	frame[len(frame)-1] ^= 1
+	if _, err := ValidateFrame(frame, offset); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	frame[len(frame)-1] ^= 1
+	frame[2] = 250
+	if _, err := ValidateFrame(frame, offset); !errors.Is(err, ErrUnsupportedChecksum) {
+		t.Errorf("expected ErrUnsupportedChecksum, got %v", err)
+	}
+}
+
+func TestWithChecksumHeaderFields(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithChecksum(CRC32CChecksum), WithChecksumPosition(ChecksumLeading), WithTimestamps())
+	for i, data := range []string{"a", "bb", "ccc"} {
+		wal.now = func() time.Time { return now.Add(time.Duration(i) * time.Minute) }
+		if _, err := wal.AppendWithSchema(ctx, "event", []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	record, err := wal.Read(ctx, 2)
+	if err != nil || string(record.Data) != "bb" || record.SchemaID != "event" || !record.Timestamp.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected record %+v (%v)", record, err)
+	}
+	records, err := wal.ReadByTimeRange(ctx, now.Add(time.Minute), now.Add(2*time.Minute))
+	if err != nil || len(records) != 2 {
+		t.Errorf("expected 2 records in the time range, got %d (%v)", len(records), err)
+	}
+	if err := wal.WriteManifest(ctx); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	manifest, err := wal.ReadManifest(ctx)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	for _, entry := range manifest.Entries {
+		if len(entry.Checksum) != 2*crc32.Size {
+			t.Errorf("offset %d: expected a CRC-32C checksum, got %q", entry.Offset, entry.Checksum)
+		}
+	}
+}
+
+func TestWithChecksumValidation(t *testing.T) {
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+	expectPanic("hash chain", func() { NewS3WAL(nil, "bucket", "log", WithChecksum(CRC32CChecksum), WithHashChain()) })
+	expectPanic("taken id", func() { WithChecksum(fakeChecksum{id: CRC32CChecksum.ID(), size: 4}) })
+	expectPanic("reserved id", func() { WithChecksum(fakeChecksum{id: 0, size: 4}) })
+	expectPanic("unregistered", func() { WithChecksum(fakeChecksum{id: 98, size: 4}) })
+	expectPanic("register bad size", func() { RegisterChecksummer(fakeChecksum{id: 99, size: 0}) })
+	expectPanic("register reserved id", func() { RegisterChecksummer(fakeChecksum{id: 0, size: 4}) })
+	expectPanic("register taken id", func() { RegisterChecksummer(fakeChecksum{id: CRC32CChecksum.ID(), size: 4}) })
+
+	RegisterChecksummer(fakeChecksum{id: 99, size: 4})
+	if wal := NewS3WAL(nil, "bucket", "log", WithChecksum(fakeChecksum{id: 99, size: 4})); wal.checksummer == nil || wal.checksummer.ID() != 99 {
+		t.Error("expected a registered checksummer to be selected")
+	}
+
+	wal := NewS3WAL(nil, "bucket", "log", WithChecksum(SHA256Checksum))
+	if wal.checksummer != nil || wal.FramingOverhead() != plainFrameOverhead {
+		t.Error("expected SHA-256 to select the default framing")
+	}
+}
+
+// fakeChecksum is a Checksummer of any ID and size, summing to zeroes.
+type fakeChecksum struct {
+	id   byte
+	size int
+}
+
+func (c fakeChecksum) ID() byte               { return c.id }
+func (c fakeChecksum) Size() int              { return c.size }
+func (c fakeChecksum) Sum(data []byte) []byte { return make([]byte, c.size) }
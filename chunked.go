@@ -0,0 +1,335 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxSinglePutSize is the largest object S3 accepts in a single PutObject.
+// Records whose frame is larger are stored as a chunked record: the payload is
+// split into chunk objects keyed by the record offset and a random upload ID,
+// and the record object itself holds a manifest listing the upload ID and the
+// size and SHA-256 of every chunk. The manifest is written last, with the
+// usual IfNoneMatch guard, so a record only becomes visible once all of its
+// chunks are in place, and a writer that loses the race for the offset never
+// touches the chunks of the one that won.
+const maxSinglePutSize = 5 << 30
+
+const chunkManifestEntryLen = 8 + sha256.Size
+
+// chunkUploadIDLen is the length of the upload ID that ends a chunk manifest.
+// Manifests written before upload IDs were introduced have none, and their
+// chunks are keyed by offset and index alone.
+const chunkUploadIDLen = 16
+
+type chunkInfo struct {
+	size     uint64
+	checksum [sha256.Size]byte
+}
+
+type chunkManifest struct {
+	uploadID []byte
+	chunks   []chunkInfo
+}
+
+// WithChunkedChecksums stores records whose frame is larger than chunkSize as
+// chunked records of chunkSize-byte chunks instead of only those too large for
+// a single PutObject. Every chunk gets its own SHA-256 in the manifest held by
+// the record object, and the frame checksum of the manifest is the root that
+// covers them all. ReadTo and ReadAt verify each chunk as it is downloaded, so
+// a reader interrupted partway through a huge record resumes with ReadAt from
+// the bytes it already has instead of downloading and verifying the whole
+// record again. Records appended with AppendStream keep a single checksum.
+// WithChunkedChecksums panics if chunkSize is not positive or exceeds the 5
+// GiB limit of a single PutObject.
+func WithChunkedChecksums(chunkSize int64) Option {
+	if chunkSize <= 0 || chunkSize > maxSinglePutSize {
+		panic(fmt.Sprintf("s3log: chunk size %d is outside the range (0, %d]", chunkSize, int64(maxSinglePutSize)))
+	}
+	return func(w *S3WAL) {
+		w.maxObjectSize = chunkSize
+	}
+}
+
+// chunkPrefix returns the key prefix of every chunk of the record at offset,
+// whichever upload wrote it. Chunks live next to the log rather than under it
+// so they never show up as records when listing.
+func (w *S3WAL) chunkPrefix(offset uint64) string {
+	return fmt.Sprintf("%s.chunks/%020d/", w.prefix, offset)
+}
+
+// chunkKey returns the key of the i-th chunk written by the given upload of
+// the record at offset.
+func (w *S3WAL) chunkKey(offset uint64, uploadID []byte, i int) string {
+	if len(uploadID) == 0 {
+		return fmt.Sprintf("%s%06d", w.chunkPrefix(offset), i)
+	}
+	return fmt.Sprintf("%s%x/%06d", w.chunkPrefix(offset), uploadID, i)
+}
+
+// needsChunking reports whether the frame of a size-byte payload written
+// with h exceeds the object size limit.
+func (w *S3WAL) needsChunking(h frameHeader, size int) bool {
+	framed := int64(size) + int64(w.FramingOverhead())
+	if h.schemaID != "" {
+		framed += 1 + int64(len(h.schemaID))
+	}
+	return framed > w.maxObjectSize
+}
+
+func encodeChunkManifest(m chunkManifest) []byte {
+	buf := make([]byte, 0, 4+len(m.chunks)*chunkManifestEntryLen+len(m.uploadID))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(m.chunks)))
+	for _, c := range m.chunks {
+		buf = binary.BigEndian.AppendUint64(buf, c.size)
+		buf = append(buf, c.checksum[:]...)
+	}
+	return append(buf, m.uploadID...)
+}
+
+func decodeChunkManifest(manifest []byte) (chunkManifest, error) {
+	if len(manifest) < 4 {
+		return chunkManifest{}, fmt.Errorf("%w: chunk manifest is missing its chunk count", ErrInvalidRecord)
+	}
+	count := int(binary.BigEndian.Uint32(manifest))
+	entriesEnd := 4 + count*chunkManifestEntryLen
+	if len(manifest) != entriesEnd && len(manifest) != entriesEnd+chunkUploadIDLen {
+		return chunkManifest{}, fmt.Errorf("%w: chunk manifest does not hold %d chunks", ErrInvalidRecord, count)
+	}
+	m := chunkManifest{chunks: make([]chunkInfo, count)}
+	for i := range m.chunks {
+		entry := manifest[4+i*chunkManifestEntryLen:]
+		m.chunks[i].size = binary.BigEndian.Uint64(entry)
+		copy(m.chunks[i].checksum[:], entry[8:])
+	}
+	if len(manifest) > entriesEnd {
+		m.uploadID = manifest[entriesEnd:]
+	}
+	return m, nil
+}
+
+// putChunks uploads data as chunks of at most maxObjectSize bytes under a new
+// upload ID and returns the manifest describing them.
+func (w *S3WAL) putChunks(ctx context.Context, offset uint64, data []byte) ([]byte, error) {
+	m := chunkManifest{uploadID: make([]byte, chunkUploadIDLen)}
+	if _, err := rand.Read(m.uploadID); err != nil {
+		return nil, fmt.Errorf("failed to generate chunk upload ID: %w", err)
+	}
+	for i := 0; len(data) > 0; i++ {
+		n := min(int64(len(data)), w.maxObjectSize)
+		chunk := data[:n]
+		data = data[n:]
+
+		input := &s3.PutObjectInput{
+			Bucket: aws.String(w.bucketName),
+			Key:    aws.String(w.chunkKey(offset, m.uploadID, i)),
+			Body:   bytes.NewReader(chunk),
+		}
+		w.applyPutOptions(input)
+		if _, err := w.client.PutObject(ctx, input); err != nil {
+			return nil, fmt.Errorf("failed to put chunk %d to S3: %w", i, err)
+		}
+		m.chunks = append(m.chunks, chunkInfo{size: uint64(n), checksum: sha256.Sum256(chunk)})
+	}
+	return encodeChunkManifest(m), nil
+}
+
+// copyChunks streams the chunks listed in manifest to dst, verifying each
+// chunk before writing it.
+func (w *S3WAL) copyChunks(ctx context.Context, offset uint64, manifest []byte, dst io.Writer) (int64, error) {
+	m, err := decodeChunkManifest(manifest)
+	if err != nil {
+		return 0, err
+	}
+	var written int64
+	for i, c := range m.chunks {
+		chunk, err := w.getChunk(ctx, offset, m.uploadID, i, c)
+		if err != nil {
+			return written, err
+		}
+		n, err := dst.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// getChunk downloads the i-th chunk written by the given upload of the record
+// at offset and verifies it against c.
+func (w *S3WAL) getChunk(ctx context.Context, offset uint64, uploadID []byte, i int, c chunkInfo) ([]byte, error) {
+	chunk, _, err := w.getBytes(ctx, w.chunkKey(offset, uploadID, i))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(chunk)) != c.size || sha256.Sum256(chunk) != c.checksum {
+		return nil, fmt.Errorf("chunk %d of record %d: %w", i, offset, ErrChecksumMismatch)
+	}
+	return chunk, nil
+}
+
+// readChunksAt fills p from the chunks listed in manifest, starting at byte
+// off of the payload, downloading only the chunks that overlap p.
+func (w *S3WAL) readChunksAt(ctx context.Context, offset uint64, manifest []byte, p []byte, off int64) (int, error) {
+	m, err := decodeChunkManifest(manifest)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	var start int64
+	for i, c := range m.chunks {
+		end := start + int64(c.size)
+		if pos := off + int64(n); n < len(p) && pos >= start && pos < end {
+			chunk, err := w.getChunk(ctx, offset, m.uploadID, i, c)
+			if err != nil {
+				return n, err
+			}
+			n += copy(p[n:], chunk[pos-start:])
+		}
+		start = end
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (w *S3WAL) readChunks(ctx context.Context, offset uint64, manifest []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := w.copyChunks(ctx, offset, manifest, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadTo writes the payload of the record at offset to dst and returns the
+// number of bytes written. Chunked records are streamed chunk by chunk, so
+// records larger than memory can be read. See ReadAt for resuming a read
+// that failed partway through.
+func (w *S3WAL) ReadTo(ctx context.Context, offset uint64, dst io.Writer) (int64, error) {
+	obj, err := w.fetchObject(ctx, offset)
+	var nsk *types.NoSuchKey
+	if err != nil && !errors.As(err, &nsk) {
+		return 0, err
+	}
+	if err == nil && !obj.isMicroBatch() {
+		if obj.header.chunked {
+			return w.copyChunks(ctx, offset, obj.payload, dst)
+		}
+		n, err := dst.Write(obj.payload)
+		return int64(n), err
+	}
+	record, err := w.Read(ctx, offset)
+	if err != nil {
+		return 0, err
+	}
+	n, err := dst.Write(record.Data)
+	return int64(n), err
+}
+
+// ReadAt reads len(p) bytes of the payload of the record at offset into p,
+// starting at byte off of the payload, with the semantics of io.ReaderAt: it
+// returns io.EOF if fewer bytes remain. Of a chunked record, only the chunks
+// overlapping p are downloaded, each verified before use, so a transfer cut
+// short after n bytes resumes with ReadAt from n. Other records are read and
+// verified whole.
+func (w *S3WAL) ReadAt(ctx context.Context, offset uint64, p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative position %d", off)
+	}
+	obj, err := w.fetchObject(ctx, offset)
+	var nsk *types.NoSuchKey
+	if err != nil && !errors.As(err, &nsk) {
+		return 0, err
+	}
+	if err == nil && obj.header.chunked {
+		return w.readChunksAt(ctx, offset, obj.payload, p, off)
+	}
+	data := obj.payload
+	if err != nil || obj.isMicroBatch() {
+		record, err := w.Read(ctx, offset)
+		if err != nil {
+			return 0, err
+		}
+		data = record.Data
+	}
+	if off >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// deleteChunks deletes the chunk objects of the records at offsets [start,
+// end], from every upload, except those of the offsets in kept. It returns
+// the result of every DeleteObjects batch and the keys each left over.
+func (w *S3WAL) deleteChunks(ctx context.Context, start, end uint64, kept map[uint64]bool, opts DeleteOptions) ([]DeleteBatchResult, [][]string, error) {
+	root := w.prefix + ".chunks/"
+	input := &s3.ListObjectsV2Input{
+		Bucket:     aws.String(w.bucketName),
+		Prefix:     aws.String(root),
+		StartAfter: aws.String(fmt.Sprintf("%s%020d", root, start)),
+	}
+	var results []DeleteBatchResult
+	var remaining [][]string
+	var pending []string
+	flush := func() {
+		if len(pending) > 0 {
+			result, left := w.deleteBatch(ctx, pending, opts)
+			results = append(results, result)
+			remaining = append(remaining, left)
+			pending = nil
+		}
+	}
+	err := w.listPages(ctx, input, func(output *s3.ListObjectsV2Output) error {
+		for _, obj := range output.Contents {
+			key := aws.ToString(obj.Key)
+			digits, _, ok := strings.Cut(strings.TrimPrefix(key, root), "/")
+			offset, err := strconv.ParseUint(digits, 10, 64)
+			if !ok || err != nil {
+				continue
+			}
+			if offset > end {
+				return errRangeEnd
+			}
+			if kept[offset] {
+				continue
+			}
+			pending = append(pending, key)
+			if len(pending) == maxDeleteBatch {
+				flush()
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errRangeEnd) {
+		err = nil
+	}
+	flush()
+	return results, remaining, err
+}
+
+// deleteRecordChunks deletes every chunk of the record at offset.
+func (w *S3WAL) deleteRecordChunks(ctx context.Context, offset uint64) error {
+	results, _, err := w.deleteChunks(ctx, offset, offset, nil, DeleteOptions{})
+	for _, result := range results {
+		err = errors.Join(err, result.Err)
+	}
+	return err
+}
@@ -0,0 +1,240 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestChunkedRecord(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	defer emptyBucket(context.Background(), setupMinioClient(), wal.bucketName, wal.prefix+".chunks/")
+	ctx := context.Background()
+	wal.maxObjectSize = 1000
+
+	small := []byte("fits in one object")
+	large := make([]byte, 3500)
+	for i := range large {
+		large[i] = byte(i % 256)
+	}
+	if _, err := wal.Append(ctx, small); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	offset, err := wal.Append(ctx, large)
+	if err != nil {
+		t.Fatalf("failed to append chunked record: %v", err)
+	}
+
+	record, err := wal.Read(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read chunked record: %v", err)
+	}
+	if !bytes.Equal(record.Data, large) {
+		t.Errorf("chunked data mismatch: got %d bytes", len(record.Data))
+	}
+
+	var buf bytes.Buffer
+	n, err := wal.ReadTo(ctx, offset, &buf)
+	if err != nil {
+		t.Fatalf("failed to read chunked record to writer: %v", err)
+	}
+	if n != int64(len(large)) || !bytes.Equal(buf.Bytes(), large) {
+		t.Errorf("ReadTo data mismatch: got %d bytes", n)
+	}
+
+	buf.Reset()
+	if _, err := wal.ReadTo(ctx, 1, &buf); err != nil || buf.String() != string(small) {
+		t.Errorf("ReadTo of regular record returned %q (%v)", buf.String(), err)
+	}
+
+	last, err := wal.LastRecord(ctx)
+	if err != nil || !bytes.Equal(last.Data, large) {
+		t.Errorf("LastRecord did not reassemble the chunked record (%v)", err)
+	}
+
+	_, err = wal.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(chunkKeys(t, wal, offset)[2]),
+		Body:   bytes.NewReader(make([]byte, 1000)),
+	})
+	if err != nil {
+		t.Fatalf("failed to corrupt chunk: %v", err)
+	}
+	if _, err := wal.Read(ctx, offset); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for corrupted chunk, got %v", err)
+	}
+}
+
+func TestChunkedChecksumsReadAt(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	defer emptyBucket(context.Background(), setupMinioClient(), base.bucketName, base.prefix+".chunks/")
+	ctx := context.Background()
+
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithChunkedChecksums(100))
+	data := make([]byte, 350)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	offset, err := wal.Append(ctx, data)
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte("small")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	p := make([]byte, 120)
+	if n, err := wal.ReadAt(ctx, offset, p, 150); err != nil || n != 120 || !bytes.Equal(p, data[150:270]) {
+		t.Errorf("expected bytes 150 to 270, got %d bytes (%v)", n, err)
+	}
+	if n, err := wal.ReadAt(ctx, offset, p, 300); !errors.Is(err, io.EOF) || n != 50 || !bytes.Equal(p[:n], data[300:]) {
+		t.Errorf("expected the last 50 bytes and io.EOF, got %d bytes (%v)", n, err)
+	}
+	if n, err := wal.ReadAt(ctx, offset+1, p[:3], 2); err != nil || string(p[:n]) != "all" {
+		t.Errorf("expected %q, got %q (%v)", "all", p[:n], err)
+	}
+
+	// a corrupted chunk only fails reads that overlap it
+	_, err = wal.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(chunkKeys(t, wal, offset)[0]),
+		Body:   bytes.NewReader(make([]byte, 100)),
+	})
+	if err != nil {
+		t.Fatalf("failed to corrupt chunk: %v", err)
+	}
+	if _, err := wal.ReadAt(ctx, offset, p[:10], 95); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if n, err := wal.ReadAt(ctx, offset, p[:10], 100); err != nil || !bytes.Equal(p[:n], data[100:110]) {
+		t.Errorf("expected bytes 100 to 110, got %d bytes (%v)", n, err)
+	}
+}
+
+// chunkKeys lists the keys of the chunks of the record at offset.
+func chunkKeys(t *testing.T, wal *S3WAL, offset uint64) []string {
+	t.Helper()
+	output, err := wal.client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(wal.bucketName),
+		Prefix: aws.String(wal.chunkPrefix(offset)),
+	})
+	if err != nil {
+		t.Fatalf("failed to list chunks: %v", err)
+	}
+	var keys []string
+	for _, obj := range output.Contents {
+		keys = append(keys, aws.ToString(obj.Key))
+	}
+	return keys
+}
+
+func TestChunkedRecordLostRace(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	defer emptyBucket(context.Background(), setupMinioClient(), wal.bucketName, wal.prefix+".chunks/")
+	ctx := context.Background()
+	wal.maxObjectSize = 100
+
+	winner := bytes.Repeat([]byte("w"), 250)
+	offset, err := wal.Append(ctx, winner)
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	// a second writer that also believes offset 1 is free
+	loser := NewS3WAL(wal.client, wal.bucketName, wal.prefix, WithChunkedChecksums(100))
+	if _, err := loser.Append(ctx, bytes.Repeat([]byte("l"), 250)); err == nil {
+		t.Fatal("expected the second writer to lose the race for offset 1")
+	}
+	record, err := wal.Read(ctx, offset)
+	if err != nil || !bytes.Equal(record.Data, winner) {
+		t.Errorf("expected the winner's record to stay intact, got %d bytes (%v)", len(record.Data), err)
+	}
+}
+
+func TestChunkingCountsFraming(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	defer emptyBucket(context.Background(), setupMinioClient(), wal.bucketName, wal.prefix+".chunks/")
+	ctx := context.Background()
+	wal.maxObjectSize = 100
+
+	// the payload fits the limit but its frame does not
+	data := bytes.Repeat([]byte("x"), 100-wal.FramingOverhead()+1)
+	offset, err := wal.Append(ctx, data)
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if keys := chunkKeys(t, wal, offset); len(keys) != 1 {
+		t.Errorf("expected the record to be chunked, got chunks %q", keys)
+	}
+	if record, err := wal.Read(ctx, offset); err != nil || !bytes.Equal(record.Data, data) {
+		t.Errorf("unexpected record of %d bytes (%v)", len(record.Data), err)
+	}
+}
+
+func TestLegacyChunkManifest(t *testing.T) {
+	wal := NewS3WAL(nil, "bucket", "log")
+	legacy := encodeChunkManifest(chunkManifest{chunks: []chunkInfo{{size: 3}}})
+	m, err := decodeChunkManifest(legacy)
+	if err != nil || len(m.chunks) != 1 || m.uploadID != nil {
+		t.Fatalf("unexpected manifest %+v (%v)", m, err)
+	}
+	if key := wal.chunkKey(7, m.uploadID, 0); key != "log.chunks/00000000000000000007/000000" {
+		t.Errorf("expected the offset and index key of a legacy chunk, got %q", key)
+	}
+}
+
+func TestDeleteChunkedRecords(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	defer emptyBucket(context.Background(), setupMinioClient(), wal.bucketName, wal.prefix+".chunks/")
+	ctx := context.Background()
+	wal.maxObjectSize = 100
+
+	large := bytes.Repeat([]byte("x"), 250)
+	for _, data := range [][]byte{[]byte("a"), large, large, []byte("b")} {
+		if _, err := wal.Append(ctx, data); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	report, err := wal.DeleteRange(ctx, 2, 2, DeleteOptions{})
+	if err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if report.Deleted != 4 {
+		t.Errorf("expected the record and its 3 chunks to be deleted, got %d objects", report.Deleted)
+	}
+	if keys := chunkKeys(t, wal, 2); len(keys) != 0 {
+		t.Errorf("expected the chunks of offset 2 to be deleted, got %q", keys)
+	}
+	if record, err := wal.Read(ctx, 3); err != nil || !bytes.Equal(record.Data, large) {
+		t.Errorf("expected offset 3 to stay readable (%v)", err)
+	}
+	if _, err := wal.Truncate(ctx, 4, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if keys := chunkKeys(t, wal, 3); len(keys) != 0 {
+		t.Errorf("expected the chunks of offset 3 to be truncated, got %q", keys)
+	}
+
+	// eviction takes the chunks of the evicted record along
+	evicting := NewS3WAL(wal.client, wal.bucketName, wal.prefix, WithChunkedChecksums(100), WithMaxTotalBytes(100))
+	evicting.setLength(4)
+	offset, err := evicting.Append(ctx, large)
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := evicting.Append(ctx, bytes.Repeat([]byte("y"), 80)); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if keys := chunkKeys(t, wal, offset); len(keys) != 0 {
+		t.Errorf("expected the chunks of evicted offset %d to be deleted, got %q", offset, keys)
+	}
+}
@@ -0,0 +1,105 @@
+package s3log
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Client is the subset of the S3 API used by S3WAL. *s3.Client satisfies it.
+type S3Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// UsageStats counts the S3 requests issued by an S3WAL since it was created.
+type UsageStats struct {
+	PutRequests  uint64
+	GetRequests  uint64
+	HeadRequests uint64
+	ListRequests uint64
+	// DeleteRequests are free on S3 and not priced by EstimateCost.
+	DeleteRequests uint64
+}
+
+type usageCounters struct {
+	put, get, head, list, delete atomic.Uint64
+}
+
+// usageClient counts requests on their way to the wrapped client.
+type usageClient struct {
+	S3Client
+	usage *usageCounters
+}
+
+func (c usageClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.usage.put.Add(1)
+	return c.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func (c usageClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.usage.get.Add(1)
+	return c.S3Client.GetObject(ctx, params, optFns...)
+}
+
+func (c usageClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.usage.head.Add(1)
+	return c.S3Client.HeadObject(ctx, params, optFns...)
+}
+
+func (c usageClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.usage.list.Add(1)
+	return c.S3Client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func (c usageClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	c.usage.put.Add(1)
+	return c.S3Client.CreateMultipartUpload(ctx, params, optFns...)
+}
+
+func (c usageClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	c.usage.put.Add(1)
+	return c.S3Client.UploadPart(ctx, params, optFns...)
+}
+
+func (c usageClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	c.usage.put.Add(1)
+	return c.S3Client.CompleteMultipartUpload(ctx, params, optFns...)
+}
+
+func (c usageClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	c.usage.put.Add(1)
+	return c.S3Client.CopyObject(ctx, params, optFns...)
+}
+
+func (c usageClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.usage.delete.Add(1)
+	return c.S3Client.DeleteObject(ctx, params, optFns...)
+}
+
+func (c usageClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	c.usage.delete.Add(1)
+	return c.S3Client.DeleteObjects(ctx, params, optFns...)
+}
+
+// UsageStats returns the number of S3 requests issued so far, by kind.
+// Multipart upload and copy requests are counted as puts.
+func (w *S3WAL) UsageStats() UsageStats {
+	return UsageStats{
+		PutRequests:    w.usage.put.Load(),
+		GetRequests:    w.usage.get.Load(),
+		HeadRequests:   w.usage.head.Load(),
+		ListRequests:   w.usage.list.Load(),
+		DeleteRequests: w.usage.delete.Load(),
+	}
+}
@@ -0,0 +1,70 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+)
+
+// Close shuts the WAL down without losing acknowledged records. It
+//
+//  1. makes every later append fail with ErrClosed,
+//  2. stops the goroutines started by AppendChannel, waiting for an append
+//     they have already started to complete, and
+//  3. writes the records spilled by WithLocalSpill to S3, as Drain does.
+//
+// Once Close returns nil, every record whose append succeeded is in S3. If
+// ctx expires or S3 is still unreachable, Close returns an error naming the
+// offsets of the spilled records that are not known to be in S3; they remain
+// in the spill file, and Close or Drain can be retried.
+func (w *S3WAL) Close(ctx context.Context) error {
+	w.shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		w.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait for background appends: %w", ctx.Err())
+	}
+
+	if _, pending, err := w.drain(ctx); err != nil {
+		if len(pending) > 0 {
+			return fmt.Errorf("failed to flush spilled records at offsets %v: %w", pending, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Abort makes every later append fail with ErrClosed and signals the
+// AppendChannel goroutines to stop, without waiting for them and without
+// flushing spilled records. Spilled records are left in the spill file, where
+// a later Drain will find them.
+func (w *S3WAL) Abort() {
+	w.shutdown()
+}
+
+func (w *S3WAL) shutdown() {
+	w.closeOnce.Do(func() {
+		close(w.closing)
+	})
+}
+
+// checkOpen returns ErrClosed once Close or Abort has been called, and
+// ErrSealed once the log is known to be sealed. Appends check again once they
+// hold mu, which Seal also takes, so an append that was waiting for mu while
+// Seal ran fails instead of writing after the marker.
+func (w *S3WAL) checkOpen() error {
+	select {
+	case <-w.closing:
+		return ErrClosed
+	default:
+	}
+	if w.sealed.Load() {
+		return ErrSealed
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClose(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client := &unreachableClient{S3Client: setupMinioClient()}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithLocalSpill(t.TempDir()))
+
+	in := make(chan []byte)
+	out, err := wal.AppendChannel(ctx, in)
+	if err != nil {
+		t.Fatalf("failed to start AppendChannel: %v", err)
+	}
+	in <- []byte("one")
+	if outcome := <-out; outcome.Err != nil {
+		t.Fatalf("failed to append: %v", outcome.Err)
+	}
+
+	client.down = true
+	in <- []byte("two")
+	if outcome := <-out; outcome.Err != nil || outcome.Offset != 2 {
+		t.Fatalf("expected offset 2 to be spilled, got %+v", outcome)
+	}
+
+	err = wal.Close(ctx)
+	if err == nil || !strings.Contains(err.Error(), "[2]") {
+		t.Fatalf("expected Close to report offset 2 as unflushed, got %v", err)
+	}
+	if _, ok := <-out; ok {
+		t.Error("expected AppendChannel to stop on Close")
+	}
+	if _, err := wal.Append(ctx, []byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+
+	client.down = false
+	if err := wal.Close(ctx); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	record, err := wal.Read(ctx, 2)
+	if err != nil || string(record.Data) != "two" {
+		t.Errorf("expected spilled record to be flushed, got %q, %v", record.Data, err)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client := &unreachableClient{S3Client: setupMinioClient(), down: true}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithLocalSpill(t.TempDir()))
+	if _, err := wal.Append(ctx, []byte("spilled")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	client.down = false
+
+	wal.Abort()
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("late")}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if !wal.spillPending() {
+		t.Error("expected Abort to leave spilled records in place")
+	}
+}
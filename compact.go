@@ -0,0 +1,373 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CompactionPolicy selects the record objects merged by Compact.
+type CompactionPolicy struct {
+	// Interval is the time between compaction passes of StartCompactor.
+	Interval time.Duration
+	// MaxObjectSize is the size of the largest object considered small
+	// enough to be merged. It must be positive.
+	MaxObjectSize int64
+	// MinAge keeps objects written more recently than this out of
+	// compaction.
+	MinAge time.Duration
+	// MaxRecords caps the number of records merged into one segment. Zero
+	// means MaxMicroBatchRecords, which is also the upper limit.
+	MaxRecords int
+}
+
+// CompactionStats counts the work done by compaction since the S3WAL was
+// created.
+type CompactionStats struct {
+	Runs          int64
+	ObjectsMerged int64
+	// BytesReclaimed is the decrease in stored bytes. The index of a segment
+	// costs about as much as the framing it saves, so for tiny records this
+	// can be negative; the saving is in the number of objects.
+	BytesReclaimed int64
+}
+
+type compactionCounters struct {
+	runs, merged, reclaimed atomic.Int64
+}
+
+// CompactionStats returns the totals of every Compact pass so far, including
+// those run by StartCompactor.
+func (w *S3WAL) CompactionStats() CompactionStats {
+	return CompactionStats{
+		Runs:           w.compaction.runs.Load(),
+		ObjectsMerged:  w.compaction.merged.Load(),
+		BytesReclaimed: w.compaction.reclaimed.Load(),
+	}
+}
+
+type compactionCandidate struct {
+	offset uint64
+	size   int64
+	etag   *string
+	data   []byte
+}
+
+// Compact merges runs of consecutive small record objects into micro-batch
+// segments. The segment replaces the object of the run's first offset,
+// conditional on that object being unchanged, and the remaining objects are
+// deleted afterwards. Reads keep working throughout: until an object is
+// deleted it is read directly, and afterwards Read finds its record in the
+// segment. Objects left behind by an interrupted pass are deleted by the next
+// one.
+//
+// Only records without a schema, timestamp, user metadata, partition key,
+// dependencies, snapshot tag or chunking are merged, since a micro-batch
+// cannot hold those. Records written with WithoutOffsetHeader are not merged
+// either: their checksum covers the data alone, and the checksum computed for
+// a record inside a segment would differ from the one returned when it was
+// appended.
+//
+// Each run is read and merged while holding the lock that appends take, so
+// appends through this S3WAL wait for at most one run and never interleave
+// with a merge. Writers in other processes are not excluded, so only compact
+// a log from the process that appends to it.
+//
+// A pass holds the compaction lease, an object under the log prefix, so that
+// only one process compacts a log at a time. While another process holds it,
+// Compact fails with ErrLeaseHeld without changing anything. The lease is
+// renewed as the pass goes, and a pass that finds it taken over stops. The
+// lease of a crashed process expires a minute after it was last renewed.
+func (w *S3WAL) Compact(ctx context.Context, policy CompactionPolicy) (CompactionStats, error) {
+	return w.compact(ctx, policy, false)
+}
+
+// CompactAndVerify is Compact that verifies every step before deleting
+// anything. Source objects are downloaded bypassing the read cache and must
+// pass checksum validation, even with WithChecksumOptional, or the pass
+// stops. Each segment is downloaded again after it is written and compared
+// record by record with its sources; on a mismatch the segment is replaced by
+// the first source record again, no source is deleted, and the pass stops
+// with ErrCompactionMismatch.
+func (w *S3WAL) CompactAndVerify(ctx context.Context, policy CompactionPolicy) (CompactionStats, error) {
+	return w.compact(ctx, policy, true)
+}
+
+func (w *S3WAL) compact(ctx context.Context, policy CompactionPolicy, verify bool) (CompactionStats, error) {
+	if policy.MaxObjectSize <= 0 {
+		return CompactionStats{}, fmt.Errorf("compaction max object size must be positive")
+	}
+	maxRecords := policy.MaxRecords
+	if maxRecords == 0 {
+		maxRecords = MaxMicroBatchRecords
+	}
+	if maxRecords < 2 || maxRecords > MaxMicroBatchRecords {
+		return CompactionStats{}, fmt.Errorf("compaction max records must be between 2 and %d, got %d", MaxMicroBatchRecords, maxRecords)
+	}
+	cutoff := w.now().Add(-policy.MinAge)
+	l, err := w.acquireLease(ctx, w.compactionLeaseKey())
+	if err != nil {
+		return CompactionStats{}, err
+	}
+
+	// w.mu is held from the first download of a run until the run has been
+	// merged, so that no append changes the log in between.
+	locked := false
+	unlock := func() {
+		if locked {
+			w.mu.Unlock()
+			locked = false
+		}
+	}
+	defer unlock()
+
+	var stats CompactionStats
+	var run []compactionCandidate
+	flush := func() error {
+		defer func() { run = run[:0] }()
+		if len(run) < 2 {
+			return nil
+		}
+		if err := w.renewLease(ctx, l); err != nil {
+			return err
+		}
+		reclaimed, err := w.mergeRun(ctx, run, verify)
+		if err != nil {
+			return err
+		}
+		stats.ObjectsMerged += int64(len(run))
+		stats.BytesReclaimed += reclaimed
+		return nil
+	}
+
+	var batchEnd uint64
+	visit := func(offset uint64, obj types.Object) error {
+		if offset <= batchEnd {
+			// left behind by an interrupted merge into the preceding segment
+			if err := w.renewLease(ctx, l); err != nil {
+				return err
+			}
+			stats.BytesReclaimed += aws.ToInt64(obj.Size)
+			return w.deleteObject(ctx, offset)
+		}
+		if aws.ToInt64(obj.Size) > policy.MaxObjectSize || aws.ToTime(obj.LastModified).After(cutoff) {
+			return flush()
+		}
+		if !locked {
+			w.mu.Lock()
+			locked = true
+		}
+		fetch := w.fetchObject
+		if verify {
+			fetch = w.fetchVerified
+		}
+		fetched, err := fetch(ctx, offset)
+		if err != nil {
+			return err
+		}
+		if fetched.isMicroBatch() {
+			n, err := strconv.ParseUint(fetched.metadata[metaMicroBatch], 10, 64)
+			if err != nil || n == 0 {
+				return fmt.Errorf("%w: bad micro-batch record count %q", ErrInvalidRecord, fetched.metadata[metaMicroBatch])
+			}
+			batchEnd = offset + n - 1
+			return flush()
+		}
+		if fetched.header.flags() != 0 || len(userMetadata(fetched.metadata)) > 0 || fetched.metadata[metaPartitionKey] != "" || fetched.metadata[metaDependsOn] != "" || fetched.metadata[metaSnapshotOf] != "" || fetched.metadata[metaNoOffsetHeader] != "" {
+			return flush()
+		}
+		if len(run) > 0 && run[len(run)-1].offset+1 != offset {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		run = append(run, compactionCandidate{offset: offset, size: aws.ToInt64(obj.Size), etag: obj.ETag, data: fetched.payload})
+		if len(run) == maxRecords {
+			return flush()
+		}
+		return nil
+	}
+	err = w.forEachObject(ctx, func(offset uint64, obj types.Object) error {
+		err := visit(offset, obj)
+		if len(run) == 0 {
+			unlock()
+		}
+		return err
+	})
+	if err == nil {
+		err = flush()
+	}
+	unlock()
+	w.forgetStored()
+	err = errors.Join(err, w.releaseLease(context.WithoutCancel(ctx), l))
+
+	w.compaction.runs.Add(1)
+	w.compaction.merged.Add(stats.ObjectsMerged)
+	w.compaction.reclaimed.Add(stats.BytesReclaimed)
+	stats.Runs = 1
+	return stats, err
+}
+
+// fetchVerified downloads and decodes the object at offset, with none of the
+// fallbacks of fetchObject: no cache, retries, repair or legacy frames.
+func (w *S3WAL) fetchVerified(ctx context.Context, offset uint64) (fetchedObject, error) {
+	got, err := w.getObject(ctx, w.getObjectKey(offset), "")
+	if err != nil {
+		return fetchedObject{}, err
+	}
+	return decodeObject(offset, got)
+}
+
+// mergeRun writes run as one segment and returns the number of bytes saved.
+// With verify, the segment is checked against run before any of the other
+// objects are deleted.
+func (w *S3WAL) mergeRun(ctx context.Context, run []compactionCandidate, verify bool) (int64, error) {
+	first := run[0].offset
+	records := make([][]byte, len(run))
+	var size int64
+	for i, c := range run {
+		records[i] = c.data
+		size += c.size
+	}
+	buf, err := prepareBody(first, encodeMicroBatch(first, records))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare object body: %w", err)
+	}
+	if err := w.markMicroBatches(ctx); err != nil {
+		return 0, err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(w.bucketName),
+		Key:      aws.String(w.getObjectKey(first)),
+		Body:     bytes.NewReader(buf),
+		Metadata: map[string]string{metaMicroBatch: strconv.Itoa(len(run))},
+	}
+	if !w.noPreconditions {
+		input.IfMatch = run[0].etag
+	}
+	w.applyPutOptions(input)
+	if _, err := w.client.PutObject(ctx, input); err != nil {
+		return 0, fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	w.readCache.remove(first)
+	if verify {
+		if err := w.verifySegment(ctx, run); err != nil {
+			return 0, errors.Join(err, w.restoreRecord(ctx, first, run[0].data))
+		}
+	}
+	for _, c := range run[1:] {
+		if err := w.deleteObject(ctx, c.offset); err != nil {
+			return 0, err
+		}
+	}
+	return size - int64(len(buf)), nil
+}
+
+// verifySegment downloads the segment written for run and checks that it
+// holds exactly the records of run.
+func (w *S3WAL) verifySegment(ctx context.Context, run []compactionCandidate) error {
+	first := run[0].offset
+	obj, err := w.fetchVerified(ctx, first)
+	if err != nil {
+		return fmt.Errorf("failed to verify segment at offset %d: %w", first, err)
+	}
+	records, err := decodeMicroBatch(first, obj.payload)
+	if err != nil {
+		return fmt.Errorf("failed to verify segment at offset %d: %w", first, err)
+	}
+	if !obj.isMicroBatch() || len(records) != len(run) {
+		return fmt.Errorf("%w: segment at offset %d holds %d records, expected %d", ErrCompactionMismatch, first, len(records), len(run))
+	}
+	for i, record := range records {
+		if record.Offset != run[i].offset || !bytes.Equal(record.Data, run[i].data) {
+			return fmt.Errorf("%w: record %d of segment at offset %d", ErrCompactionMismatch, run[i].offset, first)
+		}
+	}
+	return nil
+}
+
+// restoreRecord writes data back as the plain record at offset, replacing a
+// segment that failed verification.
+func (w *S3WAL) restoreRecord(ctx context.Context, offset uint64, data []byte) error {
+	buf, metadata, err := w.frameRecord(frameHeader{offset: offset}, nil, data)
+	if err != nil {
+		return fmt.Errorf("failed to prepare object body: %w", err)
+	}
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(w.bucketName),
+		Key:      aws.String(w.getObjectKey(offset)),
+		Body:     bytes.NewReader(buf),
+		Metadata: metadata,
+	}
+	w.applyPutOptions(input)
+	if _, err := w.client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to restore record at offset %d: %w", offset, err)
+	}
+	return nil
+}
+
+func (w *S3WAL) deleteObject(ctx context.Context, offset uint64) error {
+	_, err := w.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.getObjectKey(offset)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object from S3: %w", err)
+	}
+	return nil
+}
+
+// StartCompactor runs Compact with policy every policy.Interval in a
+// background goroutine until ctx is cancelled or the WAL is closed. A pass
+// in progress when the WAL is closed is cancelled, which is safe: the next
+// pass finishes what it left behind. Errors are logged and retried on the
+// next tick; a pass skipped because another process holds the compaction
+// lease is only logged at debug level. Progress is reported by CompactionStats.
+func (w *S3WAL) StartCompactor(ctx context.Context, policy CompactionPolicy) error {
+	if policy.Interval <= 0 {
+		return fmt.Errorf("compaction interval must be positive")
+	}
+	if policy.MaxObjectSize <= 0 {
+		return fmt.Errorf("compaction max object size must be positive")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-w.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	w.workers.Add(1)
+	go func() {
+		defer w.workers.Done()
+		defer cancel()
+		ticker := time.NewTicker(policy.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			_, err := w.Compact(ctx, policy)
+			switch {
+			case err == nil || ctx.Err() != nil:
+			case errors.Is(err, ErrLeaseHeld):
+				w.logger.Debug("s3log: compaction skipped", "reason", err)
+			default:
+				w.logger.Warn("s3log: compaction failed", "error", err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,254 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestCompact(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	expected := make(map[uint64]string)
+	appendRecords := func(n int) {
+		for i := 0; i < n; i++ {
+			data := fmt.Sprintf("record %d", len(expected)+1)
+			offset, err := wal.Append(ctx, []byte(data))
+			if err != nil {
+				t.Fatalf("failed to append: %v", err)
+			}
+			expected[offset] = data
+		}
+	}
+	appendRecords(5)
+	if _, err := wal.AppendWithSchema(ctx, "v1", []byte("record 6")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	expected[6] = "record 6"
+	appendRecords(3)
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("record 10"), []byte("record 11")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	expected[10], expected[11] = "record 10", "record 11"
+	appendRecords(1)
+
+	before, err := wal.Stats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	policy := CompactionPolicy{MaxObjectSize: 1024, MaxRecords: 3}
+	stats, err := wal.Compact(ctx, policy)
+	if err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	after, err := wal.Stats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.ObjectsMerged != 8 || stats.BytesReclaimed != before.Bytes-after.Bytes {
+		t.Errorf("unexpected compaction stats: %+v, bytes went from %d to %d", stats, before.Bytes, after.Bytes)
+	}
+	if stats, err := wal.Stats(ctx); err != nil || stats.Objects != 6 {
+		t.Errorf("expected 6 objects after compaction, got %d (%v)", stats.Objects, err)
+	}
+	for offset, data := range expected {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if string(record.Data) != data {
+			t.Errorf("offset %d: expected %q, got %q", offset, data, record.Data)
+		}
+	}
+
+	// an object left behind by an interrupted merge
+	body, _ := prepareBody(2, []byte("record 2"))
+	_, err = wal.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(wal.getObjectKey(2)),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		t.Fatalf("failed to put leftover object: %v", err)
+	}
+	if _, err := wal.Compact(ctx, policy); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	if stats, err := wal.Stats(ctx); err != nil || stats.Objects != 6 {
+		t.Errorf("expected leftover object to be deleted, got %d objects (%v)", stats.Objects, err)
+	}
+	if total := wal.CompactionStats(); total.Runs != 2 || total.ObjectsMerged != 8 {
+		t.Errorf("unexpected total compaction stats: %+v", total)
+	}
+
+	if _, err := wal.Compact(ctx, CompactionPolicy{}); err == nil {
+		t.Error("expected error for zero max object size, got nil")
+	}
+}
+
+// segmentCorruptingClient alters the first record of every micro-batch it
+// writes while keeping the frame valid, as a buggy encoder would.
+type segmentCorruptingClient struct {
+	S3Client
+}
+
+func (c segmentCorruptingClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if _, ok := params.Metadata[metaMicroBatch]; ok {
+		body, err := io.ReadAll(params.Body)
+		if err != nil {
+			return nil, err
+		}
+		payload := bytes.Clone(body[8 : len(body)-32])
+		payload[0] ^= 1
+		corrupted, _ := prepareBody(binary.BigEndian.Uint64(body), payload)
+		params.Body = bytes.NewReader(corrupted)
+	}
+	return c.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func TestCompactAndVerify(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 4; i++ {
+		if _, err := base.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	policy := CompactionPolicy{MaxObjectSize: 1024, MaxRecords: 2}
+
+	broken := NewS3WAL(segmentCorruptingClient{base.client}, base.bucketName, base.prefix)
+	if _, err := broken.CompactAndVerify(ctx, policy); !errors.Is(err, ErrCompactionMismatch) {
+		t.Fatalf("expected ErrCompactionMismatch, got %v", err)
+	}
+	if stats, err := base.Stats(ctx); err != nil || stats.Objects != 4 {
+		t.Errorf("expected no source to be deleted, got %d objects (%v)", stats.Objects, err)
+	}
+	for offset := uint64(1); offset <= 4; offset++ {
+		record, err := base.Read(ctx, offset)
+		if err != nil || string(record.Data) != fmt.Sprintf("record %d", offset) {
+			t.Errorf("offset %d: expected the original record, got %q (%v)", offset, record.Data, err)
+		}
+	}
+
+	stats, err := base.CompactAndVerify(ctx, policy)
+	if err != nil || stats.ObjectsMerged != 4 {
+		t.Fatalf("expected 4 objects merged, got %+v (%v)", stats, err)
+	}
+	if stats, err := base.Stats(ctx); err != nil || stats.Objects != 2 {
+		t.Errorf("expected 2 segments, got %d objects (%v)", stats.Objects, err)
+	}
+	corruptRecord(t, base, 3)
+	if _, err := base.CompactAndVerify(ctx, CompactionPolicy{MaxObjectSize: 1024}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for a corrupt source, got %v", err)
+	}
+}
+
+func TestStartCompactor(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if _, err := wal.Append(ctx, []byte("small")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if err := wal.StartCompactor(ctx, CompactionPolicy{MaxObjectSize: 1024}); err == nil {
+		t.Error("expected error for zero interval, got nil")
+	}
+	err := wal.StartCompactor(ctx, CompactionPolicy{Interval: 10 * time.Millisecond, MaxObjectSize: 1024})
+	if err != nil {
+		t.Fatalf("failed to start compactor: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for wal.CompactionStats().ObjectsMerged != 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("compactor made no progress: %+v", wal.CompactionStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := wal.Close(ctx); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	runs := wal.CompactionStats().Runs
+	time.Sleep(50 * time.Millisecond)
+	if wal.CompactionStats().Runs != runs {
+		t.Error("expected compactor to stop on Close")
+	}
+}
+
+func TestCompactWithConcurrentAppends(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 20; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	done := make(chan error)
+	go func() {
+		for i := 21; i <= 40; i++ {
+			if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	if _, err := wal.Compact(ctx, CompactionPolicy{MaxObjectSize: 1024, MaxRecords: 5}); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("failed to append during compaction: %v", err)
+	}
+	for i := uint64(1); i <= 40; i++ {
+		record, err := wal.Read(ctx, i)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", i, err)
+		}
+		if want := fmt.Sprintf("record %d", i); string(record.Data) != want {
+			t.Errorf("offset %d: expected %q, got %q", i, want, record.Data)
+		}
+	}
+}
+
+func TestCompactSkipsOffsetlessRecords(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithoutOffsetHeader())
+
+	for i := 0; i < 3; i++ {
+		if _, err := wal.Append(ctx, []byte("offsetless")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	root, err := wal.MerkleRoot(ctx, 1, 3)
+	if err != nil {
+		t.Fatalf("failed to compute Merkle root: %v", err)
+	}
+	stats, err := wal.Compact(ctx, CompactionPolicy{MaxObjectSize: 1024})
+	if err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	if stats.ObjectsMerged != 0 {
+		t.Errorf("expected offsetless records to be skipped, merged %d", stats.ObjectsMerged)
+	}
+	if after, err := wal.MerkleRoot(ctx, 1, 3); err != nil || !bytes.Equal(after, root) {
+		t.Errorf("expected Merkle root to be unchanged, got %x (%v)", after, err)
+	}
+}
@@ -0,0 +1,118 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Segment is a range of offsets of a CompositeWAL stored by one S3WAL, e.g.
+// in one bucket. Records keep their offsets in every segment: a segment
+// starting at 1001 stores its first record under the key of offset 1001.
+type Segment struct {
+	WAL *S3WAL
+	// Start is the first offset of the segment. The segment ends where the
+	// next one starts, and the last segment is open-ended.
+	Start uint64
+}
+
+// CompositeWAL reads a log whose contiguous offset ranges are stored by
+// different S3WALs, such as the old and new bucket of a migration, as a
+// single log. Reads are routed to the segment holding the offset, and appends
+// go to the last segment.
+type CompositeWAL struct {
+	segments []Segment
+}
+
+var _ WAL = (*CompositeWAL)(nil)
+
+// NewCompositeWAL returns a CompositeWAL over segments, which must be in
+// ascending order of their Start.
+func NewCompositeWAL(segments ...Segment) *CompositeWAL {
+	if len(segments) == 0 {
+		panic("s3log: composite log needs at least one segment")
+	}
+	for i, s := range segments {
+		if s.WAL == nil || s.Start == 0 {
+			panic(fmt.Sprintf("s3log: segment %d needs a WAL and a start offset", i))
+		}
+		if i > 0 && s.Start <= segments[i-1].Start {
+			panic(fmt.Sprintf("s3log: segment %d starts at %d, not after %d", i, s.Start, segments[i-1].Start))
+		}
+	}
+	return &CompositeWAL{segments: segments}
+}
+
+// segment returns the index of the segment holding offset.
+func (c *CompositeWAL) segment(offset uint64) (int, error) {
+	i := sort.Search(len(c.segments), func(i int) bool {
+		return c.segments[i].Start > offset
+	}) - 1
+	if i < 0 {
+		return 0, fmt.Errorf("offset %d is before the first segment, which starts at %d", offset, c.segments[0].Start)
+	}
+	return i, nil
+}
+
+// Append appends data to the last segment. If that segment has not appended
+// anything yet, its first record gets the segment's start offset; an S3WAL
+// taking over a segment that already holds records must be reconciled first,
+// as with any S3WAL opened on an existing log.
+func (c *CompositeWAL) Append(ctx context.Context, data []byte) (uint64, error) {
+	last := c.segments[len(c.segments)-1]
+	last.WAL.raiseLength(last.Start - 1)
+	return last.WAL.Append(ctx, data)
+}
+
+// Read reads the record at offset from the segment holding it.
+func (c *CompositeWAL) Read(ctx context.Context, offset uint64) (Record, error) {
+	i, err := c.segment(offset)
+	if err != nil {
+		return Record{}, err
+	}
+	return c.segments[i].WAL.Read(ctx, offset)
+}
+
+// LastRecord returns the last record of the last segment, or of the latest
+// segment before it holding any, e.g. while the newest bucket of a migration
+// is still empty.
+func (c *CompositeWAL) LastRecord(ctx context.Context) (Record, error) {
+	for i := len(c.segments) - 1; i >= 0; i-- {
+		record, err := c.segments[i].WAL.LastRecord(ctx)
+		if !errors.Is(err, ErrWALEmpty) {
+			return record, err
+		}
+	}
+	return Record{}, ErrWALEmpty
+}
+
+// ReadOrdered calls handler with the records at offsets [start, end] in
+// ascending offset order, reading each segment's part of the range with the
+// segment's ReadOrdered.
+func (c *CompositeWAL) ReadOrdered(ctx context.Context, start, end uint64, handler func(Record) error) error {
+	if start > end {
+		return fmt.Errorf("start offset %d is after end offset %d", start, end)
+	}
+	i, err := c.segment(start)
+	if err != nil {
+		return err
+	}
+	for ; i < len(c.segments) && c.segments[i].Start <= end; i++ {
+		from, to := max(start, c.segments[i].Start), end
+		if i+1 < len(c.segments) {
+			to = min(end, c.segments[i+1].Start-1)
+		}
+		if err := c.segments[i].WAL.ReadOrdered(ctx, from, to, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// raiseLength moves the log's length up to length if it is below it.
+func (w *S3WAL) raiseLength(length uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.length = max(w.length, length)
+}
@@ -0,0 +1,63 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCompositeWAL(t *testing.T) {
+	old, cleanupOld := getWAL(t)
+	defer cleanupOld()
+	current, cleanupCurrent := getWAL(t)
+	defer cleanupCurrent()
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := old.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	wal := NewCompositeWAL(Segment{WAL: old, Start: 1}, Segment{WAL: current, Start: 4})
+	if record, err := wal.LastRecord(ctx); err != nil || record.Offset != 3 {
+		t.Errorf("expected last record 3 while the new segment is empty, got %d (%v)", record.Offset, err)
+	}
+
+	for i := 4; i <= 5; i++ {
+		offset, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i)))
+		if err != nil || offset != uint64(i) {
+			t.Fatalf("expected offset %d, got %d (%v)", i, offset, err)
+		}
+	}
+	if _, err := old.Read(ctx, 4); err == nil {
+		t.Error("expected appends to go to the last segment")
+	}
+	if record, err := wal.LastRecord(ctx); err != nil || record.Offset != 5 {
+		t.Errorf("expected last record 5, got %d (%v)", record.Offset, err)
+	}
+	for _, offset := range []uint64{2, 4} {
+		record, err := wal.Read(ctx, offset)
+		if err != nil || string(record.Data) != fmt.Sprintf("record %d", offset) {
+			t.Errorf("offset %d: unexpected record %q (%v)", offset, record.Data, err)
+		}
+	}
+	if _, err := wal.Read(ctx, 0); err == nil {
+		t.Error("expected error reading before the first segment")
+	}
+
+	var got []uint64
+	err := wal.ReadOrdered(ctx, 2, 5, func(record Record) error {
+		got = append(got, record.Offset)
+		return nil
+	})
+	if err != nil || fmt.Sprint(got) != "[2 3 4 5]" {
+		t.Errorf("expected offsets [2 3 4 5], got %v (%v)", got, err)
+	}
+
+	empty, cleanupEmpty := getWAL(t)
+	defer cleanupEmpty()
+	if _, err := NewCompositeWAL(Segment{WAL: empty, Start: 1}).LastRecord(ctx); !errors.Is(err, ErrWALEmpty) {
+		t.Errorf("expected ErrWALEmpty, got %v", err)
+	}
+}
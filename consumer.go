@@ -0,0 +1,250 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// checkpointKey returns the key of the checkpoint of the named consumer.
+// Checkpoints live next to the log, like blobs, so they are never listed as
+// records.
+func (w *S3WAL) checkpointKey(name string) string {
+	return w.prefix + ".checkpoints/" + name
+}
+
+// Checkpoint returns the last offset committed for the named consumer, or 0
+// if it has never committed.
+func (w *S3WAL) Checkpoint(ctx context.Context, name string) (uint64, error) {
+	result, err := w.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.checkpointKey(name)),
+	})
+	var nsk *types.NoSuchKey
+	if errors.As(err, &nsk) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get object from s3: %w", err)
+	}
+	defer result.Body.Close()
+	body, err := io.ReadAll(result.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read object body: %w", err)
+	}
+	offset, err := strconv.ParseUint(string(body), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad checkpoint %q for consumer %s: %w", body, name, err)
+	}
+	return offset, nil
+}
+
+// CommitCheckpoint durably records offset as the last offset processed by the
+// named consumer.
+func (w *S3WAL) CommitCheckpoint(ctx context.Context, name string, offset uint64) error {
+	_, err := w.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.checkpointKey(name)),
+		Body:   bytes.NewReader([]byte(strconv.FormatUint(offset, 10))),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	return nil
+}
+
+// defaultPollInterval is how often a caught-up reader following the log
+// looks for new records.
+var defaultPollInterval = time.Second
+
+// HandlerErrorPolicy selects what Consume does when the handler fails.
+type HandlerErrorPolicy int
+
+const (
+	// StopOnError commits the progress made before the failing record and
+	// returns the handler's error.
+	StopOnError HandlerErrorPolicy = iota
+	// RetryOnError calls the handler again, up to MaxRetries times, before
+	// stopping as StopOnError does.
+	RetryOnError
+	// SkipOnError logs the error and moves on to the next record.
+	SkipOnError
+)
+
+// ConsumeOptions configures Consume.
+type ConsumeOptions struct {
+	// Name identifies the consumer's checkpoint. It is required.
+	Name string
+	// StartOffset is where a consumer without a checkpoint starts. Zero
+	// means the beginning of the log.
+	StartOffset uint64
+	// CommitEvery commits the checkpoint after this many records. Zero
+	// means 100.
+	CommitEvery int
+	// CommitInterval, if positive, also commits the checkpoint when this
+	// much time has passed since the last commit.
+	CommitInterval time.Duration
+	// PollInterval is how long Consume waits before looking for new records
+	// once it has caught up with the log. Zero means defaultPollInterval.
+	PollInterval time.Duration
+	// OnError is the policy applied to handler errors.
+	OnError HandlerErrorPolicy
+	// MaxRetries is the number of retries of RetryOnError.
+	MaxRetries int
+	// RetryBackoff is the wait between retries of RetryOnError.
+	RetryBackoff time.Duration
+}
+
+// Consume calls handler with every record of the log in offset order,
+// starting after the consumer's checkpoint, and keeps following the log as
+// records are appended until ctx is cancelled. Progress is committed every
+// CommitEvery records, every CommitInterval, whenever Consume catches up with
+// the log and before it returns. A record is only covered by a commit once
+// the handler has succeeded on it, or skipped it under SkipOnError, so after
+// a crash the records since the last commit are delivered again: handlers
+// must be idempotent.
+//
+// Consume returns ctx's error once ctx is cancelled, or the handler's error
+// under StopOnError and RetryOnError.
+func (w *S3WAL) Consume(ctx context.Context, handler func(Record) error, opts ConsumeOptions) error {
+	if opts.Name == "" {
+		return fmt.Errorf("consumer name is required")
+	}
+	commitEvery := opts.CommitEvery
+	if commitEvery <= 0 {
+		commitEvery = 100
+	}
+	poll := opts.PollInterval
+	if poll <= 0 {
+		poll = defaultPollInterval
+	}
+
+	committed, err := w.Checkpoint(ctx, opts.Name)
+	if err != nil {
+		return err
+	}
+	next := max(opts.StartOffset, 1)
+	if committed > 0 {
+		next = committed + 1
+	}
+	processed := committed
+	pending := 0
+	lastCommit := time.Now()
+	commit := func(ctx context.Context) error {
+		if processed == committed {
+			return nil
+		}
+		if err := w.CommitCheckpoint(ctx, opts.Name, processed); err != nil {
+			return err
+		}
+		committed, pending, lastCommit = processed, 0, time.Now()
+		return nil
+	}
+	stop := func(err error) error {
+		return errors.Join(err, commit(context.WithoutCancel(ctx)))
+	}
+
+	for {
+		records, err := w.recordsFrom(ctx, next)
+		if err != nil {
+			return stop(err)
+		}
+		if len(records) == 0 {
+			if err := commit(ctx); err != nil {
+				return stop(err)
+			}
+			select {
+			case <-ctx.Done():
+				return stop(ctx.Err())
+			case <-time.After(poll):
+			}
+			continue
+		}
+		for _, record := range records {
+			if record.Offset < next {
+				continue
+			}
+			if err := w.handle(ctx, handler, record, opts); err != nil {
+				return stop(err)
+			}
+			processed, next = record.Offset, record.Offset+1
+			pending++
+			if pending >= commitEvery || (opts.CommitInterval > 0 && time.Since(lastCommit) >= opts.CommitInterval) {
+				if err := commit(ctx); err != nil {
+					return stop(err)
+				}
+			}
+			if ctx.Err() != nil {
+				return stop(ctx.Err())
+			}
+		}
+	}
+}
+
+func (w *S3WAL) handle(ctx context.Context, handler func(Record) error, record Record, opts ConsumeOptions) error {
+	if w.skipRecord(record) {
+		return nil
+	}
+	err := handler(record)
+	for retry := 0; err != nil && opts.OnError == RetryOnError && retry < opts.MaxRetries; retry++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.RetryBackoff):
+		}
+		err = handler(record)
+	}
+	if err != nil && opts.OnError == SkipOnError {
+		w.logger.Warn("s3log: consumer skipped record", "consumer", opts.Name, "offset", record.Offset, "error", err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("handler failed on offset %d: %w", record.Offset, err)
+	}
+	return nil
+}
+
+// recordsFrom returns the records of the object holding offset or, if there
+// is none, of the first object after it. It returns no records once offset is
+// past the end of the log.
+func (w *S3WAL) recordsFrom(ctx context.Context, offset uint64) ([]Record, error) {
+	records, err := w.readObject(ctx, offset)
+	var nsk *types.NoSuchKey
+	if !errors.As(err, &nsk) {
+		return records, err
+	}
+	first, found, err := w.findMicroBatch(ctx, offset)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return w.readObject(ctx, first)
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:     aws.String(w.bucketName),
+		Prefix:     aws.String(w.keyPrefix()),
+		StartAfter: aws.String(w.getObjectKey(offset)),
+	}
+	paginator := s3.NewListObjectsV2Paginator(w.client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects from s3: %w", err)
+		}
+		for _, obj := range output.Contents {
+			if next, err := w.getOffsetFromKey(*obj.Key); err == nil {
+				return w.readObject(ctx, next)
+			}
+		}
+	}
+	return nil, nil
+}
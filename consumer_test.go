@@ -0,0 +1,111 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsume(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("record 4"), []byte("record 5")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+
+	var mu sync.Mutex
+	var seen []uint64
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- wal.Consume(runCtx, func(r Record) error {
+			if string(r.Data) != fmt.Sprintf("record %d", r.Offset) {
+				return fmt.Errorf("unexpected data %q at offset %d", r.Data, r.Offset)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, r.Offset)
+			if len(seen) == 6 {
+				cancel()
+			}
+			return nil
+		}, ConsumeOptions{Name: "indexer", CommitEvery: 2, PollInterval: 10 * time.Millisecond})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := wal.Append(ctx, []byte("record 6")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if fmt.Sprint(seen) != "[1 2 3 4 5 6]" {
+		t.Errorf("unexpected records: %v", seen)
+	}
+	if checkpoint, err := wal.Checkpoint(ctx, "indexer"); err != nil || checkpoint != 6 {
+		t.Errorf("expected checkpoint 6, got %d (%v)", checkpoint, err)
+	}
+
+	// resumes after the checkpoint
+	if _, err := wal.Append(ctx, []byte("record 7")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	failing := errors.New("handler failure")
+	calls := 0
+	err := wal.Consume(ctx, func(r Record) error {
+		if r.Offset != 7 {
+			t.Errorf("expected to resume at 7, got %d", r.Offset)
+		}
+		calls++
+		return failing
+	}, ConsumeOptions{Name: "indexer", OnError: RetryOnError, MaxRetries: 2})
+	if !errors.Is(err, failing) || calls != 3 {
+		t.Errorf("expected the handler error after 3 calls, got %v after %d", err, calls)
+	}
+	if checkpoint, _ := wal.Checkpoint(ctx, "indexer"); checkpoint != 6 {
+		t.Errorf("expected checkpoint to stay at 6, got %d", checkpoint)
+	}
+}
+
+func TestConsumeSkipOnError(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, data := range []string{"good", "bad", "good"} {
+		if _, err := wal.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	var handled []uint64
+	err := wal.Consume(runCtx, func(r Record) error {
+		if r.Offset == 3 {
+			cancel()
+		}
+		if string(r.Data) == "bad" {
+			return errors.New("cannot process")
+		}
+		handled = append(handled, r.Offset)
+		return nil
+	}, ConsumeOptions{Name: "skipper", StartOffset: 1, OnError: SkipOnError})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if fmt.Sprint(handled) != "[1 3]" {
+		t.Errorf("unexpected handled records: %v", handled)
+	}
+	if checkpoint, _ := wal.Checkpoint(ctx, "skipper"); checkpoint != 3 {
+		t.Errorf("expected checkpoint 3, got %d", checkpoint)
+	}
+}
@@ -0,0 +1,120 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WithContentKeying deduplicates appends by payload. Before a record is
+// written, its offset is claimed under a key derived from the payload with
+// the BlobHash (see WithBlobHash), using a conditional put. If the payload
+// was appended before, the claim collides, nothing is written and Append
+// returns the offset of the earlier record.
+//
+// Records keep their sequential keys and the claims live outside the log
+// prefix, so listing, LastRecord and the ordering of records are unaffected.
+// What changes is that Append may return an offset lower than the ones it
+// returned before. Only the payload is hashed: appending the same data with
+// a different schema or metadata is still a duplicate. Micro-batches and
+// streamed appends are not deduplicated.
+func WithContentKeying() Option {
+	return func(w *S3WAL) {
+		w.contentKeying = true
+	}
+}
+
+// isPreconditionFailed reports whether a conditional request failed because
+// of the state of the object.
+func isPreconditionFailed(err error) bool {
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusPreconditionFailed {
+		return true
+	}
+	return errors.Is(err, errObjectExists)
+}
+
+// claimContent claims data for offset. If data is already claimed by a
+// record that holds it, it returns that record's offset and false. A claim
+// whose record is missing, because the writer that made it failed before
+// writing the record, is taken over, and so is one whose offset has since
+// been taken by a record with other data.
+func (w *S3WAL) claimContent(ctx context.Context, offset uint64, data []byte) (uint64, bool, error) {
+	key := w.blobKey(data)
+	ifNoneMatch, err := w.ifNoneMatch(ctx, key)
+	if err == nil {
+		err = w.putClaim(ctx, key, offset, ifNoneMatch, nil)
+	}
+	if err == nil {
+		return offset, true, nil
+	}
+	if !isPreconditionFailed(err) {
+		return 0, false, err
+	}
+
+	result, err := w.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get object from s3: %w", err)
+	}
+	defer result.Body.Close()
+	var claimed uint64
+	if err := binary.Read(result.Body, binary.BigEndian, &claimed); err != nil {
+		return 0, false, fmt.Errorf("%w: bad content claim %s: %v", ErrInvalidRecord, key, err)
+	}
+
+	holds, err := w.recordHolds(ctx, claimed, data)
+	if err != nil {
+		return 0, false, err
+	}
+	if holds {
+		return claimed, false, nil
+	}
+	if err := w.putClaim(ctx, key, offset, nil, result.ETag); err != nil {
+		return 0, false, err
+	}
+	return offset, true, nil
+}
+
+func (w *S3WAL) putClaim(ctx context.Context, key string, offset uint64, ifNoneMatch, ifMatch *string) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(w.bucketName),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(binary.BigEndian.AppendUint64(nil, offset)),
+		IfNoneMatch: ifNoneMatch,
+		IfMatch:     ifMatch,
+	}
+	w.applyPutOptions(input)
+	if _, err := w.client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	return nil
+}
+
+// recordHolds reports whether the record at offset is stored, either in its
+// own object or in a micro-batch, e.g. one written by Compact, and has data as
+// its payload, compared by BlobHash.
+func (w *S3WAL) recordHolds(ctx context.Context, offset uint64, data []byte) (bool, error) {
+	record, err := w.read(ctx, offset)
+	var nsk *types.NoSuchKey
+	if errors.As(err, &nsk) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	h := w.blobHash
+	if h == nil {
+		h = SHA256BlobHash
+	}
+	return bytes.Equal(h.Sum(record.Data), h.Sum(data)), nil
+}
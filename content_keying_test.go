@@ -0,0 +1,91 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestContentKeying(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	WithContentKeying()(wal)
+
+	appendData := func(data string) uint64 {
+		t.Helper()
+		offset, err := wal.Append(ctx, []byte(data))
+		if err != nil {
+			t.Fatalf("failed to append %q: %v", data, err)
+		}
+		return offset
+	}
+	if offset := appendData("event-a"); offset != 1 {
+		t.Errorf("expected offset 1, got %d", offset)
+	}
+	if offset := appendData("event-b"); offset != 2 {
+		t.Errorf("expected offset 2, got %d", offset)
+	}
+	if offset := appendData("event-a"); offset != 1 {
+		t.Errorf("expected duplicate to return offset 1, got %d", offset)
+	}
+	if offset := appendData("event-c"); offset != 3 {
+		t.Errorf("expected offset 3, got %d", offset)
+	}
+
+	record, err := wal.LastRecord(ctx)
+	if err != nil || record.Offset != 3 {
+		t.Errorf("expected last record 3, got %d (%v)", record.Offset, err)
+	}
+
+	// a claim left behind by a writer that failed before writing its record
+	_, err = wal.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(wal.blobKey([]byte("event-d"))),
+		Body:   bytes.NewReader(binary.BigEndian.AppendUint64(nil, 99)),
+	})
+	if err != nil {
+		t.Fatalf("failed to put dangling claim: %v", err)
+	}
+	if offset := appendData("event-d"); offset != 4 {
+		t.Errorf("expected dangling claim to be taken over at offset 4, got %d", offset)
+	}
+	if offset := appendData("event-d"); offset != 4 {
+		t.Errorf("expected duplicate to return offset 4, got %d", offset)
+	}
+}
+
+func TestContentKeyingFailedPut(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client := &putFailingClient{S3Client: base.client, key: base.getObjectKey(1)}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithContentKeying())
+	if _, err := wal.Append(ctx, []byte("A")); err == nil {
+		t.Fatal("expected the injected put failure")
+	}
+	if offset, err := wal.Append(ctx, []byte("B")); err != nil || offset != 1 {
+		t.Fatalf("expected B at offset 1, got %d (%v)", offset, err)
+	}
+	// A's claim names offset 1, which now holds B
+	offset, err := wal.Append(ctx, []byte("A"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if offset != 2 {
+		t.Errorf("expected the stale claim to be taken over at offset 2, got %d", offset)
+	}
+	for offset, want := range map[uint64]string{1: "B", 2: "A"} {
+		if record, err := wal.Read(ctx, offset); err != nil || string(record.Data) != want {
+			t.Errorf("offset %d: expected %q, got %q (%v)", offset, want, record.Data, err)
+		}
+	}
+	if offset, err := wal.Append(ctx, []byte("A")); err != nil || offset != 2 {
+		t.Errorf("expected duplicate to return offset 2, got %d (%v)", offset, err)
+	}
+}
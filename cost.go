@@ -0,0 +1,53 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+)
+
+// PricingTable holds the prices used by EstimateCost. Request prices follow
+// the S3 tiers: writes cover PUT, COPY, POST and LIST requests, reads cover
+// GET and HEAD requests.
+type PricingTable struct {
+	// StorageGBMonth is the price per GB-month keyed by storage class,
+	// e.g. "STANDARD" or "STANDARD_IA".
+	StorageGBMonth map[string]float64
+	WritesPer1000  float64
+	ReadsPer1000   float64
+}
+
+// CostBreakdown is the result of EstimateCost.
+type CostBreakdown struct {
+	StorageByClass map[string]float64
+	Storage        float64
+	Requests       float64
+	Total          float64
+}
+
+// EstimateCost combines Stats with the given pricing to estimate the monthly
+// storage cost of the log plus the cost of the requests issued so far. The
+// request cost is only an estimate based on the UsageStats recorded by this
+// S3WAL, not on what other clients or processes issued against the bucket.
+func (w *S3WAL) EstimateCost(ctx context.Context, pricing PricingTable) (CostBreakdown, error) {
+	stats, err := w.Stats(ctx)
+	if err != nil {
+		return CostBreakdown{}, err
+	}
+	breakdown := CostBreakdown{StorageByClass: make(map[string]float64)}
+	for class, bytes := range stats.BytesByStorageClass {
+		price, ok := pricing.StorageGBMonth[class]
+		if !ok {
+			return CostBreakdown{}, fmt.Errorf("no storage price for storage class %s", class)
+		}
+		cost := float64(bytes) / (1 << 30) * price
+		breakdown.StorageByClass[class] = cost
+		breakdown.Storage += cost
+	}
+
+	usage := w.UsageStats()
+	writes := usage.PutRequests + usage.ListRequests
+	reads := usage.GetRequests + usage.HeadRequests
+	breakdown.Requests = float64(writes)/1000*pricing.WritesPer1000 + float64(reads)/1000*pricing.ReadsPer1000
+	breakdown.Total = breakdown.Storage + breakdown.Requests
+	return breakdown, nil
+}
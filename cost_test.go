@@ -0,0 +1,62 @@
+package s3log
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestEstimateCost(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	data := make([]byte, 1<<20)
+	for i := 0; i < 4; i++ {
+		if _, err := wal.Append(ctx, data); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.Read(ctx, 1); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	usage := wal.UsageStats()
+	if usage.PutRequests != 4 || usage.GetRequests != 1 {
+		t.Errorf("unexpected usage stats: %+v", usage)
+	}
+
+	stats, err := wal.Stats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.Objects != 4 || stats.Bytes != 4*(1<<20+40) {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	pricing := PricingTable{
+		StorageGBMonth: map[string]float64{"STANDARD": 1024},
+		WritesPer1000:  5,
+		ReadsPer1000:   0.4,
+	}
+	breakdown, err := wal.EstimateCost(ctx, pricing)
+	if err != nil {
+		t.Fatalf("failed to estimate cost: %v", err)
+	}
+	// 4 MiB + framing at 1024 per GiB is just over 4
+	if breakdown.Storage < 4 || breakdown.Storage > 4.01 {
+		t.Errorf("unexpected storage cost: %f", breakdown.Storage)
+	}
+	// 4 puts and a list for each Stats call, plus 1 get
+	expected := 6.0/1000*5 + 1.0/1000*0.4
+	if math.Abs(breakdown.Requests-expected) > 1e-9 {
+		t.Errorf("expected request cost %f, got %f", expected, breakdown.Requests)
+	}
+	if breakdown.Total != breakdown.Storage+breakdown.Requests {
+		t.Errorf("total %f does not add up", breakdown.Total)
+	}
+
+	if _, err := wal.EstimateCost(ctx, PricingTable{}); err == nil {
+		t.Error("expected error for missing storage class price, got nil")
+	}
+}
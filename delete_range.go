@@ -0,0 +1,381 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// maxDeleteBatch is the most keys a single DeleteObjects request may name.
+const maxDeleteBatch = 1000
+
+// defaultDeleteConcurrency is the number of DeleteObjects requests in flight
+// unless DeleteOptions says otherwise.
+const defaultDeleteConcurrency = 4
+
+// DeleteOptions controls how DeleteRange and Truncate issue their deletes.
+type DeleteOptions struct {
+	// Concurrency is the number of DeleteObjects batches in flight at once.
+	// Zero means 4.
+	Concurrency int
+	// MaxRetries is the number of times the keys of a batch that failed
+	// transiently, e.g. with a 5xx or SlowDown, are retried.
+	MaxRetries int
+	// RetryBackoff is the wait before each retry.
+	RetryBackoff time.Duration
+}
+
+// DeleteBatchResult is the outcome of one DeleteObjects batch.
+type DeleteBatchResult struct {
+	Keys     int
+	Deleted  int
+	Attempts int
+	// Err joins the errors of the keys left undeleted, or is nil if all
+	// keys of the batch were deleted.
+	Err error
+}
+
+// DeleteReport summarizes a DeleteRange or Truncate call.
+type DeleteReport struct {
+	Deleted int
+	// Batches holds the result of every batch, in key order.
+	Batches []DeleteBatchResult
+	// Remaining lists the keys that could not be deleted, in key order. The
+	// deletion can be resumed by calling DeleteRange again, or by deleting
+	// these keys.
+	Remaining []string
+}
+
+// WithBatchDelete selects whether DeleteRange and Truncate delete with
+// DeleteObjects batches, the default, or with one DeleteObject request per
+// key, for S3-compatible stores lacking the batch API. Without it, a store
+// answering DeleteObjects with NotImplemented is detected on the first batch,
+// and every deletion after that uses DeleteObject.
+func WithBatchDelete(enabled bool) Option {
+	return func(w *S3WAL) {
+		w.noBatchDelete.Store(!enabled)
+	}
+}
+
+// isNotImplemented reports whether S3 rejected a request because it does not
+// support the operation.
+func isNotImplemented(err error) bool {
+	var apiErr smithy.APIError
+	var respErr interface{ HTTPStatusCode() int }
+	return (errors.As(err, &apiErr) && apiErr.ErrorCode() == "NotImplemented") ||
+		(errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotImplemented)
+}
+
+// errRangeEnd stops a listing once it has passed the end of a range.
+var errRangeEnd = errors.New("end of range")
+
+// DeleteRange deletes the record objects holding offsets [start, end] with
+// DeleteObjects batches of up to 1000 keys, sent Concurrency at a time while
+// the log is still being listed. Keys that fail transiently are retried; if
+// keys remain after that, they are listed in the report and an error joining
+// the failed batches' errors is returned. A micro-batch is only deleted if
+// all its records are in the range. The chunks of deleted chunked records are
+// deleted after them, in batches of their own. Deleting the newest records lets an
+// S3WAL that finds the tail by listing reuse their offsets.
+func (w *S3WAL) DeleteRange(ctx context.Context, start, end uint64, opts DeleteOptions) (DeleteReport, error) {
+	if start > end {
+		return DeleteReport{}, fmt.Errorf("start offset %d is after end offset %d", start, end)
+	}
+	defer w.forgetStored()
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultDeleteConcurrency
+	}
+
+	type batch struct {
+		index int
+		keys  []string
+	}
+	var (
+		mu        sync.Mutex
+		results   []DeleteBatchResult
+		remaining [][]string
+	)
+	batches := make(chan batch)
+	var workers sync.WaitGroup
+	for range concurrency {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for b := range batches {
+				result, left := w.deleteBatch(ctx, b.keys, opts)
+				mu.Lock()
+				results[b.index] = result
+				remaining[b.index] = left
+				mu.Unlock()
+			}
+		}()
+	}
+	send := func(keys []string) {
+		mu.Lock()
+		index := len(results)
+		results = append(results, DeleteBatchResult{})
+		remaining = append(remaining, nil)
+		mu.Unlock()
+		batches <- batch{index: index, keys: keys}
+	}
+
+	// the last object in the range may be a micro-batch extending past end,
+	// so each key is held back until the next one is seen
+	var pending []string
+	var held string
+	var heldOffset uint64
+	err := w.forEachObject(ctx, func(offset uint64, obj types.Object) error {
+		if offset < start {
+			return nil
+		}
+		if offset > end {
+			return errRangeEnd
+		}
+		if held != "" {
+			pending = append(pending, held)
+			if len(pending) == maxDeleteBatch {
+				send(pending)
+				pending = nil
+			}
+		}
+		held, heldOffset = aws.ToString(obj.Key), offset
+		return nil
+	})
+	if errors.Is(err, errRangeEnd) {
+		err = nil
+	}
+	if err == nil && held != "" {
+		last, lerr := w.objectEnd(ctx, heldOffset)
+		if lerr != nil {
+			err = lerr
+		} else if last <= end {
+			pending = append(pending, held)
+		}
+	}
+	if len(pending) > 0 {
+		send(pending)
+	}
+	close(batches)
+	workers.Wait()
+
+	// the chunks of the records that were deleted go with them
+	if err == nil {
+		kept := make(map[uint64]bool)
+		for _, left := range remaining {
+			for _, key := range left {
+				if offset, perr := w.getOffsetFromKey(key); perr == nil {
+					kept[offset] = true
+				}
+			}
+		}
+		chunkResults, chunkRemaining, cerr := w.deleteChunks(ctx, start, end, kept, opts)
+		results = append(results, chunkResults...)
+		remaining = append(remaining, chunkRemaining...)
+		err = cerr
+	}
+
+	report := DeleteReport{Batches: results}
+	var errs []error
+	for i, result := range results {
+		report.Deleted += result.Deleted
+		report.Remaining = append(report.Remaining, remaining[i]...)
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	if err != nil {
+		return report, err
+	}
+	if len(report.Remaining) > 0 {
+		return report, fmt.Errorf("failed to delete %d objects: %w", len(report.Remaining), errors.Join(errs...))
+	}
+	return report, nil
+}
+
+// Truncate deletes every record object holding offsets below before, as
+// DeleteRange does. The bound is exclusive: offset before itself is kept, so
+// deleting offsets up to and including upTo takes Truncate(ctx, upTo+1,
+// opts). A micro-batch holding before is kept. Before deleting, it
+// records before in a marker object under the prefix, so that Read of a
+// deleted offset fails with ErrTruncated rather than NoSuchKey, in this and
+// other processes, and so that a writer started on the emptied log continues
+// after the truncated offsets. The length is left alone, so appends continue
+// after the current tail.
+func (w *S3WAL) Truncate(ctx context.Context, before uint64, opts DeleteOptions) (DeleteReport, error) {
+	if before <= 1 {
+		return DeleteReport{}, nil
+	}
+	if err := w.raiseMarker(ctx, w.truncationKey(), before); err != nil {
+		return DeleteReport{}, err
+	}
+	w.noteTruncation(before)
+	return w.DeleteRange(ctx, 1, before-1, opts)
+}
+
+// transientDeleteCodes are the per-key error codes of DeleteObjects that are
+// worth retrying.
+var transientDeleteCodes = []string{"InternalError", "ServiceUnavailable", "SlowDown"}
+
+// deleteBatch deletes keys with DeleteObjects, retrying the keys that failed
+// transiently, and returns the batch's result and the keys left over in key
+// order.
+func (w *S3WAL) deleteBatch(ctx context.Context, keys []string, opts DeleteOptions) (DeleteBatchResult, []string) {
+	if w.noBatchDelete.Load() {
+		return w.deleteEach(ctx, keys, opts)
+	}
+	result := DeleteBatchResult{Keys: len(keys)}
+	var permanent []string
+	var permErr, retryErr error
+	retry := keys
+	for len(retry) > 0 {
+		result.Attempts++
+		retryErr = nil
+		objects := make([]types.ObjectIdentifier, len(retry))
+		for i, key := range retry {
+			objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+		output, err := w.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(w.bucketName),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil && isNotImplemented(err) && result.Deleted == 0 && len(permanent) == 0 {
+			w.logger.Warn("s3log: DeleteObjects is not supported, deleting objects one at a time", "error", err)
+			w.noBatchDelete.Store(true)
+			return w.deleteEach(ctx, keys, opts)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to delete objects from S3: %w", err)
+			if isTransportError(err) {
+				retryErr = err
+			} else {
+				permErr = errors.Join(permErr, err)
+				permanent, retry = append(permanent, retry...), nil
+			}
+		} else {
+			failed := make(map[string]bool, len(output.Errors))
+			var transient []string
+			for _, e := range output.Errors {
+				key := aws.ToString(e.Key)
+				failed[key] = true
+				err := fmt.Errorf("failed to delete object %s from S3: %s: %s", key, aws.ToString(e.Code), aws.ToString(e.Message))
+				if slices.Contains(transientDeleteCodes, aws.ToString(e.Code)) {
+					transient = append(transient, key)
+					retryErr = errors.Join(retryErr, err)
+				} else {
+					permanent = append(permanent, key)
+					permErr = errors.Join(permErr, err)
+				}
+			}
+			for _, key := range retry {
+				if failed[key] {
+					continue
+				}
+				result.Deleted++
+				if offset, err := w.getOffsetFromKey(key); err == nil {
+					w.readCache.remove(offset)
+				}
+			}
+			retry = transient
+		}
+		if len(retry) == 0 || result.Attempts > opts.MaxRetries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			retryErr = errors.Join(retryErr, ctx.Err())
+		case <-time.After(opts.RetryBackoff):
+			continue
+		}
+		break
+	}
+	result.Err = errors.Join(permErr, retryErr)
+	return result, sortedKeys(permanent, retry)
+}
+
+// deleteEach is deleteBatch with one DeleteObject request per key, sent
+// opts.Concurrency at a time within the batch.
+func (w *S3WAL) deleteEach(ctx context.Context, keys []string, opts DeleteOptions) (DeleteBatchResult, []string) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultDeleteConcurrency
+	}
+	result := DeleteBatchResult{Keys: len(keys)}
+	var (
+		mu   sync.Mutex
+		errs []error
+		left []string
+	)
+	work := make(chan string)
+	var workers sync.WaitGroup
+	for range min(concurrency, len(keys)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for key := range work {
+				attempts, err := w.deleteOne(ctx, key, opts)
+				mu.Lock()
+				result.Attempts = max(result.Attempts, attempts)
+				if err != nil {
+					errs = append(errs, err)
+					left = append(left, key)
+				} else {
+					result.Deleted++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, key := range keys {
+		work <- key
+	}
+	close(work)
+	workers.Wait()
+
+	result.Err = errors.Join(errs...)
+	return result, sortedKeys(left, nil)
+}
+
+// deleteOne deletes key with DeleteObject, retrying transient failures, and
+// returns the number of attempts made.
+func (w *S3WAL) deleteOne(ctx context.Context, key string, opts DeleteOptions) (int, error) {
+	for attempt := 1; ; attempt++ {
+		_, err := w.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(w.bucketName),
+			Key:    aws.String(key),
+		})
+		if err == nil {
+			if offset, err := w.getOffsetFromKey(key); err == nil {
+				w.readCache.remove(offset)
+			}
+			return attempt, nil
+		}
+		err = fmt.Errorf("failed to delete object %s from S3: %w", key, err)
+		var apiErr smithy.APIError
+		transient := isTransportError(err) ||
+			(errors.As(err, &apiErr) && slices.Contains(transientDeleteCodes, apiErr.ErrorCode()))
+		if !transient || attempt > opts.MaxRetries {
+			return attempt, err
+		}
+		select {
+		case <-ctx.Done():
+			return attempt, errors.Join(err, ctx.Err())
+		case <-time.After(opts.RetryBackoff):
+		}
+	}
+}
+
+func sortedKeys(a, b []string) []string {
+	keys := append(slices.Clone(a), b...)
+	slices.Sort(keys)
+	return keys
+}
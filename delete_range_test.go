@@ -0,0 +1,263 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// deleteFailingClient fails the deletion of chosen keys with an error code,
+// a set number of times each.
+type deleteFailingClient struct {
+	S3Client
+	mu       sync.Mutex
+	code     map[string]string
+	failures map[string]int
+}
+
+func (c *deleteFailingClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	c.mu.Lock()
+	var kept []types.ObjectIdentifier
+	var failed []types.Error
+	for _, obj := range params.Delete.Objects {
+		key := aws.ToString(obj.Key)
+		if c.failures[key] > 0 {
+			c.failures[key]--
+			failed = append(failed, types.Error{Key: obj.Key, Code: aws.String(c.code[key]), Message: aws.String("injected")})
+			continue
+		}
+		kept = append(kept, obj)
+	}
+	c.mu.Unlock()
+	output := &s3.DeleteObjectsOutput{}
+	if len(kept) > 0 {
+		input := *params
+		input.Delete = &types.Delete{Objects: kept, Quiet: params.Delete.Quiet}
+		var err error
+		if output, err = c.S3Client.DeleteObjects(ctx, &input, optFns...); err != nil {
+			return nil, err
+		}
+	}
+	output.Errors = append(output.Errors, failed...)
+	return output, nil
+}
+
+func TestDeleteRange(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 8; i++ {
+		if _, err := base.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := base.AppendMicroBatch(ctx, [][]byte{[]byte("record 9"), []byte("record 10")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if _, err := base.Append(ctx, []byte("record 11")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	client := &deleteFailingClient{
+		S3Client: base.client,
+		code:     map[string]string{base.getObjectKey(3): "SlowDown", base.getObjectKey(5): "AccessDenied"},
+		failures: map[string]int{base.getObjectKey(3): 1, base.getObjectKey(5): 1},
+	}
+	wal := NewS3WAL(client, base.bucketName, base.prefix)
+
+	// the micro-batch holding 9 and 10 extends past the range and is kept
+	report, err := wal.DeleteRange(ctx, 2, 9, DeleteOptions{Concurrency: 2, MaxRetries: 2})
+	if err == nil {
+		t.Fatal("expected error for a key that failed permanently, got nil")
+	}
+	if report.Deleted != 6 || len(report.Remaining) != 1 || report.Remaining[0] != base.getObjectKey(5) {
+		t.Errorf("expected 6 deleted and offset 5 remaining, got %+v", report)
+	}
+	if len(report.Batches) != 1 || report.Batches[0].Attempts != 2 {
+		t.Errorf("expected a single batch retried once, got %+v", report.Batches)
+	}
+	for offset, want := range map[uint64]bool{1: true, 2: false, 3: false, 5: true, 8: false, 9: true, 10: true, 11: true} {
+		_, err := base.Read(ctx, offset)
+		var nsk *types.NoSuchKey
+		if exists := !errors.As(err, &nsk); exists != want {
+			t.Errorf("offset %d: expected exists=%v, got %v", offset, want, err)
+		}
+	}
+
+	report, err = wal.Truncate(ctx, 11, DeleteOptions{})
+	if err != nil || report.Deleted != 3 {
+		t.Fatalf("expected 3 objects deleted, got %+v (%v)", report, err)
+	}
+	if record, err := base.FirstRecord(ctx); err != nil || record.Offset != 11 {
+		t.Errorf("expected first offset 11, got %d (%v)", record.Offset, err)
+	}
+}
+
+// noBatchDeleteClient behaves like a store without the DeleteObjects API.
+type noBatchDeleteClient struct {
+	S3Client
+	batches atomic.Int32
+	singles atomic.Int32
+}
+
+func (c *noBatchDeleteClient) DeleteObjects(context.Context, *s3.DeleteObjectsInput, ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	c.batches.Add(1)
+	return nil, &smithy.GenericAPIError{Code: "NotImplemented", Message: "A header you provided implies functionality that is not implemented"}
+}
+
+func (c *noBatchDeleteClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.singles.Add(1)
+	return c.S3Client.DeleteObject(ctx, params, optFns...)
+}
+
+func TestDeleteRangeWithoutBatchAPI(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 6; i++ {
+		if _, err := base.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	client := &noBatchDeleteClient{S3Client: base.client}
+	wal := NewS3WAL(client, base.bucketName, base.prefix)
+	report, err := wal.DeleteRange(ctx, 1, 3, DeleteOptions{Concurrency: 2})
+	if err != nil || report.Deleted != 3 {
+		t.Fatalf("expected 3 deletions, got %d (%v)", report.Deleted, err)
+	}
+	if client.batches.Load() != 1 || client.singles.Load() != 3 {
+		t.Errorf("expected 1 rejected batch and 3 single deletes, got %d and %d", client.batches.Load(), client.singles.Load())
+	}
+	if report, err := wal.Truncate(ctx, 6, DeleteOptions{}); err != nil || report.Deleted != 2 {
+		t.Fatalf("expected 2 deletions, got %d (%v)", report.Deleted, err)
+	}
+	if client.batches.Load() != 1 {
+		t.Errorf("expected the batch API not to be tried again, got %d attempts", client.batches.Load())
+	}
+	if first, err := base.FirstRecord(ctx); err != nil || first.Offset != 6 {
+		t.Errorf("expected first record 6, got %d (%v)", first.Offset, err)
+	}
+
+	forced := &noBatchDeleteClient{S3Client: base.client}
+	wal = NewS3WAL(forced, base.bucketName, base.prefix, WithBatchDelete(false))
+	if _, err := wal.Truncate(ctx, 7, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if forced.batches.Load() != 0 || forced.singles.Load() != 1 {
+		t.Errorf("expected only single deletes, got %d batches and %d singles", forced.batches.Load(), forced.singles.Load())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 10; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	report, err := wal.Truncate(ctx, 6, DeleteOptions{})
+	if err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if report.Deleted != 5 {
+		t.Errorf("expected 5 objects deleted, got %d", report.Deleted)
+	}
+	if wal.length != 10 {
+		t.Errorf("expected length to stay at 10, got %d", wal.length)
+	}
+	if _, err := wal.Read(ctx, 3); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+	if record, err := wal.Read(ctx, 6); err != nil || string(record.Data) != "record 6" {
+		t.Errorf("unexpected record 6: %q (%v)", record.Data, err)
+	}
+
+	// another process learns of the truncation from the marker
+	other := NewS3WAL(wal.client, wal.bucketName, wal.prefix)
+	if _, err := other.Read(ctx, 1); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated from another S3WAL, got %v", err)
+	}
+	_, err = other.Read(ctx, 11)
+	var nsk *types.NoSuchKey
+	if errors.Is(err, ErrTruncated) || !errors.As(err, &nsk) {
+		t.Errorf("expected NoSuchKey past the tail, got %v", err)
+	}
+
+	// a lower truncation does not move the marker back
+	if _, err := other.Truncate(ctx, 3, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	fresh := NewS3WAL(wal.client, wal.bucketName, wal.prefix)
+	if _, err := fresh.Read(ctx, 4); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+	if offset, err := wal.Append(ctx, []byte("next")); err != nil || offset != 11 {
+		t.Errorf("expected append at 11, got %d (%v)", offset, err)
+	}
+}
+
+// markerCountingClient counts the GetObject requests for one key.
+type markerCountingClient struct {
+	S3Client
+	key  string
+	gets atomic.Int32
+}
+
+func (c *markerCountingClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if aws.ToString(params.Key) == c.key {
+		c.gets.Add(1)
+	}
+	return c.S3Client.GetObject(ctx, params, optFns...)
+}
+
+func TestTruncateEverything(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.Truncate(ctx, 4, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+
+	// a restarted writer continues after the truncated offsets
+	reopened, err := Open(ctx, wal.client, wal.bucketName, wal.prefix)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if offset, err := reopened.Append(ctx, []byte("next")); err != nil || offset != 4 {
+		t.Errorf("expected append at 4, got %d (%v)", offset, err)
+	}
+
+	// polling past the tail reads the marker once, not on every miss
+	client := &markerCountingClient{S3Client: wal.client, key: wal.truncationKey()}
+	reader := NewS3WAL(client, wal.bucketName, wal.prefix)
+	for range 5 {
+		if _, err := reader.Read(ctx, 5); errors.Is(err, ErrTruncated) || err == nil {
+			t.Errorf("expected a miss past the tail, got %v", err)
+		}
+	}
+	if gets := client.gets.Load(); gets != 1 {
+		t.Errorf("expected 1 marker request, got %d", gets)
+	}
+	if _, err := reader.Read(ctx, 2); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+}
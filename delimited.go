@@ -0,0 +1,59 @@
+package s3log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// WithMaxDelimitedRecordSize sets the largest record AppendDelimited accepts,
+// which is also the most it buffers from its reader. It defaults to
+// bufio.MaxScanTokenSize (64 KiB).
+func WithMaxDelimitedRecordSize(n int) Option {
+	return func(w *S3WAL) {
+		w.maxDelimitedRecordSize = n
+	}
+}
+
+// AppendDelimited reads r until EOF, splits it on delim and appends every
+// segment, without the delimiter, as a record. The input is streamed, so only
+// one record is held in memory at a time. A final segment not followed by
+// delim is appended too, but a trailing delimiter does not produce an empty
+// record; empty segments between two delimiters do.
+//
+// It returns the offsets of the appended records in order. If reading or
+// appending fails, the offsets appended so far are returned along with the
+// error; a segment longer than WithMaxDelimitedRecordSize fails with
+// bufio.ErrTooLong.
+func (w *S3WAL) AppendDelimited(ctx context.Context, r io.Reader, delim byte) ([]uint64, error) {
+	scanner := bufio.NewScanner(r)
+	maxSize := w.maxDelimitedRecordSize
+	if maxSize <= 0 {
+		maxSize = bufio.MaxScanTokenSize
+	}
+	scanner.Buffer(make([]byte, 0, min(maxSize, 64<<10)), maxSize)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+
+	var offsets []uint64
+	for scanner.Scan() {
+		offset, err := w.Append(ctx, scanner.Bytes())
+		if err != nil {
+			return offsets, fmt.Errorf("failed to append record %d: %w", len(offsets)+1, err)
+		}
+		offsets = append(offsets, offset)
+	}
+	if err := scanner.Err(); err != nil {
+		return offsets, fmt.Errorf("failed to read delimited input: %w", err)
+	}
+	return offsets, nil
+}
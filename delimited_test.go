@@ -0,0 +1,48 @@
+package s3log
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAppendDelimited(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	input := "{\"a\":1}\n{\"b\":2}\n\n{\"c\":3}"
+	offsets, err := wal.AppendDelimited(ctx, strings.NewReader(input), '\n')
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if !slices.Equal(offsets, []uint64{1, 2, 3, 4}) {
+		t.Fatalf("expected offsets 1-4, got %v", offsets)
+	}
+	for i, expected := range strings.Split(input, "\n") {
+		record, err := wal.Read(ctx, offsets[i])
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if string(record.Data) != expected {
+			t.Errorf("offset %d: expected %q, got %q", offsets[i], expected, record.Data)
+		}
+	}
+
+	offsets, err = wal.AppendDelimited(ctx, strings.NewReader("x;y;"), ';')
+	if err != nil || !slices.Equal(offsets, []uint64{5, 6}) {
+		t.Errorf("expected no record for a trailing delimiter, got %v (%v)", offsets, err)
+	}
+
+	WithMaxDelimitedRecordSize(4)(wal)
+	offsets, err = wal.AppendDelimited(ctx, strings.NewReader("ok\ntoo long\n"), '\n')
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("expected bufio.ErrTooLong, got %v", err)
+	}
+	if !slices.Equal(offsets, []uint64{7}) {
+		t.Errorf("expected the records before the long one to be appended, got %v", offsets)
+	}
+}
@@ -0,0 +1,105 @@
+// Package dynamoindex implements s3log.OffsetIndex on top of DynamoDB.
+package dynamoindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	s3log "github.com/xmohamd/s3-log"
+)
+
+var _ s3log.OffsetIndex = (*Index)(nil)
+
+// Attribute names of the items written by Index.
+const (
+	// KeyAttribute is the table's partition key, a string naming the log.
+	KeyAttribute = "log"
+	// TailAttribute holds the tail offset as a number.
+	TailAttribute = "tail"
+)
+
+// Client is the subset of the DynamoDB API used by Index. *dynamodb.Client
+// satisfies it.
+type Client interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// Index stores the tail offset of one log as a single item of a DynamoDB
+// table whose partition key is the string attribute KeyAttribute. Several
+// logs can share a table under different names. It is safe for concurrent
+// use.
+type Index struct {
+	client Client
+	table  string
+	log    string
+}
+
+// New returns an Index keeping the tail of the log named log in table. The
+// bucket and prefix of the log make a good name.
+func New(client Client, table, log string) *Index {
+	return &Index{client: client, table: table, log: log}
+}
+
+func (i *Index) key() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		KeyAttribute: &types.AttributeValueMemberS{Value: i.log},
+	}
+}
+
+// GetTail returns the recorded tail with a strongly consistent read, or 0 if
+// the log has no item yet.
+func (i *Index) GetTail(ctx context.Context) (uint64, error) {
+	output, err := i.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(i.table),
+		Key:                  i.key(),
+		ConsistentRead:       aws.Bool(true),
+		ProjectionExpression: aws.String(TailAttribute),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get item from dynamodb: %w", err)
+	}
+	attr, ok := output.Item[TailAttribute]
+	if !ok {
+		return 0, nil
+	}
+	n, ok := attr.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("attribute %s of %s is not a number", TailAttribute, i.log)
+	}
+	tail, err := strconv.ParseUint(n.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("attribute %s of %s is not an offset: %w", TailAttribute, i.log, err)
+	}
+	return tail, nil
+}
+
+// PutTail records offset as the tail with a conditional update, which leaves
+// a greater recorded offset in place.
+func (i *Index) PutTail(ctx context.Context, offset uint64) error {
+	_, err := i.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(i.table),
+		Key:                 i.key(),
+		UpdateExpression:    aws.String("SET #tail = :offset"),
+		ConditionExpression: aws.String("attribute_not_exists(#tail) OR #tail < :offset"),
+		ExpressionAttributeNames: map[string]string{
+			"#tail": TailAttribute,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":offset": &types.AttributeValueMemberN{Value: strconv.FormatUint(offset, 10)},
+		},
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update item in dynamodb: %w", err)
+	}
+	return nil
+}
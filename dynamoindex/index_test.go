@@ -0,0 +1,78 @@
+package dynamoindex
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeClient keeps the tails of a single table in memory and evaluates the
+// condition PutTail sends.
+type fakeClient struct {
+	mu    sync.Mutex
+	table string
+	tails map[string]uint64
+}
+
+func (c *fakeClient) GetItem(_ context.Context, params *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if aws.ToString(params.TableName) != c.table || !aws.ToBool(params.ConsistentRead) {
+		return nil, &types.ResourceNotFoundException{}
+	}
+	log := params.Key[KeyAttribute].(*types.AttributeValueMemberS).Value
+	tail, ok := c.tails[log]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+		TailAttribute: &types.AttributeValueMemberN{Value: strconv.FormatUint(tail, 10)},
+	}}, nil
+}
+
+func (c *fakeClient) UpdateItem(_ context.Context, params *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	log := params.Key[KeyAttribute].(*types.AttributeValueMemberS).Value
+	offset, err := strconv.ParseUint(params.ExpressionAttributeValues[":offset"].(*types.AttributeValueMemberN).Value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	if tail, ok := c.tails[log]; ok && tail >= offset {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+	c.tails[log] = offset
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{table: "wal-index", tails: make(map[string]uint64)}
+	index := New(client, "wal-index", "bucket/orders")
+	other := New(client, "wal-index", "bucket/users")
+
+	if tail, err := index.GetTail(ctx); err != nil || tail != 0 {
+		t.Fatalf("expected no tail, got %d (%v)", tail, err)
+	}
+	for _, offset := range []uint64{3, 7, 5} {
+		if err := index.PutTail(ctx, offset); err != nil {
+			t.Fatalf("failed to put tail %d: %v", offset, err)
+		}
+	}
+	if tail, err := index.GetTail(ctx); err != nil || tail != 7 {
+		t.Errorf("expected tail 7, got %d (%v)", tail, err)
+	}
+	if tail, err := other.GetTail(ctx); err != nil || tail != 0 {
+		t.Errorf("expected logs to be kept apart, got %d (%v)", tail, err)
+	}
+
+	client.tails["bucket/orders"] = 1 << 63
+	if tail, err := index.GetTail(ctx); err != nil || tail != 1<<63 {
+		t.Errorf("expected tail %d, got %d (%v)", uint64(1<<63), tail, err)
+	}
+}
@@ -0,0 +1,116 @@
+// Package emf implements s3log.Metrics by writing CloudWatch Embedded Metric
+// Format (EMF) records, one JSON object per line. Written to stdout on AWS
+// Lambda, or to a log stream read by the CloudWatch agent, the records are
+// turned into CloudWatch metrics without any further setup.
+package emf
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	s3log "github.com/xmohamd/s3-log"
+)
+
+var (
+	_ s3log.LatencyMetrics   = (*Metrics)(nil)
+	_ s3log.HistogramMetrics = (*Metrics)(nil)
+)
+
+// Units of the metrics written by Metrics.
+const (
+	UnitCount        = "Count"
+	UnitNone         = "None"
+	UnitMilliseconds = "Milliseconds"
+)
+
+// Metrics writes every counter increment, gauge value, histogram sample and
+// latency it receives as its own EMF record: counters in UnitCount, gauges
+// and samples in UnitNone and latencies in UnitMilliseconds. CloudWatch aggregates the records, so
+// no state is kept between them. It is safe for concurrent use.
+type Metrics struct {
+	mu         sync.Mutex
+	out        io.Writer
+	namespace  string
+	dimensions map[string]string
+	now        func() time.Time
+}
+
+// New returns a Metrics writing to out, typically os.Stdout, under the
+// CloudWatch namespace. Every record carries dimensions, which may be nil,
+// as its single dimension set.
+func New(out io.Writer, namespace string, dimensions map[string]string) *Metrics {
+	return &Metrics{
+		out:        out,
+		namespace:  namespace,
+		dimensions: dimensions,
+		now:        time.Now,
+	}
+}
+
+// IncCounter implements s3log.Metrics.
+func (m *Metrics) IncCounter(name string, delta int64) {
+	m.emit(name, UnitCount, delta)
+}
+
+// SetGauge implements s3log.Metrics.
+func (m *Metrics) SetGauge(name string, value float64) {
+	m.emit(name, UnitNone, value)
+}
+
+// Observe implements s3log.HistogramMetrics. Each value is written as its
+// own record, from which CloudWatch computes the distribution.
+func (m *Metrics) Observe(name string, value float64) {
+	m.emit(name, UnitNone, value)
+}
+
+// ObserveLatency implements s3log.LatencyMetrics.
+func (m *Metrics) ObserveLatency(name string, d time.Duration) {
+	m.emit(name, UnitMilliseconds, float64(d)/float64(time.Millisecond))
+}
+
+type metricDefinition struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+type metricDirective struct {
+	Namespace  string             `json:"Namespace"`
+	Dimensions [][]string         `json:"Dimensions"`
+	Metrics    []metricDefinition `json:"Metrics"`
+}
+
+type metadata struct {
+	Timestamp         int64             `json:"Timestamp"`
+	CloudWatchMetrics []metricDirective `json:"CloudWatchMetrics"`
+}
+
+// emit writes one record holding a single metric. Write errors are dropped,
+// since a metric must never fail the operation it describes.
+func (m *Metrics) emit(name, unit string, value any) {
+	keys := make([]string, 0, len(m.dimensions))
+	record := make(map[string]any, len(m.dimensions)+2)
+	for key, val := range m.dimensions {
+		keys = append(keys, key)
+		record[key] = val
+	}
+	slices.Sort(keys)
+	record[name] = value
+	record["_aws"] = metadata{
+		Timestamp: m.now().UnixMilli(),
+		CloudWatchMetrics: []metricDirective{{
+			Namespace:  m.namespace,
+			Dimensions: [][]string{keys},
+			Metrics:    []metricDefinition{{Name: name, Unit: unit}},
+		}},
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, _ = m.out.Write(append(line, '\n'))
+}
@@ -0,0 +1,76 @@
+package emf
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	var out bytes.Buffer
+	m := New(&out, "s3log", map[string]string{"Log": "orders", "Env": "prod"})
+	m.now = func() time.Time { return time.UnixMilli(1700000000000) }
+
+	m.IncCounter("s3log_appends", 2)
+	m.SetGauge("s3log_mirror_lag", 7)
+	m.ObserveLatency("s3log_read_latency", 1500*time.Microsecond)
+	m.Observe("s3log_record_size", 42)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d: %q", len(lines), out.String())
+	}
+	want := []struct {
+		name  string
+		unit  string
+		value float64
+	}{
+		{"s3log_appends", UnitCount, 2},
+		{"s3log_mirror_lag", UnitNone, 7},
+		{"s3log_read_latency", UnitMilliseconds, 1.5},
+		{"s3log_record_size", UnitNone, 42},
+	}
+	for i, line := range lines {
+		var record struct {
+			AWS struct {
+				Timestamp         int64
+				CloudWatchMetrics []struct {
+					Namespace  string
+					Dimensions [][]string
+					Metrics    []struct{ Name, Unit string }
+				}
+			} `json:"_aws"`
+			Log string
+			Env string
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %d is not JSON: %v", i, err)
+		}
+		var values map[string]any
+		if err := json.Unmarshal([]byte(line), &values); err != nil {
+			t.Fatalf("line %d is not JSON: %v", i, err)
+		}
+		if record.AWS.Timestamp != 1700000000000 {
+			t.Errorf("line %d: expected timestamp 1700000000000, got %d", i, record.AWS.Timestamp)
+		}
+		if record.Log != "orders" || record.Env != "prod" {
+			t.Errorf("line %d: expected dimension values, got %q and %q", i, record.Log, record.Env)
+		}
+		directives := record.AWS.CloudWatchMetrics
+		if len(directives) != 1 || directives[0].Namespace != "s3log" {
+			t.Fatalf("line %d: unexpected directives %+v", i, directives)
+		}
+		if dims := directives[0].Dimensions; len(dims) != 1 || strings.Join(dims[0], ",") != "Env,Log" {
+			t.Errorf("line %d: expected dimension set [Env Log], got %v", i, dims)
+		}
+		metrics := directives[0].Metrics
+		if len(metrics) != 1 || metrics[0].Name != want[i].name || metrics[0].Unit != want[i].unit {
+			t.Errorf("line %d: expected %s in %s, got %+v", i, want[i].name, want[i].unit, metrics)
+		}
+		if values[want[i].name] != want[i].value {
+			t.Errorf("line %d: expected value %v, got %v", i, want[i].value, values[want[i].name])
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package s3log
+
+// WithSSEKMS encrypts every record object with SSE-KMS using the given KMS
+// key ID or ARN.
+func WithSSEKMS(keyID string) Option {
+	return func(w *S3WAL) {
+		w.kmsKeyID = keyID
+	}
+}
+
+// WithBucketKeyEnabled sets BucketKeyEnabled on record uploads so that S3
+// uses a bucket-level key for SSE-KMS instead of calling KMS for every
+// object, which considerably reduces KMS costs at high append rates. It is
+// off by default, matching S3.
+func WithBucketKeyEnabled(enabled bool) Option {
+	return func(w *S3WAL) {
+		w.bucketKey = enabled
+	}
+}
@@ -0,0 +1,51 @@
+package s3log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// putRecorder is an S3Client that accepts and remembers every PutObject.
+type putRecorder struct {
+	S3Client
+	puts []*s3.PutObjectInput
+}
+
+func (c *putRecorder) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.puts = append(c.puts, params)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestBucketKeyEnabled(t *testing.T) {
+	ctx := context.Background()
+
+	client := &putRecorder{}
+	wal := NewS3WAL(client, "bucket", "log", WithSSEKMS("alias/wal"), WithBucketKeyEnabled(true))
+	if _, err := wal.Append(ctx, []byte("secret")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	for _, put := range client.puts {
+		if !aws.ToBool(put.BucketKeyEnabled) {
+			t.Errorf("expected BucketKeyEnabled on %s", aws.ToString(put.Key))
+		}
+		if put.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(put.SSEKMSKeyId) != "alias/wal" {
+			t.Errorf("expected SSE-KMS on %s", aws.ToString(put.Key))
+		}
+	}
+
+	client = &putRecorder{}
+	wal = NewS3WAL(client, "bucket", "log", WithSSEKMS("alias/wal"))
+	if _, err := wal.Append(ctx, []byte("secret")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if client.puts[0].BucketKeyEnabled != nil {
+		t.Error("expected BucketKeyEnabled to be unset by default")
+	}
+}
@@ -0,0 +1,69 @@
+package s3log
+
+import (
+	"context"
+	"sync"
+)
+
+// existsConcurrency is the number of HeadObject requests ExistsMany keeps in
+// flight.
+const existsConcurrency = 16
+
+// ExistsMany reports which of offsets have a record object of their own,
+// probing each with HeadObject, 16 at a time, rather than listing the log.
+// Offsets held inside a micro-batch other than its first report false, and
+// offsets ruled out by WithBloomFilter report false without a request. The
+// first error other than a missing key cancels the remaining probes and is
+// returned.
+func (w *S3WAL) ExistsMany(ctx context.Context, offsets []uint64) (map[uint64]bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		exists   = make(map[uint64]bool, len(offsets))
+		firstErr error
+	)
+	work := make(chan uint64)
+	var workers sync.WaitGroup
+	for range min(existsConcurrency, len(offsets)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for offset := range work {
+				if w.bloomAbsent(offset) {
+					mu.Lock()
+					exists[offset] = false
+					mu.Unlock()
+					continue
+				}
+				ok, err := w.objectExists(ctx, w.getObjectKey(offset))
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				exists[offset] = ok
+				mu.Unlock()
+			}
+		}()
+	}
+send:
+	for _, offset := range offsets {
+		select {
+		case work <- offset:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(work)
+	workers.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return exists, nil
+}
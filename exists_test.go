@@ -0,0 +1,41 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExistsMany(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for range 5 {
+		if _, err := wal.Append(ctx, []byte("data")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.Truncate(ctx, 3, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+
+	exists, err := wal.ExistsMany(ctx, []uint64{1, 2, 3, 5, 9})
+	if err != nil {
+		t.Fatalf("failed to check offsets: %v", err)
+	}
+	want := map[uint64]bool{1: false, 2: false, 3: true, 5: true, 9: false}
+	if len(exists) != len(want) {
+		t.Errorf("expected %v, got %v", want, exists)
+	}
+	for offset, ok := range want {
+		if exists[offset] != ok {
+			t.Errorf("offset %d: expected %v, got %v", offset, ok, exists[offset])
+		}
+	}
+
+	denied := NewS3WAL(noListClient{wal.client}, wal.bucketName, wal.prefix)
+	if _, err := denied.ExistsMany(ctx, []uint64{3, 9}); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied, got %v", err)
+	}
+}
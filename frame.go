@@ -0,0 +1,354 @@
+package s3log
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A plain frame is offset || data || checksum. Frames carrying additional
+// header fields are laid out as magic || flags || offset || fields || data ||
+// checksum instead, or as magic || flags || checksum || offset || fields ||
+// data with flagLeadingChecksum. With flagChecksumFormat, a byte naming the
+// checksum algorithm follows the flags and the checksum has that algorithm's
+// size; otherwise it is a 32-byte SHA-256. Sequential offsets start at 1, so
+// the leading byte of a plain frame's big-endian offset is zero, and offsets
+// from an IDGenerator whose leading byte is frameMagic are refused, so a
+// plain frame can never be mistaken for an extended one.
+const frameMagic byte = 0xA5
+
+// smallRecordSize is the largest payload putRecord frames into a buffer from
+// smallFramePool rather than a freshly allocated one. The buffer is reused
+// once PutObject returns, by which time the SDK has finished with the body.
+const smallRecordSize = 4 << 10
+
+var smallFramePool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, plainFrameOverhead+smallRecordSize)
+		return &buf
+	},
+}
+
+// appendPlainFrame appends the plain frame of data at offset to dst.
+func appendPlainFrame(dst []byte, offset uint64, data []byte) []byte {
+	start := len(dst)
+	dst = binary.BigEndian.AppendUint64(dst, offset)
+	dst = append(dst, data...)
+	checksum := sha256.Sum256(dst[start:])
+	return append(dst, checksum[:]...)
+}
+
+type frameFlags byte
+
+const (
+	flagSchema frameFlags = 1 << iota
+	// flagChunked marks a frame whose payload is a chunk manifest rather
+	// than the record data itself.
+	flagChunked
+	flagTimestamp
+	// flagPayloadChecksum marks a frame whose trailer is the checksum of the
+	// payload alone, as supplied to AppendPrehashed, rather than of the whole
+	// frame.
+	flagPayloadChecksum
+	// flagPrevChecksum marks a frame whose header holds the checksum of the
+	// previous record, as written by WithHashChain.
+	flagPrevChecksum
+	// flagLeadingChecksum marks a frame whose checksum follows the flags
+	// instead of trailing the data, as written with ChecksumLeading.
+	flagLeadingChecksum
+	// flagChecksumFormat marks a frame whose flags are followed by the ID of
+	// the Checksummer it was written with, as selected by WithChecksum.
+	flagChecksumFormat
+)
+
+const knownFrameFlags = flagSchema | flagChunked | flagTimestamp | flagPayloadChecksum | flagPrevChecksum | flagLeadingChecksum | flagChecksumFormat
+
+type frameHeader struct {
+	offset    uint64
+	schemaID  string
+	chunked   bool
+	timestamp time.Time
+	// checksum, if set, is the trailer to store instead of computing one.
+	checksum *[32]byte
+	// prevChecksum, if set, is the checksum of the record at offset-1.
+	prevChecksum *[32]byte
+	// leadingChecksum places the checksum at the start of the frame.
+	leadingChecksum bool
+	// sum, if set, is the checksum algorithm used instead of SHA-256.
+	sum Checksummer
+}
+
+func (h frameHeader) flags() frameFlags {
+	var f frameFlags
+	if h.schemaID != "" {
+		f |= flagSchema
+	}
+	if h.chunked {
+		f |= flagChunked
+	}
+	if !h.timestamp.IsZero() {
+		f |= flagTimestamp
+	}
+	if h.checksum != nil {
+		f |= flagPayloadChecksum
+	}
+	if h.prevChecksum != nil {
+		f |= flagPrevChecksum
+	}
+	if h.leadingChecksum {
+		f |= flagLeadingChecksum
+	}
+	if h.sum != nil {
+		f |= flagChecksumFormat
+	}
+	return f
+}
+
+// checksumLen returns the size of the checksum of a frame encoded with h.
+func (h frameHeader) checksumLen() int {
+	if h.sum == nil {
+		return checksumSize
+	}
+	return h.sum.Size()
+}
+
+// prefixLen returns the length of the magic, flags and checksum format that
+// start an extended frame encoded with h.
+func (h frameHeader) prefixLen() int {
+	if h.sum == nil {
+		return 2
+	}
+	return 3
+}
+
+// storedChecksum returns the checksum stored in frame, which must have been
+// encoded with h, wherever it is placed.
+func (h frameHeader) storedChecksum(frame []byte) []byte {
+	if h.leadingChecksum {
+		return frame[h.prefixLen() : h.prefixLen()+h.checksumLen()]
+	}
+	return frame[len(frame)-h.checksumLen():]
+}
+
+// digest returns the checksum of frame, which must have been encoded with h,
+// computed over everything but the stored checksum.
+func (h frameHeader) digest(frame []byte) []byte {
+	n := h.checksumLen()
+	if h.sum != nil {
+		if !h.leadingChecksum {
+			return h.sum.Sum(frame[:len(frame)-n])
+		}
+		covered := make([]byte, 0, len(frame)-n)
+		covered = append(covered, frame[:h.prefixLen()]...)
+		return h.sum.Sum(append(covered, frame[h.prefixLen()+n:]...))
+	}
+	if !h.leadingChecksum {
+		sum := sha256.Sum256(frame[:len(frame)-n])
+		return sum[:]
+	}
+	d := sha256.New()
+	d.Write(frame[:2])
+	d.Write(frame[2+n:])
+	return d.Sum(nil)
+}
+
+func encodeFrame(h frameHeader, data []byte) ([]byte, error) {
+	flags := h.flags()
+	if flags == 0 {
+		return prepareBody(h.offset, data)
+	}
+	buf := make([]byte, 0, 3+8+8+1+len(h.schemaID)+32+len(data)+h.checksumLen())
+	buf = append(buf, frameMagic, byte(flags))
+	if h.sum != nil {
+		buf = append(buf, h.sum.ID())
+	}
+	if h.leadingChecksum {
+		// filled in once the rest of the frame is known
+		buf = append(buf, make([]byte, h.checksumLen())...)
+	}
+	buf = binary.BigEndian.AppendUint64(buf, h.offset)
+	if flags&flagTimestamp != 0 {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(h.timestamp.UnixNano()))
+	}
+	if flags&flagSchema != 0 {
+		if len(h.schemaID) > 255 {
+			return nil, fmt.Errorf("schema id longer than 255 bytes")
+		}
+		buf = append(buf, byte(len(h.schemaID)))
+		buf = append(buf, h.schemaID...)
+	}
+	if flags&flagPrevChecksum != 0 {
+		buf = append(buf, h.prevChecksum[:]...)
+	}
+	buf = append(buf, data...)
+	if h.leadingChecksum {
+		checksum := h.digest(buf)
+		if h.checksum != nil {
+			checksum = h.checksum[:]
+		}
+		copy(buf[h.prefixLen():], checksum)
+		return buf, nil
+	}
+	if h.checksum != nil {
+		return append(buf, h.checksum[:]...), nil
+	}
+	if h.sum != nil {
+		return append(buf, h.sum.Sum(buf)...), nil
+	}
+	checksum := calculateChecksum(bytes.NewBuffer(buf))
+	return append(buf, checksum[:]...), nil
+}
+
+// frameChecksum returns the checksum algorithm of an extended frame, nil for
+// SHA-256, along with its size and the length of the frame's prefix.
+func frameChecksum(data []byte) (Checksummer, int, int, error) {
+	if len(data) < 2 {
+		return nil, 0, 0, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+	}
+	if frameFlags(data[1])&flagChecksumFormat == 0 {
+		return nil, checksumSize, 2, nil
+	}
+	if len(data) < 3 {
+		return nil, 0, 0, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+	}
+	sum, err := lookupChecksummer(data[2])
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return sum, sum.Size(), 3, nil
+}
+
+// decodeFrame validates a frame read from the object keyed by offset and
+// returns its header and payload.
+func decodeFrame(data []byte, offset uint64) (frameHeader, []byte, error) {
+	if len(data) == 0 {
+		return frameHeader{}, nil, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+	}
+	if data[0] != frameMagic {
+		if len(data) < plainFrameOverhead {
+			return frameHeader{}, nil, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+		}
+		if ok, err := validateOffset(data, offset); !ok {
+			if err != nil {
+				return frameHeader{}, nil, fmt.Errorf("%w: offset mismatch: %w", ErrInvalidRecord, err)
+			}
+			return frameHeader{}, nil, fmt.Errorf("%w: offset mismatch: expected %d", ErrInvalidRecord, offset)
+		}
+		if !validateChecksum(data) {
+			return frameHeader{}, nil, ErrChecksumMismatch
+		}
+		return frameHeader{offset: offset}, data[offsetHeaderSize : len(data)-checksumSize], nil
+	}
+
+	_, n, prefix, err := frameChecksum(data)
+	if err != nil {
+		return frameHeader{}, nil, err
+	}
+	if len(data) < prefix+offsetHeaderSize+n {
+		return frameHeader{}, nil, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+	}
+	end := len(data) - n
+	if frameFlags(data[1])&flagLeadingChecksum != 0 {
+		end = len(data)
+	}
+	h, pos, err := parseExtendedHeader(data[:end])
+	if err != nil {
+		return frameHeader{}, nil, err
+	}
+	if frameFlags(data[1])&flagPayloadChecksum != 0 {
+		if h.sum != nil {
+			return frameHeader{}, nil, fmt.Errorf("%w: payload checksum with checksum algorithm %d", ErrInvalidRecord, h.sum.ID())
+		}
+		h.checksum = (*[32]byte)(h.storedChecksum(data))
+	}
+	if h.offset != offset {
+		return frameHeader{}, nil, fmt.Errorf("%w: offset mismatch: expected %d, got %d", ErrInvalidRecord, offset, h.offset)
+	}
+	if h.checksum != nil {
+		if *h.checksum != sha256.Sum256(data[pos:end]) {
+			return frameHeader{}, nil, ErrChecksumMismatch
+		}
+	} else if !bytes.Equal(h.digest(data), h.storedChecksum(data)) {
+		return frameHeader{}, nil, ErrChecksumMismatch
+	}
+	return h, data[pos:end], nil
+}
+
+// ValidateFrame checks a frame held in memory, such as one returned by
+// ReadRaw, with the same offset and checksum validation as Read, and returns
+// the record it holds. The object's metadata is not available, so frames
+// written with WithoutOffsetHeader are rejected because they have no offset
+// to check, and a micro-batch is returned as a single record holding the
+// encoded batch. Chunked frames fail with ErrInvalidRecord since their data
+// is stored elsewhere.
+func ValidateFrame(data []byte, expectedOffset uint64) (Record, error) {
+	h, payload, err := decodeFrame(data, expectedOffset)
+	if err != nil {
+		return Record{}, err
+	}
+	if h.chunked {
+		return Record{}, fmt.Errorf("%w: frame holds a chunk manifest", ErrInvalidRecord)
+	}
+	return Record{
+		Offset:    expectedOffset,
+		Data:      payload,
+		SchemaID:  h.schemaID,
+		Timestamp: h.timestamp,
+	}, nil
+}
+
+// parseExtendedHeader parses the header of an extended frame from data, which
+// must not include a trailing checksum, and returns it along with the
+// position where the payload starts.
+func parseExtendedHeader(data []byte) (frameHeader, int, error) {
+	if len(data) < 2+8 || data[0] != frameMagic {
+		return frameHeader{}, 0, fmt.Errorf("%w: bad frame header", ErrInvalidRecord)
+	}
+	flags := frameFlags(data[1])
+	if flags&^knownFrameFlags != 0 {
+		return frameHeader{}, 0, fmt.Errorf("%w: unsupported frame flags %#x", ErrInvalidRecord, flags)
+	}
+	sum, n, pos, err := frameChecksum(data)
+	if err != nil {
+		return frameHeader{}, 0, err
+	}
+	if flags&flagLeadingChecksum != 0 {
+		pos += n
+	}
+	if pos+8 > len(data) {
+		return frameHeader{}, 0, fmt.Errorf("%w: bad frame header", ErrInvalidRecord)
+	}
+	h := frameHeader{
+		offset:          binary.BigEndian.Uint64(data[pos : pos+8]),
+		chunked:         flags&flagChunked != 0,
+		leadingChecksum: flags&flagLeadingChecksum != 0,
+		sum:             sum,
+	}
+	pos += 8
+	if flags&flagTimestamp != 0 {
+		if pos+8 > len(data) {
+			return frameHeader{}, 0, fmt.Errorf("%w: timestamp truncated", ErrInvalidRecord)
+		}
+		h.timestamp = time.Unix(0, int64(binary.BigEndian.Uint64(data[pos:]))).UTC()
+		pos += 8
+	}
+	if flags&flagSchema != 0 {
+		if pos >= len(data) || pos+1+int(data[pos]) > len(data) {
+			return frameHeader{}, 0, fmt.Errorf("%w: schema id truncated", ErrInvalidRecord)
+		}
+		n := int(data[pos])
+		h.schemaID = string(data[pos+1 : pos+1+n])
+		pos += 1 + n
+	}
+	if flags&flagPrevChecksum != 0 {
+		if pos+32 > len(data) {
+			return frameHeader{}, 0, fmt.Errorf("%w: previous checksum truncated", ErrInvalidRecord)
+		}
+		h.prevChecksum = (*[32]byte)(data[pos : pos+32])
+		pos += 32
+	}
+	return h, pos, nil
+}
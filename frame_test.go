@@ -0,0 +1,158 @@
+package s3log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func FuzzDecodeRecord(f *testing.F) {
+	plain, _ := encodeFrame(frameHeader{offset: 1}, []byte("hello"))
+	extended, _ := encodeFrame(frameHeader{offset: 1, schemaID: "s", timestamp: time.Unix(1, 0)}, []byte("hello"))
+	batch, _ := prepareBody(1, encodeMicroBatch(1, [][]byte{[]byte("a"), []byte("bc")}))
+	manifest, _ := encodeFrame(frameHeader{offset: 1, chunked: true}, encodeChunkManifest(chunkManifest{chunks: []chunkInfo{{size: 3}}}))
+	leading, _ := encodeFrame(frameHeader{offset: 1, leadingChecksum: true}, []byte("hello"))
+	crc, _ := encodeFrame(frameHeader{offset: 1, sum: CRC32CChecksum}, []byte("hello"))
+	f.Add(plain)
+	f.Add(leading)
+	f.Add(crc)
+	f.Add(extended)
+	f.Add(batch)
+	f.Add(manifest)
+	f.Add([]byte{})
+	f.Add([]byte{frameMagic, byte(knownFrameFlags)})
+	huge := binary.BigEndian.AppendUint64(make([]byte, 40), 1)
+	f.Add(binary.BigEndian.AppendUint32(huge, 0xffffffff))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		typed := func(err error) bool {
+			return errors.Is(err, ErrInvalidRecord) || errors.Is(err, ErrChecksumMismatch)
+		}
+		h, payload, err := decodeFrame(data, 1)
+		if err != nil {
+			if !typed(err) {
+				t.Fatalf("untyped frame error: %v", err)
+			}
+		} else if len(payload) > len(data) {
+			t.Fatalf("payload longer than frame")
+		}
+		if h.chunked {
+			if _, err := decodeChunkManifest(payload); err != nil && !typed(err) {
+				t.Fatalf("untyped chunk manifest error: %v", err)
+			}
+		}
+		if _, err := decodeMicroBatch(1, data); err != nil && !typed(err) {
+			t.Fatalf("untyped micro-batch error: %v", err)
+		}
+		if _, err := decodeChunkManifest(data); err != nil && !typed(err) {
+			t.Fatalf("untyped chunk manifest error: %v", err)
+		}
+		if _, err := decodeUnchecksummedFrame(data, 1); err != nil && !typed(err) {
+			t.Fatalf("untyped legacy frame error: %v", err)
+		}
+	})
+}
+
+func BenchmarkPrepareBody(b *testing.B) {
+	for _, size := range []int{64, 1 << 10, 4 << 10} {
+		data := make([]byte, size)
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if _, err := prepareBody(uint64(i), data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("%dB/pooled", size), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				bufp := smallFramePool.Get().(*[]byte)
+				*bufp = appendPlainFrame((*bufp)[:0], uint64(i), data)
+				smallFramePool.Put(bufp)
+			}
+		})
+	}
+}
+
+func TestAppendPlainFrame(t *testing.T) {
+	data := []byte("small record")
+	want, err := prepareBody(42, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := bytes.NewBuffer(binary.BigEndian.AppendUint64(nil, 42))
+	body.Write(data)
+	checksum := calculateChecksum(body)
+	body.Write(checksum[:])
+	if !bytes.Equal(want, body.Bytes()) {
+		t.Errorf("prepareBody changed its output")
+	}
+	if got := appendPlainFrame([]byte("prefix"), 42, data); !bytes.Equal(got[len("prefix"):], want) {
+		t.Errorf("appendPlainFrame does not match prepareBody")
+	}
+}
+
+func TestValidateFrame(t *testing.T) {
+	plain, _ := prepareBody(7, []byte("payload"))
+	record, err := ValidateFrame(plain, 7)
+	if err != nil {
+		t.Fatalf("failed to validate frame: %v", err)
+	}
+	if record.Offset != 7 || string(record.Data) != "payload" {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	ts := time.Unix(1700000000, 0).UTC()
+	extended, err := encodeFrame(frameHeader{offset: 8, schemaID: "orders.v1", timestamp: ts}, []byte("order"))
+	if err != nil {
+		t.Fatalf("failed to encode frame: %v", err)
+	}
+	record, err = ValidateFrame(extended, 8)
+	if err != nil {
+		t.Fatalf("failed to validate frame: %v", err)
+	}
+	if record.SchemaID != "orders.v1" || !record.Timestamp.Equal(ts) || string(record.Data) != "order" {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	if _, err := ValidateFrame(plain, 8); !errors.Is(err, ErrInvalidRecord) {
+		t.Errorf("expected ErrInvalidRecord for the wrong offset, got %v", err)
+	}
+	corrupted := bytes.Clone(plain)
+	corrupted[10] ^= 1
+	if _, err := ValidateFrame(corrupted, 7); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	chunked, _ := encodeFrame(frameHeader{offset: 9, chunked: true}, []byte("manifest"))
+	if _, err := ValidateFrame(chunked, 9); !errors.Is(err, ErrInvalidRecord) {
+		t.Errorf("expected ErrInvalidRecord for a chunked frame, got %v", err)
+	}
+}
+
+func TestDecodeFrameMinimumLength(t *testing.T) {
+	plain, _ := prepareBody(1, nil)
+	extended, _ := encodeFrame(frameHeader{offset: 1, schemaID: "s"}, nil)
+	offsetless := calculateChecksum(bytes.NewBuffer(nil))
+	for _, tc := range []struct {
+		name   string
+		frame  []byte
+		decode func([]byte) error
+	}{
+		{"plain", plain, func(b []byte) error { _, _, err := decodeFrame(b, 1); return err }},
+		{"extended", extended, func(b []byte) error { _, _, err := decodeFrame(b, 1); return err }},
+		{"offsetless", offsetless[:], func(b []byte) error { _, err := decodeOffsetlessFrame(b); return err }},
+	} {
+		if err := tc.decode(tc.frame); err != nil {
+			t.Errorf("%s: failed to decode an empty %d-byte record: %v", tc.name, len(tc.frame), err)
+		}
+		if err := tc.decode(tc.frame[:len(tc.frame)-1]); !errors.Is(err, ErrInvalidRecord) && !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("%s: expected a typed error for a truncated record, got %v", tc.name, err)
+		}
+	}
+}
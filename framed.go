@@ -0,0 +1,49 @@
+package s3log
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// AppendFramed appends a record that is already framed, such as one returned
+// by ReadRaw from another WAL, keeping its header fields and rewriting only
+// the offset, and the checksum that covers it. The frame is validated first,
+// so a corrupt frame fails with ErrChecksumMismatch instead of being stored
+// under a fresh checksum. A timestamp in the frame is kept even with
+// WithTimestamps; with WithHashChain, the link to the previous record is
+// replaced by one into this WAL.
+//
+// Frames holding a chunk manifest are rejected with ErrInvalidRecord, since
+// the chunks belong to the source WAL. Micro-batches and frames written with
+// WithoutOffsetHeader cannot be told apart from their bytes alone and must not
+// be passed in.
+func (w *S3WAL) AppendFramed(ctx context.Context, frame []byte) (uint64, error) {
+	offset, err := frameOffset(frame)
+	if err != nil {
+		return 0, err
+	}
+	h, payload, err := decodeFrame(frame, offset)
+	if err != nil {
+		return 0, err
+	}
+	if h.chunked {
+		return 0, fmt.Errorf("%w: frame holds a chunk manifest", ErrInvalidRecord)
+	}
+	h.prevChecksum = nil
+	offset, _, err = w.append(ctx, h, nil, payload)
+	return offset, err
+}
+
+// frameOffset returns the offset stored in the header of a plain or extended
+// frame without validating the rest of it.
+func frameOffset(frame []byte) (uint64, error) {
+	if len(frame) < 2+8 {
+		return 0, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+	}
+	if frame[0] == frameMagic {
+		h, _, err := parseExtendedHeader(frame)
+		return h.offset, err
+	}
+	return binary.BigEndian.Uint64(frame[:8]), nil
+}
@@ -0,0 +1,65 @@
+package s3log
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestAppendFramed(t *testing.T) {
+	src, cleanup := getWAL(t)
+	defer cleanup()
+	dst, cleanupDst := getWAL(t)
+	defer cleanupDst()
+	ctx := context.Background()
+
+	WithTimestamps()(src)
+	if _, err := src.Append(ctx, []byte("plain")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := src.AppendWithSchema(ctx, "v1", []byte("with schema")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	data := []byte("prehashed")
+	if _, err := src.AppendPrehashed(ctx, data, sha256.Sum256(data)); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := dst.Append(ctx, []byte("existing")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	for offset := uint64(1); offset <= 3; offset++ {
+		want, err := src.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		frame, err := src.ReadRaw(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read raw: %v", err)
+		}
+		got, err := dst.AppendFramed(ctx, frame)
+		if err != nil || got != offset+1 {
+			t.Fatalf("expected offset %d, got %d (%v)", offset+1, got, err)
+		}
+		record, err := dst.Read(ctx, got)
+		if err != nil {
+			t.Fatalf("failed to read forwarded record: %v", err)
+		}
+		if string(record.Data) != string(want.Data) || record.SchemaID != want.SchemaID || !record.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("offset %d: expected %+v, got %+v", got, want, record)
+		}
+	}
+
+	frame, err := src.ReadRaw(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to read raw: %v", err)
+	}
+	frame[9] ^= 0xff
+	if _, err := dst.AppendFramed(ctx, frame); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for a corrupt frame, got %v", err)
+	}
+	if _, err := dst.AppendFramed(ctx, []byte("short")); !errors.Is(err, ErrInvalidRecord) {
+		t.Errorf("expected ErrInvalidRecord for a short frame, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package s3log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDGenerator assigns the offsets of appended records. Next must return an
+// offset greater than last, the offset of the previous record appended by
+// the S3WAL, so that keys, and therefore LastRecord, stay ordered.
+//
+// Offsets remain uint64s: keys are their zero-padded decimal form, which
+// sorts lexicographically in numeric order. Identifiers wider than 64 bits,
+// such as 128-bit ULIDs, would need Record.Offset and every API taking an
+// offset to become an opaque, byte-comparable ID type, which is not
+// supported.
+type IDGenerator interface {
+	Next(last uint64) uint64
+}
+
+// WithIDGenerator makes appends take their offsets from g instead of
+// incrementing the previous offset. Offsets are then sparse, which the
+// features that assume a dense log do not support: Repair refuses to fill
+// gaps, MerkleRoot and InclusionProof need every offset of their range to
+// exist, and ReadByTimeRange falls back to a linear scan. Records of a
+// micro-batch still get consecutive offsets, starting at the generated one.
+// Appends fail if the leading byte of an offset equals the marker of
+// extended frames, since a plain frame starting with it would be misread.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(w *S3WAL) {
+		w.idGenerator = g
+	}
+}
+
+// nextOffset returns the offset of the next appended record. During a
+// bulk-load session, the first offset it returns starts the range of offsets
+// stored under bulk-load keys.
+func (w *S3WAL) nextOffset() (uint64, error) {
+	offset := w.length + 1
+	if w.idGenerator != nil {
+		offset = w.idGenerator.Next(w.length)
+		if err := checkFrameOffset(offset); err != nil {
+			return 0, err
+		}
+	}
+	if w.bulkSpread > 0 {
+		w.bulkStart.CompareAndSwap(0, offset)
+	}
+	return offset, nil
+}
+
+// checkFrameOffset returns an error if a plain frame starting with offset
+// would look like an extended frame.
+func checkFrameOffset(offset uint64) error {
+	if byte(offset>>56) == frameMagic {
+		return fmt.Errorf("offset %#x starts with the extended frame marker %#x", offset, frameMagic)
+	}
+	return nil
+}
+
+// SnowflakeGenerator generates time-ordered offsets laid out as 42 bits of
+// milliseconds since the Unix epoch, a 10-bit node ID and a 12-bit sequence
+// number, so writers with distinct node IDs never generate the same offset.
+// Its offsets reach the extended frame marker in their leading byte in 2059,
+// after which appends using them fail. It is safe for concurrent use.
+type SnowflakeGenerator struct {
+	node uint64
+	now  func() time.Time
+
+	mu   sync.Mutex
+	last uint64
+}
+
+// NewSnowflakeGenerator returns a SnowflakeGenerator for node, which must be
+// below 1024.
+func NewSnowflakeGenerator(node uint16) *SnowflakeGenerator {
+	if node >= 1<<10 {
+		panic("s3log: snowflake node ID must be below 1024")
+	}
+	return &SnowflakeGenerator{node: uint64(node), now: time.Now}
+}
+
+func (g *SnowflakeGenerator) Next(last uint64) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := uint64(g.now().UnixMilli())<<22 | g.node<<12
+	// within the same millisecond, or if the clock went backwards, count
+	// up from the last offset while keeping the node ID
+	if floor := max(last, g.last); id <= floor {
+		id = floor + 1
+		if id&(0x3ff<<12) != g.node<<12 {
+			id = (id>>22+1)<<22 | g.node<<12
+		}
+	}
+	g.last = id
+	return id
+}
@@ -0,0 +1,93 @@
+package s3log
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSnowflakeGenerator(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	g := NewSnowflakeGenerator(5)
+	g.now = func() time.Time { return now }
+
+	first := g.Next(0)
+	if first>>22 != uint64(now.UnixMilli()) || first>>12&0x3ff != 5 {
+		t.Fatalf("unexpected layout of %d", first)
+	}
+	last := first
+	for i := 0; i < 5000; i++ {
+		id := g.Next(last)
+		if id <= last || id>>12&0x3ff != 5 {
+			t.Fatalf("expected an id above %d with node 5, got %d", last, id)
+		}
+		last = id
+	}
+
+	// another node's offset from later in the same millisecond
+	other := uint64(now.UnixMilli())<<22 | 9<<12 | 7
+	if id := g.Next(other); id <= other || id>>12&0x3ff != 5 {
+		t.Errorf("expected an id above %d with node 5, got %d", other, id)
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	WithIDGenerator(NewSnowflakeGenerator(1))(wal)
+
+	var offsets []uint64
+	for _, data := range []string{"a", "b", "c"} {
+		offset, err := wal.Append(ctx, []byte(data))
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		if len(offsets) > 0 && offset <= offsets[len(offsets)-1] {
+			t.Fatalf("offsets are not increasing: %v, %d", offsets, offset)
+		}
+		offsets = append(offsets, offset)
+	}
+	batch, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("d"), []byte("e")})
+	if err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if batch[0] <= offsets[2] || batch[1] != batch[0]+1 {
+		t.Errorf("unexpected micro-batch offsets %v after %d", batch, offsets[2])
+	}
+
+	record, err := wal.Read(ctx, offsets[1])
+	if err != nil || string(record.Data) != "b" {
+		t.Errorf("failed to read generated offset: %q, %v", record.Data, err)
+	}
+	last, err := NewS3WAL(wal.client, wal.bucketName, wal.prefix).LastRecord(ctx)
+	if err != nil || last.Offset != batch[1] {
+		t.Errorf("expected last record %d, got %d (%v)", batch[1], last.Offset, err)
+	}
+	if _, err := wal.Repair(ctx, RepairOptions{FillGaps: true, DryRun: true}); err == nil {
+		t.Error("expected Repair to refuse filling gaps, got nil")
+	}
+}
+
+// fixedGenerator always returns next.
+type fixedGenerator struct{ next uint64 }
+
+func (g fixedGenerator) Next(uint64) uint64 { return g.next }
+
+func TestGeneratedOffsetLooksLikeFrame(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	WithIDGenerator(fixedGenerator{uint64(frameMagic) << 56})(wal)
+	if _, err := wal.Append(ctx, []byte("a")); err == nil {
+		t.Error("expected error for an offset starting with the frame marker, got nil")
+	}
+	WithIDGenerator(fixedGenerator{uint64(frameMagic)<<56 - 1})(wal)
+	if _, err := wal.BatchAppend(ctx, [][]byte{[]byte("a"), []byte("b")}); err == nil {
+		t.Error("expected error for a batch reaching the frame marker, got nil")
+	}
+	if offset, err := wal.Append(ctx, []byte("a")); err != nil || offset != uint64(frameMagic)<<56-1 {
+		t.Errorf("expected offset %d, got %d (%v)", uint64(frameMagic)<<56-1, offset, err)
+	}
+}
@@ -0,0 +1,97 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// AnomalyKind classifies the problems found by VerifyIntegrity.
+type AnomalyKind int
+
+const (
+	// AnomalyCorruptRecord is an object that is not a valid record, e.g.
+	// because its checksum does not match.
+	AnomalyCorruptRecord AnomalyKind = iota + 1
+	// AnomalyDuplicateOffset is an offset stored under more than one key.
+	AnomalyDuplicateOffset
+)
+
+func (k AnomalyKind) String() string {
+	switch k {
+	case AnomalyCorruptRecord:
+		return "corrupt record"
+	case AnomalyDuplicateOffset:
+		return "duplicate offset"
+	default:
+		return "unknown anomaly"
+	}
+}
+
+// Anomaly is a problem found by VerifyIntegrity.
+type Anomaly struct {
+	Kind   AnomalyKind
+	Offset uint64
+	// Keys are the objects involved: the corrupt object, or every key
+	// holding a duplicated offset.
+	Keys []string
+	// Err is the decoding error of a corrupt record.
+	Err error
+}
+
+// IntegrityReport is the result of VerifyIntegrity.
+type IntegrityReport struct {
+	Objects   int
+	Anomalies []Anomaly
+}
+
+// VerifyIntegrity downloads and validates every record object of the log and
+// reports the anomalies it finds instead of stopping at the first one. An
+// offset found under several keys is reported once as AnomalyDuplicateOffset
+// listing all of them; only the first of those keys to be listed is
+// validated.
+func (w *S3WAL) VerifyIntegrity(ctx context.Context) (IntegrityReport, error) {
+	var report IntegrityReport
+	duplicates := make(map[uint64]int)
+	err := w.scanObjects(ctx, func(offset uint64, obj types.Object, duplicateOf string) error {
+		report.Objects++
+		key := aws.ToString(obj.Key)
+		if duplicateOf != "" {
+			if i, ok := duplicates[offset]; ok {
+				report.Anomalies[i].Keys = append(report.Anomalies[i].Keys, key)
+				return nil
+			}
+			duplicates[offset] = len(report.Anomalies)
+			report.Anomalies = append(report.Anomalies, Anomaly{
+				Kind:   AnomalyDuplicateOffset,
+				Offset: offset,
+				Keys:   []string{duplicateOf, key},
+			})
+			return nil
+		}
+		data, metadata, err := w.getBytes(ctx, key)
+		if err != nil {
+			return err
+		}
+		if metadata[metaNoOffsetHeader] != "" {
+			_, err = decodeOffsetlessFrame(data)
+		} else {
+			_, _, err = decodeFrame(data, offset)
+		}
+		if err != nil {
+			if !errors.Is(err, ErrInvalidRecord) && !errors.Is(err, ErrChecksumMismatch) {
+				return err
+			}
+			report.Anomalies = append(report.Anomalies, Anomaly{
+				Kind:   AnomalyCorruptRecord,
+				Offset: offset,
+				Keys:   []string{key},
+				Err:    err,
+			})
+		}
+		return nil
+	})
+	return report, err
+}
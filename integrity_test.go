@@ -0,0 +1,67 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, data := range []string{"one", "two", "three"} {
+		if _, err := wal.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	report, err := wal.VerifyIntegrity(ctx)
+	if err != nil || report.Objects != 3 || len(report.Anomalies) != 0 {
+		t.Fatalf("expected a clean report, got %+v (%v)", report, err)
+	}
+
+	corruptRecord(t, wal, 2)
+	// offset 3 again, under keys of older key schemes
+	for _, key := range []string{wal.prefix + "/3", wal.prefix + "/0003"} {
+		body, _ := prepareBody(3, []byte("three"))
+		_, err := wal.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(wal.bucketName),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(body),
+		})
+		if err != nil {
+			t.Fatalf("failed to put %s: %v", key, err)
+		}
+	}
+
+	report, err = wal.VerifyIntegrity(ctx)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if report.Objects != 5 || len(report.Anomalies) != 2 {
+		t.Fatalf("expected 5 objects and 2 anomalies, got %+v", report)
+	}
+	for _, anomaly := range report.Anomalies {
+		switch anomaly.Kind {
+		case AnomalyCorruptRecord:
+			if anomaly.Offset != 2 || !errors.Is(anomaly.Err, ErrChecksumMismatch) {
+				t.Errorf("unexpected corruption anomaly: %+v", anomaly)
+			}
+		case AnomalyDuplicateOffset:
+			if anomaly.Offset != 3 || len(anomaly.Keys) != 3 {
+				t.Errorf("unexpected duplicate anomaly: %+v", anomaly)
+			}
+		default:
+			t.Errorf("unexpected anomaly: %+v", anomaly)
+		}
+	}
+
+	if _, err := wal.TailOffset(ctx); !errors.Is(err, ErrDuplicateOffset) {
+		t.Errorf("expected iteration to fail with ErrDuplicateOffset, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package s3log
+
+import "fmt"
+
+// KeyFormatter returns the object key of the record at offset in the log
+// stored under prefix.
+type KeyFormatter func(prefix string, offset uint64) string
+
+// KeyParser returns the offset of the record stored under key, or an error
+// if key does not name a record.
+type KeyParser func(key string) (uint64, error)
+
+// WithKeyFormatter replaces the default key scheme, prefix followed by "/"
+// and the zero-padded offset, e.g. to conform to an external system's key
+// conventions. It must be paired with a WithKeyParser that inverts it, and
+// the two must stay consistent:
+//
+//   - every key must start with prefix + "/", or nothing for an empty prefix,
+//     since listings only look there;
+//   - keys must sort lexicographically in offset order, since LastRecord and
+//     tail detection take the greatest key for the last record;
+//   - the parser must return an error for keys that are not records, such as
+//     the "sealed", "manifest" and "snapshot" objects under the prefix.
+//
+// Bulk-load and chunk keys keep their own schemes. NewS3WAL panics if the
+// formatter is set without a parser or they disagree on a sample offset.
+func WithKeyFormatter(f KeyFormatter) Option {
+	if f == nil {
+		panic("s3log: key formatter must not be nil")
+	}
+	return func(w *S3WAL) {
+		w.keyFormatter = f
+	}
+}
+
+// WithKeySuffix appends suffix, e.g. ".wal" or ".json", to the keys of record
+// objects after the zero-padded offset, for tooling that keys off file
+// extensions. A constant suffix keeps keys in offset order. Keys that do not
+// end in the suffix are not taken for records, so it must not be changed for
+// an existing log. WithKeyFormatter takes precedence over it.
+func WithKeySuffix(suffix string) Option {
+	return func(w *S3WAL) {
+		w.keySuffix = suffix
+	}
+}
+
+// WithKeyParser replaces the default parsing of offsets from keys, which
+// takes the digits that follow the last non-digit character. See
+// WithKeyFormatter for the rules the two must follow.
+func WithKeyParser(p KeyParser) Option {
+	if p == nil {
+		panic("s3log: key parser must not be nil")
+	}
+	return func(w *S3WAL) {
+		w.keyParser = p
+	}
+}
+
+// checkKeyScheme panics if a custom key formatter is unusable.
+func (w *S3WAL) checkKeyScheme() {
+	if w.keyFormatter == nil {
+		return
+	}
+	if w.keyParser == nil {
+		panic("s3log: WithKeyFormatter requires WithKeyParser")
+	}
+	const sample = 42
+	key := w.keyFormatter(w.prefix, sample)
+	if prefix := w.keyPrefix(); len(key) <= len(prefix) || key[:len(prefix)] != prefix {
+		panic(fmt.Sprintf("s3log: formatted key %q does not start with %q", key, prefix))
+	}
+	if offset, err := w.keyParser(key); err != nil || offset != sample {
+		panic(fmt.Sprintf("s3log: key parser does not invert the formatter for %q", key))
+	}
+}
@@ -0,0 +1,104 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeyFormatter(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	format := func(prefix string, offset uint64) string {
+		return fmt.Sprintf("%s/r%016x", prefix, offset)
+	}
+	parse := func(key string) (uint64, error) {
+		_, hex, ok := strings.Cut(key, "/r")
+		if !ok {
+			return 0, fmt.Errorf("key %q is not a record", key)
+		}
+		return strconv.ParseUint(hex, 16, 64)
+	}
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithKeyFormatter(format), WithKeyParser(parse))
+	for i := 1; i <= 20; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if err := wal.Seal(ctx); err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if key := wal.getObjectKey(17); key != base.prefix+"/r0000000000000011" {
+		t.Errorf("unexpected key %q", key)
+	}
+	record, err := wal.Read(ctx, 17)
+	if err != nil || string(record.Data) != "record 17" {
+		t.Errorf("unexpected record %q (%v)", record.Data, err)
+	}
+
+	reopened := NewS3WAL(base.client, base.bucketName, base.prefix, WithKeyFormatter(format), WithKeyParser(parse))
+	last, err := reopened.LastRecord(ctx)
+	if err != nil || last.Offset != 20 {
+		t.Errorf("expected last record 20, got %d (%v)", last.Offset, err)
+	}
+	page, _, err := reopened.ReadPage(ctx, "", 100)
+	if err != nil || len(page) != 20 {
+		t.Errorf("expected 20 records on the page, got %d (%v)", len(page), err)
+	}
+}
+
+func TestKeyFormatterValidation(t *testing.T) {
+	format := func(prefix string, offset uint64) string {
+		return fmt.Sprintf("%s/%d", prefix, offset)
+	}
+	expectPanic := func(name string, opts ...Option) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		NewS3WAL(nil, "bucket", "log", opts...)
+	}
+	expectPanic("no parser", WithKeyFormatter(format))
+	expectPanic("outside prefix", WithKeyFormatter(func(string, uint64) string { return "other/1" }), WithKeyParser(func(string) (uint64, error) { return 1, nil }))
+	expectPanic("inconsistent", WithKeyFormatter(format), WithKeyParser(func(string) (uint64, error) { return 7, nil }))
+}
+
+func TestKeySuffix(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithKeySuffix(".wal"))
+	for i := 1; i <= 12; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if err := wal.WriteManifest(ctx); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	if key := wal.getObjectKey(7); key != base.prefix+"/00000000000000000007.wal" {
+		t.Errorf("unexpected key %q", key)
+	}
+	if offset, err := wal.getOffsetFromKey(base.prefix + "/00000000000000000007.wal"); err != nil || offset != 7 {
+		t.Errorf("expected offset 7, got %d (%v)", offset, err)
+	}
+	if _, err := wal.getOffsetFromKey(base.prefix + "/00000000000000000007"); err == nil {
+		t.Error("expected error for a key without the suffix")
+	}
+
+	reopened, err := Open(ctx, base.client, base.bucketName, base.prefix, WithKeySuffix(".wal"))
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	last, err := reopened.LastRecord(ctx)
+	if err != nil || last.Offset != 12 || string(last.Data) != "record 12" {
+		t.Errorf("expected last record 12, got %d %q (%v)", last.Offset, last.Data, err)
+	}
+}
@@ -0,0 +1,137 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// compactionLeaseName is the name of the object holding the compaction lease,
+// stored under the log prefix. Like sealMarker, it does not end in digits.
+const compactionLeaseName = "compaction-lease"
+
+// compactionLeaseTTL is how long a compaction lease lasts unless renewed. A
+// pass renews its lease once half of it has run out, so a crashed process
+// blocks compaction elsewhere for at most this long.
+const compactionLeaseTTL = time.Minute
+
+func (w *S3WAL) compactionLeaseKey() string {
+	return w.keyPrefix() + compactionLeaseName
+}
+
+// lease is a lease object held by this process. The object holds the holder
+// ID and the expiry in Unix nanoseconds, and every change to it is
+// conditional on its ETag, so at most one process holds an unexpired lease.
+type lease struct {
+	key     string
+	holder  string
+	etag    string
+	expires time.Time
+}
+
+// acquireLease takes the lease under key, failing with ErrLeaseHeld while
+// another process holds it.
+func (w *S3WAL) acquireLease(ctx context.Context, key string) (*lease, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate lease holder ID: %w", err)
+	}
+	l := &lease{key: key, holder: hex.EncodeToString(id)}
+
+	got, err := w.getObject(ctx, key, "")
+	var nsk *types.NoSuchKey
+	switch {
+	case errors.As(err, &nsk):
+	case err != nil:
+		return nil, err
+	default:
+		holder, expires, err := parseLease(got.data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad lease %q in %s", ErrInvalidRecord, got.data, key)
+		}
+		if w.now().Before(expires) {
+			return nil, fmt.Errorf("%w: %s is held by %s until %s", ErrLeaseHeld, key, holder, expires.Format(time.RFC3339))
+		}
+		l.etag = got.etag
+	}
+	if err := w.putLease(ctx, l, w.now().Add(compactionLeaseTTL)); err != nil {
+		if isPreconditionFailed(err) {
+			return nil, fmt.Errorf("%w: %s was taken by another process", ErrLeaseHeld, key)
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+// renewLease extends l once half of it has run out, failing with ErrLeaseHeld
+// if it has been taken over in the meantime.
+func (w *S3WAL) renewLease(ctx context.Context, l *lease) error {
+	if l.expires.Sub(w.now()) > compactionLeaseTTL/2 {
+		return nil
+	}
+	err := w.putLease(ctx, l, w.now().Add(compactionLeaseTTL))
+	if isPreconditionFailed(err) {
+		return fmt.Errorf("%w: %s was taken by another process", ErrLeaseHeld, l.key)
+	}
+	return err
+}
+
+// releaseLease lets l expire at once. A lease that has been taken over is
+// left alone.
+func (w *S3WAL) releaseLease(ctx context.Context, l *lease) error {
+	err := w.putLease(ctx, l, time.Unix(0, 0))
+	if isPreconditionFailed(err) {
+		return nil
+	}
+	return err
+}
+
+// putLease writes l with the given expiry, conditional on the lease object
+// being unchanged since l last wrote or read it.
+func (w *S3WAL) putLease(ctx context.Context, l *lease, expires time.Time) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(l.key),
+		Body:   bytes.NewReader([]byte(l.holder + " " + strconv.FormatInt(expires.UnixNano(), 10))),
+	}
+	if !w.noPreconditions {
+		if l.etag == "" {
+			input.IfNoneMatch = aws.String("*")
+		} else {
+			input.IfMatch = aws.String(l.etag)
+		}
+	}
+	w.applyPutOptions(input)
+	out, err := w.client.PutObject(ctx, input)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return err
+		}
+		return fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	l.etag = aws.ToString(out.ETag)
+	l.expires = expires
+	return nil
+}
+
+func parseLease(data []byte) (string, time.Time, error) {
+	holder, expires, ok := strings.Cut(string(data), " ")
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("missing expiry")
+	}
+	nanos, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return holder, time.Unix(0, nanos), nil
+}
@@ -0,0 +1,52 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCompactionLease(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if _, err := wal.Append(ctx, []byte("small")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	other := NewS3WAL(wal.client, wal.bucketName, wal.prefix)
+	held, err := other.acquireLease(ctx, other.compactionLeaseKey())
+	if err != nil {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+	policy := CompactionPolicy{MaxObjectSize: 1024}
+	if _, err := wal.Compact(ctx, policy); !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("expected ErrLeaseHeld while another process compacts, got %v", err)
+	}
+	if stats, err := wal.Stats(ctx); err != nil || stats.Objects != 4 {
+		t.Errorf("expected 4 untouched objects, got %d (%v)", stats.Objects, err)
+	}
+
+	// a lease that has run out is taken over, and its holder cannot renew it
+	wal.now = func() time.Time { return time.Now().Add(2 * compactionLeaseTTL) }
+	stats, err := wal.Compact(ctx, policy)
+	if err != nil {
+		t.Fatalf("failed to compact after the lease expired: %v", err)
+	}
+	if stats.ObjectsMerged != 4 {
+		t.Errorf("expected 4 objects merged, got %d", stats.ObjectsMerged)
+	}
+	other.now = wal.now
+	if err := other.renewLease(ctx, held); !errors.Is(err, ErrLeaseHeld) {
+		t.Errorf("expected ErrLeaseHeld renewing a lease taken over, got %v", err)
+	}
+
+	// the lease is released at the end of a pass
+	other.now = time.Now
+	if _, err := other.Compact(ctx, policy); err != nil {
+		t.Errorf("failed to compact after the previous pass: %v", err)
+	}
+}
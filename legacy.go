@@ -0,0 +1,32 @@
+package s3log
+
+import "fmt"
+
+// WithChecksumOptional accepts records framed as offset || data without a
+// trailing checksum, as produced by some legacy imports. When a plain frame
+// fails validation, its offset header matches and it is too short to hold a
+// checksum, Read returns everything after the offset header as the payload
+// and logs a warning.
+//
+// A legacy record with 32 bytes of data or more cannot be told apart from a
+// corrupted record with a checksum, so it still fails with ErrChecksumMismatch
+// and has to be rewritten with proper framing. Only enable this option while
+// consuming a legacy dataset.
+func WithChecksumOptional() Option {
+	return func(w *S3WAL) {
+		w.optChecksum = true
+	}
+}
+
+func decodeUnchecksummedFrame(data []byte, offset uint64) ([]byte, error) {
+	if len(data) < offsetHeaderSize || data[0] == frameMagic {
+		return nil, fmt.Errorf("%w: not a legacy frame", ErrInvalidRecord)
+	}
+	if len(data) >= plainFrameOverhead {
+		return nil, fmt.Errorf("%w: long enough to hold a checksum", ErrInvalidRecord)
+	}
+	if ok, err := validateOffset(data, offset); !ok {
+		return nil, fmt.Errorf("%w: offset mismatch: %v", ErrInvalidRecord, err)
+	}
+	return data[offsetHeaderSize:], nil
+}
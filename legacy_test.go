@@ -0,0 +1,62 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestChecksumOptional(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Append(ctx, []byte("framed")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	for offset, data := range map[uint64]string{2: "short", 3: "a legacy record long enough to hold a checksum"} {
+		body := binary.BigEndian.AppendUint64(nil, offset)
+		body = append(body, data...)
+		_, err := wal.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(wal.bucketName),
+			Key:    aws.String(wal.getObjectKey(offset)),
+			Body:   bytes.NewReader(body),
+		})
+		if err != nil {
+			t.Fatalf("failed to import legacy record: %v", err)
+		}
+	}
+
+	if _, err := wal.Read(ctx, 2); err == nil {
+		t.Error("expected error for a legacy record without the option, got nil")
+	}
+
+	var logs bytes.Buffer
+	WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))(wal)
+	WithChecksumOptional()(wal)
+
+	expected := map[uint64]string{1: "framed", 2: "short"}
+	for offset, data := range expected {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if string(record.Data) != data {
+			t.Errorf("offset %d: expected %q, got %q", offset, data, record.Data)
+		}
+	}
+	if n := strings.Count(logs.String(), "without a valid checksum"); n != 1 {
+		t.Errorf("expected 1 warning, got %d:\n%s", n, logs.String())
+	}
+	// a record this long could be a corrupted one, so it is not taken as legacy
+	if _, err := wal.Read(ctx, 3); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for a long legacy record, got %v", err)
+	}
+}
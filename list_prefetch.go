@@ -0,0 +1,70 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithListPrefetch makes full scans of the log, such as those of LastRecord,
+// TailOffset and Stats, fetch up to pages list pages ahead in the background
+// while the current page is processed. Each page still needs the continuation
+// token of the one before it, so the gain is the overlap of processing, e.g.
+// the HeadObject checks for duplicate offsets, with listing. At most pages
+// pages of up to 1000 keys are buffered. It is off by default.
+func WithListPrefetch(pages int) Option {
+	return func(w *S3WAL) {
+		w.listPrefetch = pages
+	}
+}
+
+// listPages calls fn with every page of the listing described by input, in
+// order, prefetching pages as configured by WithListPrefetch.
+func (w *S3WAL) listPages(ctx context.Context, input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output) error) error {
+	paginator := s3.NewListObjectsV2Paginator(w.client, input)
+	if w.listPrefetch <= 0 {
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list objects from s3: %w", err)
+			}
+			if err := fn(output); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type page struct {
+		output *s3.ListObjectsV2Output
+		err    error
+	}
+	// the fetching goroutine holds one page while the channel is full
+	pages := make(chan page, w.listPrefetch-1)
+	go func() {
+		defer close(pages)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			select {
+			case pages <- page{output: output, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	for p := range pages {
+		if p.err != nil {
+			return fmt.Errorf("failed to list objects from s3: %w", p.err)
+		}
+		if err := fn(p.output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestListPrefetch(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	input := func() *s3.ListObjectsV2Input {
+		return &s3.ListObjectsV2Input{
+			Bucket:  aws.String(wal.bucketName),
+			Prefix:  aws.String(wal.prefix + "/"),
+			MaxKeys: aws.Int32(2),
+		}
+	}
+	var expected []string
+	for offset := uint64(1); offset <= 7; offset++ {
+		expected = append(expected, wal.getObjectKey(offset))
+	}
+
+	for _, prefetch := range []int{0, 1, 3} {
+		WithListPrefetch(prefetch)(wal)
+		var keys []string
+		err := wal.listPages(ctx, input(), func(output *s3.ListObjectsV2Output) error {
+			for _, obj := range output.Contents {
+				keys = append(keys, *obj.Key)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("prefetch %d: failed to list: %v", prefetch, err)
+		}
+		if !slices.Equal(keys, expected) {
+			t.Errorf("prefetch %d: expected %v, got %v", prefetch, expected, keys)
+		}
+
+		stop := errors.New("stop")
+		pages := 0
+		err = wal.listPages(ctx, input(), func(*s3.ListObjectsV2Output) error {
+			pages++
+			return stop
+		})
+		if !errors.Is(err, stop) || pages != 1 {
+			t.Errorf("prefetch %d: expected to stop after 1 page, got %d pages (%v)", prefetch, pages, err)
+		}
+
+		record, err := wal.LastRecord(ctx)
+		if err != nil || record.Offset != 7 {
+			t.Errorf("prefetch %d: expected last record 7, got %d (%v)", prefetch, record.Offset, err)
+		}
+	}
+}
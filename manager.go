@@ -0,0 +1,99 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WALManager opens the logs stored under different prefixes of one bucket.
+// It is safe for concurrent use, and so are the S3WAL instances it returns.
+type WALManager struct {
+	client     S3Client
+	bucketName string
+	opts       []Option
+
+	mu   sync.Mutex
+	logs map[string]*S3WAL
+}
+
+// NewWALManager returns a WALManager for bucketName. The options are applied
+// to every S3WAL it opens.
+func NewWALManager(client S3Client, bucketName string, opts ...Option) *WALManager {
+	return &WALManager{
+		client:     client,
+		bucketName: bucketName,
+		opts:       opts,
+		logs:       make(map[string]*S3WAL),
+	}
+}
+
+// Open returns the S3WAL for prefix, creating it on first use. Later calls
+// with the same prefix return the same instance.
+func (m *WALManager) Open(prefix string) *S3WAL {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if w, ok := m.logs[prefix]; ok {
+		return w
+	}
+	w := NewS3WAL(m.client, m.bucketName, prefix, m.opts...)
+	m.logs[prefix] = w
+	return w
+}
+
+// ListLogs returns the prefixes of the top-level logs in the bucket, in
+// lexical order. See ListWALs.
+func (m *WALManager) ListLogs(ctx context.Context) ([]string, error) {
+	return ListWALs(ctx, m.client, m.bucketName, "")
+}
+
+// ListWALs returns the prefixes of the logs directly under rootPrefix in
+// bucketName, in lexical order, using a delimited listing so that record keys
+// are never enumerated. rootPrefix may be empty for the top level of the
+// bucket; a trailing slash is optional. The returned prefixes include
+// rootPrefix and can be passed to NewS3WAL as is. Logs nested more than one
+// path segment below rootPrefix are not discovered, and the prefixes holding
+// a log's blobs, checkpoints and chunks are skipped.
+func ListWALs(ctx context.Context, client S3Client, bucketName, rootPrefix string) ([]string, error) {
+	if rootPrefix != "" && !strings.HasSuffix(rootPrefix, "/") {
+		rootPrefix += "/"
+	}
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(bucketName),
+		Delimiter: aws.String("/"),
+	}
+	if rootPrefix != "" {
+		input.Prefix = aws.String(rootPrefix)
+	}
+	paginator := s3.NewListObjectsV2Paginator(accessClient{client}, input)
+
+	var prefixes []string
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects from s3: %w", err)
+		}
+		for _, p := range output.CommonPrefixes {
+			prefix := aws.ToString(p.Prefix)
+			if !isSidePrefix(prefix) {
+				prefixes = append(prefixes, strings.TrimSuffix(prefix, "/"))
+			}
+		}
+	}
+	return prefixes, nil
+}
+
+// isSidePrefix reports whether prefix, a common prefix ending in a slash,
+// holds the side objects of a log rather than a log.
+func isSidePrefix(prefix string) bool {
+	for _, side := range sideKeyPrefixes {
+		if strings.HasSuffix(prefix, side) {
+			return true
+		}
+	}
+	return false
+}
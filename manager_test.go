@@ -0,0 +1,87 @@
+package s3log
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestWALManager(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	m := NewWALManager(setupMinioClient(), base.bucketName)
+	if m.Open(base.prefix) != m.Open(base.prefix) {
+		t.Error("expected Open to return the cached instance")
+	}
+
+	names := []string{base.prefix, base.prefix + "-orders", base.prefix + "-users"}
+	var wg sync.WaitGroup
+	for _, name := range names {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := m.Open(name).Append(ctx, []byte(name)); err != nil {
+					t.Errorf("failed to append to %s: %v", name, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	logs, err := m.ListLogs(ctx)
+	if err != nil {
+		t.Fatalf("failed to list logs: %v", err)
+	}
+	for _, name := range names {
+		if !slices.Contains(logs, name) {
+			t.Errorf("expected %s in %v", name, logs)
+		}
+		offset, err := m.Open(name).TailOffset(ctx)
+		if err != nil || offset != 4 {
+			t.Errorf("expected 4 records in %s, got %d (%v)", name, offset, err)
+		}
+	}
+	for _, name := range names[1:] {
+		if err := emptyBucket(ctx, setupMinioClient(), base.bucketName, name); err != nil {
+			t.Logf("failed to empty %s: %v", name, err)
+		}
+	}
+}
+
+func TestListWALs(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	client := setupMinioClient()
+
+	root := base.prefix + "/tenants"
+	names := []string{root + "/a", root + "/b", root + "/c"}
+	for _, name := range names {
+		w := NewS3WAL(client, base.bucketName, name)
+		if _, err := w.Append(ctx, []byte(name)); err != nil {
+			t.Fatalf("failed to append to %s: %v", name, err)
+		}
+	}
+	if err := NewS3WAL(client, base.bucketName, names[0]).CommitCheckpoint(ctx, "reader", 1); err != nil {
+		t.Fatalf("failed to commit checkpoint: %v", err)
+	}
+
+	for _, r := range []string{root, root + "/"} {
+		logs, err := ListWALs(ctx, client, base.bucketName, r)
+		if err != nil {
+			t.Fatalf("failed to list logs: %v", err)
+		}
+		if !slices.Equal(logs, names) {
+			t.Errorf("ListWALs(%q): expected %v, got %v", r, names, logs)
+		}
+	}
+
+	logs, err := ListWALs(ctx, client, base.bucketName, base.prefix+"/missing")
+	if err != nil || len(logs) != 0 {
+		t.Errorf("expected no logs under a missing prefix, got %v (%v)", logs, err)
+	}
+}
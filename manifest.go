@@ -0,0 +1,167 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// manifestName is the name of the manifest object under the log prefix. It
+// does not end in digits, so listings never take it for a record.
+const manifestName = "manifest"
+
+// manifestConcurrency is the number of checksum reads WriteManifest keeps in
+// flight.
+const manifestConcurrency = 16
+
+// Manifest enumerates the record objects of a log, as written by
+// WriteManifest.
+type Manifest struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Entries     []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry describes one record object.
+type ManifestEntry struct {
+	// Offset is the first offset held by the object, and End its last,
+	// which differs from Offset for a micro-batch.
+	Offset uint64 `json:"offset"`
+	End    uint64 `json:"end"`
+	// Size is the size of the stored object, framing included.
+	Size int64 `json:"size"`
+	// Checksum is the hex-encoded checksum stored in the object's frame,
+	// the one AppendWithChecksum returns for a record stored on its own.
+	Checksum string `json:"checksum"`
+}
+
+func (w *S3WAL) manifestKey() string {
+	return w.keyPrefix() + manifestName
+}
+
+// WriteManifest lists the log and writes a JSON manifest of its record
+// objects, in offset order, to the "manifest" object under the log prefix,
+// replacing any earlier one, so that external indexers can read one object
+// instead of listing the log. Each object's checksum is fetched with a range
+// read. The manifest is a snapshot: records appended or deleted afterwards
+// are only reflected once it is written again.
+func (w *S3WAL) WriteManifest(ctx context.Context) error {
+	manifest := Manifest{GeneratedAt: w.now().UTC()}
+	err := w.forEachObject(ctx, func(offset uint64, obj types.Object) error {
+		manifest.Entries = append(manifest.Entries, ManifestEntry{
+			Offset: offset,
+			End:    offset,
+			Size:   aws.ToInt64(obj.Size),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.fillManifestEntries(ctx, manifest.Entries); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	_, err = w.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(w.bucketName),
+		Key:         aws.String(w.manifestKey()),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	return nil
+}
+
+// fillManifestEntries reads the checksum and, for micro-batches, the last
+// offset of every entry.
+func (w *S3WAL) fillManifestEntries(ctx context.Context, entries []ManifestEntry) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	work := make(chan *ManifestEntry)
+	var workers sync.WaitGroup
+	for range min(manifestConcurrency, len(entries)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for entry := range work {
+				if err := w.fillManifestEntry(ctx, entry); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+send:
+	for i := range entries {
+		select {
+		case work <- &entries[i]:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(work)
+	workers.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (w *S3WAL) fillManifestEntry(ctx context.Context, entry *ManifestEntry) error {
+	trailer, _, metadata, err := w.getRange(ctx, w.getObjectKey(entry.Offset), fmt.Sprintf("bytes=-%d", checksumSize))
+	if err != nil {
+		return err
+	}
+	if count, ok := metadata[metaMicroBatch]; ok {
+		n, err := strconv.ParseUint(count, 10, 64)
+		if err != nil || n == 0 {
+			return fmt.Errorf("%w: bad micro-batch record count %q", ErrInvalidRecord, count)
+		}
+		entry.End = entry.Offset + n - 1
+	} else if w.leadingChecksum || w.checksummer != nil {
+		if trailer, err = w.leafChecksum(ctx, entry.Offset); err != nil {
+			return err
+		}
+	} else if len(trailer) != checksumSize {
+		return fmt.Errorf("%w: data too short", ErrInvalidRecord)
+	}
+	entry.Checksum = hex.EncodeToString(trailer)
+	return nil
+}
+
+// ReadManifest loads the manifest last written by WriteManifest.
+func (w *S3WAL) ReadManifest(ctx context.Context) (Manifest, error) {
+	body, _, err := w.getBytes(ctx, w.manifestKey())
+	if err != nil {
+		return Manifest{}, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return manifest, nil
+}
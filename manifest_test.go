@@ -0,0 +1,60 @@
+package s3log
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestWriteManifest(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.ReadManifest(ctx); err == nil {
+		t.Error("expected error reading a missing manifest")
+	}
+	_, checksum, err := wal.AppendWithChecksum(ctx, []byte("first"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b"), []byte("c")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte("last")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	generated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	wal.now = func() time.Time { return generated }
+	for range 2 {
+		if err := wal.WriteManifest(ctx); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+	}
+	manifest, err := wal.ReadManifest(ctx)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if !manifest.GeneratedAt.Equal(generated) {
+		t.Errorf("expected generation time %v, got %v", generated, manifest.GeneratedAt)
+	}
+	want := []struct{ offset, end uint64 }{{1, 1}, {2, 4}, {5, 5}}
+	if len(manifest.Entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), manifest.Entries)
+	}
+	for i, entry := range manifest.Entries {
+		if entry.Offset != want[i].offset || entry.End != want[i].end || entry.Size <= 0 || len(entry.Checksum) != 64 {
+			t.Errorf("entry %d: unexpected %+v", i, entry)
+		}
+	}
+	if manifest.Entries[0].Checksum != hex.EncodeToString(checksum) {
+		t.Errorf("expected checksum %x, got %s", checksum, manifest.Entries[0].Checksum)
+	}
+
+	// the manifest is not mistaken for a record
+	if tail, err := wal.TailOffset(ctx); err != nil || tail != 5 {
+		t.Errorf("expected tail 5, got %d (%v)", tail, err)
+	}
+}
@@ -0,0 +1,65 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// raiseMarker stores offset in the marker object under key unless it already
+// holds a greater one, using conditional writes so concurrent writers never
+// move it backwards.
+func (w *S3WAL) raiseMarker(ctx context.Context, key string, offset uint64) error {
+	for {
+		input := &s3.PutObjectInput{
+			Bucket: aws.String(w.bucketName),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader([]byte(strconv.FormatUint(offset, 10))),
+		}
+		if !w.noPreconditions {
+			current, etag, found, err := w.getMarker(ctx, key)
+			switch {
+			case err != nil:
+				return err
+			case !found:
+				input.IfNoneMatch = aws.String("*")
+			case current >= offset:
+				return nil
+			default:
+				input.IfMatch = aws.String(etag)
+			}
+		}
+		w.applyPutOptions(input)
+		_, err := w.client.PutObject(ctx, input)
+		if err == nil {
+			return nil
+		}
+		if !isPreconditionFailed(err) {
+			return fmt.Errorf("failed to put object to S3: %w", err)
+		}
+	}
+}
+
+// getMarker returns the offset held by the marker object under key and the
+// object's ETag, with found false if there is no marker.
+func (w *S3WAL) getMarker(ctx context.Context, key string) (uint64, string, bool, error) {
+	got, err := w.getObject(ctx, key, "")
+	var nsk *types.NoSuchKey
+	if errors.As(err, &nsk) {
+		return 0, "", false, nil
+	}
+	if err != nil {
+		return 0, "", false, err
+	}
+	offset, err := strconv.ParseUint(string(got.data), 10, 64)
+	if err != nil {
+		return 0, "", false, fmt.Errorf("%w: bad marker %q in %s", ErrInvalidRecord, got.data, key)
+	}
+	return offset, got.etag, true, nil
+}
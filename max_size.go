@@ -0,0 +1,166 @@
+package s3log
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WithMaxTotalBytes caps the stored size of the log at n bytes, as reported
+// by Stats. After every append, the oldest objects are deleted until the
+// total is back under n, so the log behaves like a size-bounded ring. Each
+// deleted object is counted as MetricEvictedObjects; a micro-batch is evicted
+// as a whole. Evicted offsets read as ErrTruncated, as after Truncate.
+//
+// The log is listed once, at the first eviction, and then the sizes of the
+// objects this S3WAL writes are added up, so appends do not list the log.
+// Compact, DeleteRange and Truncate make the next eviction list it again;
+// objects written by other processes are only counted by EvictOldest, which
+// always lists the log.
+//
+// Eviction only advances the smallest offset in the log: offsets are never
+// reused, and the newest object is never evicted even if it alone exceeds n,
+// so the tail survives. Failed evictions are logged and retried after the
+// next append, and never fail the append. The chunks of an evicted chunked
+// record are deleted along with it, but do not count towards n.
+// WithMaxTotalBytes panics if n is not positive.
+func WithMaxTotalBytes(n int64) Option {
+	if n <= 0 {
+		panic(fmt.Sprintf("s3log: max total bytes must be positive, got %d", n))
+	}
+	return func(w *S3WAL) {
+		w.maxTotalBytes = n
+	}
+}
+
+// storedObjects tracks the record objects of the log and their total size,
+// so that eviction after an append need not list the log.
+type storedObjects struct {
+	mu sync.Mutex
+	// loaded is set while objects and total reflect the log.
+	loaded  bool
+	objects []storedObject // in offset order
+	total   int64
+}
+
+type storedObject struct {
+	offset uint64
+	size   int64
+}
+
+// noteStored records that this S3WAL wrote an object of size bytes at offset.
+func (w *S3WAL) noteStored(offset uint64, size int64) {
+	if w.maxTotalBytes <= 0 {
+		return
+	}
+	s := &w.stored
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded {
+		return
+	}
+	i, found := slices.BinarySearchFunc(s.objects, offset, func(o storedObject, offset uint64) int {
+		return cmp.Compare(o.offset, offset)
+	})
+	if found {
+		s.total += size - s.objects[i].size
+		s.objects[i].size = size
+		return
+	}
+	s.objects = slices.Insert(s.objects, i, storedObject{offset: offset, size: size})
+	s.total += size
+}
+
+// forgetStored drops the tracked sizes after the log changed in a way they
+// do not follow, so that the next eviction lists the log again.
+func (w *S3WAL) forgetStored() {
+	s := &w.stored
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loaded = false
+	s.objects = nil
+	s.total = 0
+}
+
+// EvictOldest lists the log and deletes its oldest objects until its stored
+// size is at most the cap set by WithMaxTotalBytes, and returns the number of
+// objects deleted. It does nothing without WithMaxTotalBytes.
+//
+// Like Truncate, it first raises the truncation marker to the first offset it
+// keeps, so that Read of an evicted offset fails with ErrTruncated.
+func (w *S3WAL) EvictOldest(ctx context.Context) (int, error) {
+	return w.evict(ctx, true)
+}
+
+// evict is EvictOldest, listing the log only if relist is set or the sizes
+// of its objects are not tracked yet.
+func (w *S3WAL) evict(ctx context.Context, relist bool) (int, error) {
+	if w.maxTotalBytes <= 0 {
+		return 0, nil
+	}
+	s := &w.stored
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if relist || !s.loaded {
+		s.loaded = false
+		var objects []storedObject
+		var total int64
+		err := w.forEachObject(ctx, func(offset uint64, obj types.Object) error {
+			size := aws.ToInt64(obj.Size)
+			objects = append(objects, storedObject{offset: offset, size: size})
+			total += size
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		s.objects, s.total, s.loaded = objects, total, true
+	}
+
+	// the newest object is always kept
+	n := 0
+	for total := s.total; n < len(s.objects)-1 && total > w.maxTotalBytes; n++ {
+		total -= s.objects[n].size
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	before := s.objects[n].offset
+	if err := w.raiseMarker(ctx, w.truncationKey(), before); err != nil {
+		return 0, err
+	}
+	w.noteTruncation(before)
+
+	evicted := 0
+	var err error
+	for _, obj := range s.objects[:n] {
+		if err = w.deleteObject(ctx, obj.offset); err != nil {
+			break
+		}
+		w.readCache.remove(obj.offset)
+		w.incCounter(MetricEvictedObjects, 1)
+		s.total -= obj.size
+		evicted++
+		if err = w.deleteRecordChunks(ctx, obj.offset); err != nil {
+			break
+		}
+	}
+	s.objects = slices.Delete(s.objects, 0, evicted)
+	return evicted, err
+}
+
+// evictAfterAppend evicts the oldest objects after an append, using the
+// tracked sizes, and logs failures.
+func (w *S3WAL) evictAfterAppend(ctx context.Context) {
+	if w.maxTotalBytes <= 0 {
+		return
+	}
+	if _, err := w.evict(ctx, false); err != nil {
+		w.logger.Warn("s3log: failed to evict oldest records", "error", err)
+	}
+}
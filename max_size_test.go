@@ -0,0 +1,80 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMaxTotalBytes(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	metrics := &counters{}
+	// every record object takes 100 bytes, so two fit under the cap
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithMaxTotalBytes(250), WithMetrics(metrics))
+	for i := 1; i <= 5; i++ {
+		if _, err := wal.Append(ctx, bytes.Repeat([]byte{byte('0' + i)}, 60)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if record, err := wal.FirstRecord(ctx); err != nil || record.Offset != 4 {
+		t.Errorf("expected first offset 4, got %d (%v)", record.Offset, err)
+	}
+	if got := metrics.get(MetricEvictedObjects); got != 3 {
+		t.Errorf("expected 3 evicted objects, got %d", got)
+	}
+	if _, err := wal.Read(ctx, 1); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated for an evicted offset, got %v", err)
+	}
+
+	// the newest object is kept even when it alone exceeds the cap
+	offset, err := wal.Append(ctx, bytes.Repeat([]byte("x"), 500))
+	if err != nil || offset != 6 {
+		t.Fatalf("expected offset 6, got %d (%v)", offset, err)
+	}
+	if record, err := wal.FirstRecord(ctx); err != nil || record.Offset != 6 {
+		t.Errorf("expected only offset 6 to remain, got first offset %d (%v)", record.Offset, err)
+	}
+	if tail, err := NewS3WAL(base.client, base.bucketName, base.prefix).TailOffset(ctx); err != nil || tail != 6 {
+		t.Errorf("expected tail 6, got %d (%v)", tail, err)
+	}
+}
+
+func TestMaxTotalBytesTracksSizes(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithMaxTotalBytes(1<<20))
+	for i := 0; i < 5; i++ {
+		if _, err := wal.Append(ctx, []byte("small")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	// the log is listed at the first eviction only
+	if lists := wal.UsageStats().ListRequests; lists != 1 {
+		t.Errorf("expected 1 LIST for 6 appends, got %d", lists)
+	}
+	stats, err := wal.Stats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if wal.stored.total != stats.Bytes {
+		t.Errorf("expected tracked size %d, got %d", stats.Bytes, wal.stored.total)
+	}
+}
+
+func TestWithMaxTotalBytesPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a zero cap")
+		}
+	}()
+	WithMaxTotalBytes(0)
+}
@@ -0,0 +1,211 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Merkle trees are built over record checksums following RFC 6962: leaves are
+// hashed as SHA-256(0x00 || checksum) and interior nodes as
+// SHA-256(0x01 || left || right), splitting at the largest power of two
+// smaller than the number of leaves.
+
+func merkleLeafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func merkleTreeRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return merkleLeafHash(leaves[0])
+	}
+	k := merkleSplit(len(leaves))
+	return merkleNodeHash(merkleTreeRoot(leaves[:k]), merkleTreeRoot(leaves[k:]))
+}
+
+func merkleTreePath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := merkleSplit(len(leaves))
+	if m < k {
+		return append(merkleTreePath(m, leaves[:k]), merkleTreeRoot(leaves[k:]))
+	}
+	return append(merkleTreePath(m-k, leaves[k:]), merkleTreeRoot(leaves[:k]))
+}
+
+// leafChecksum returns the stored checksum of the record at offset. Records
+// stored in their own object only have their checksum fetched with a range
+// read, at the end of the object, or at its start with ChecksumLeading. With
+// WithChecksum the whole object is read, since the checksum's size and
+// position depend on the algorithm named in its header. Records inside a
+// micro-batch have no checksum of their own, so theirs is computed as the
+// checksum a plain frame of the record would carry.
+func (w *S3WAL) leafChecksum(ctx context.Context, offset uint64) ([]byte, error) {
+	if w.checksummer != nil {
+		data, metadata, err := w.getBytes(ctx, w.getObjectKey(offset))
+		var nsk *types.NoSuchKey
+		if err != nil && !errors.As(err, &nsk) {
+			return nil, err
+		}
+		if err == nil && metadata[metaMicroBatch] == "" {
+			if len(data) > 0 && data[0] == frameMagic && metadata[metaNoOffsetHeader] == "" {
+				h, _, err := decodeFrame(data, offset)
+				if err != nil {
+					return nil, err
+				}
+				return h.storedChecksum(data), nil
+			}
+			if len(data) < checksumSize {
+				return nil, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+			}
+			return data[len(data)-checksumSize:], nil
+		}
+	} else if w.leadingChecksum {
+		head, _, metadata, err := w.getRange(ctx, w.getObjectKey(offset), fmt.Sprintf("bytes=0-%d", 2+checksumSize-1))
+		var nsk *types.NoSuchKey
+		if err != nil && !errors.As(err, &nsk) {
+			return nil, err
+		}
+		if err == nil && len(head) == 2+checksumSize && head[0] == frameMagic &&
+			frameFlags(head[1])&flagLeadingChecksum != 0 && metadata[metaNoOffsetHeader] == "" {
+			return head[2:], nil
+		}
+	}
+	result, err := w.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.getObjectKey(offset)),
+		Range:  aws.String("bytes=-32"),
+	})
+	var nsk *types.NoSuchKey
+	if err != nil && !errors.As(err, &nsk) {
+		return nil, fmt.Errorf("failed to get object from s3: %w", err)
+	}
+	if err == nil {
+		defer result.Body.Close()
+		if _, batch := result.Metadata[metaMicroBatch]; !batch {
+			trailer, err := io.ReadAll(result.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read object body: %w", err)
+			}
+			if len(trailer) != 32 {
+				return nil, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+			}
+			return trailer, nil
+		}
+	}
+	record, err := w.Read(ctx, offset)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := prepareBody(offset, record.Data)
+	if err != nil {
+		return nil, err
+	}
+	return frame[len(frame)-32:], nil
+}
+
+func (w *S3WAL) merkleLeaves(ctx context.Context, start, end uint64) ([][]byte, error) {
+	if start == 0 || end < start {
+		return nil, fmt.Errorf("invalid offset range [%d, %d]", start, end)
+	}
+	leaves := make([][]byte, 0, end-start+1)
+	for offset := start; offset <= end; offset++ {
+		leaf, err := w.leafChecksum(ctx, offset)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, leaf)
+	}
+	return leaves, nil
+}
+
+// AppendWithChecksum is Append that also returns the checksum stored with the
+// record, which is its leaf in the trees built by MerkleRoot and the checksum
+// expected by VerifyInclusionProof. With WithContentKeying, a duplicate
+// append returns the checksum of the earlier record.
+func (w *S3WAL) AppendWithChecksum(ctx context.Context, data []byte) (uint64, []byte, error) {
+	return w.append(ctx, frameHeader{}, nil, data)
+}
+
+// MerkleRoot returns the root of a Merkle tree whose leaves are the checksums
+// of the records at offsets [start, end], in offset order.
+func (w *S3WAL) MerkleRoot(ctx context.Context, start, end uint64) ([]byte, error) {
+	leaves, err := w.merkleLeaves(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return merkleTreeRoot(leaves), nil
+}
+
+// InclusionProof returns the audit path proving that the record at offset is
+// included in the tree returned by MerkleRoot for [start, end]. It can be
+// checked with VerifyInclusionProof.
+func (w *S3WAL) InclusionProof(ctx context.Context, offset, start, end uint64) ([][]byte, error) {
+	if offset < start || offset > end {
+		return nil, fmt.Errorf("offset %d is outside [%d, %d]", offset, start, end)
+	}
+	leaves, err := w.merkleLeaves(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return merkleTreePath(int(offset-start), leaves), nil
+}
+
+// VerifyInclusionProof reports whether proof shows that checksum is the leaf at
+// index (offset minus the start of the range) in a tree of size leaves with
+// the given root.
+func VerifyInclusionProof(root, checksum []byte, index, size uint64, proof [][]byte) bool {
+	if index >= size {
+		return false
+	}
+	fn, sn := index, size-1
+	r := merkleLeafHash(checksum)
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
@@ -0,0 +1,105 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestMerkleRootAndInclusionProof(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("audit %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("x"), []byte("y"), []byte("z")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if _, err := wal.AppendWithSchema(ctx, "audit.v1", []byte("audit 6")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	root, err := wal.MerkleRoot(ctx, 1, 7)
+	if err != nil {
+		t.Fatalf("failed to compute merkle root: %v", err)
+	}
+	other, err := wal.MerkleRoot(ctx, 1, 6)
+	if err != nil {
+		t.Fatalf("failed to compute merkle root: %v", err)
+	}
+	if bytes.Equal(root, other) {
+		t.Error("roots of different ranges must differ")
+	}
+
+	for offset := uint64(1); offset <= 7; offset++ {
+		leaf, err := wal.leafChecksum(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to get leaf %d: %v", offset, err)
+		}
+		proof, err := wal.InclusionProof(ctx, offset, 1, 7)
+		if err != nil {
+			t.Fatalf("failed to build proof for %d: %v", offset, err)
+		}
+		if !VerifyInclusionProof(root, leaf, offset-1, 7, proof) {
+			t.Errorf("proof for offset %d does not verify", offset)
+		}
+		if VerifyInclusionProof(other, leaf, offset-1, 7, proof) {
+			t.Errorf("proof for offset %d verifies against the wrong root", offset)
+		}
+		tampered := append([]byte{}, leaf...)
+		tampered[0] ^= 1
+		if VerifyInclusionProof(root, tampered, offset-1, 7, proof) {
+			t.Errorf("proof for offset %d verifies a tampered leaf", offset)
+		}
+	}
+
+	if _, err := wal.InclusionProof(ctx, 9, 1, 7); err == nil {
+		t.Error("expected error for offset outside the range, got nil")
+	}
+	if _, err := wal.MerkleRoot(ctx, 1, 8); err == nil {
+		t.Error("expected error for range past the tail, got nil")
+	}
+}
+
+func TestAppendWithChecksum(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	WithContentKeying()(wal)
+
+	var checksums [][]byte
+	for _, data := range [][]byte{[]byte("small"), bytes.Repeat([]byte("x"), 10000), []byte("small")} {
+		offset, checksum, err := wal.AppendWithChecksum(ctx, data)
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		stored, err := wal.leafChecksum(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read checksum of offset %d: %v", offset, err)
+		}
+		if !bytes.Equal(checksum, stored) {
+			t.Errorf("offset %d: returned checksum %x, stored %x", offset, checksum, stored)
+		}
+		checksums = append(checksums, checksum)
+	}
+	if !bytes.Equal(checksums[0], checksums[2]) {
+		t.Error("expected a duplicate append to return the original checksum")
+	}
+
+	root, err := wal.MerkleRoot(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("failed to compute root: %v", err)
+	}
+	proof, err := wal.InclusionProof(ctx, 2, 1, 2)
+	if err != nil {
+		t.Fatalf("failed to compute proof: %v", err)
+	}
+	if !VerifyInclusionProof(root, checksums[1], 1, 2, proof) {
+		t.Error("returned checksum does not verify against the tree")
+	}
+}
@@ -0,0 +1,55 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxUserMetadataSize is the S3 limit on the total size of user metadata: the
+// sum of the UTF-8 lengths of all keys and values.
+const maxUserMetadataSize = 2 << 10
+
+// reservedMetadataPrefix prefixes the metadata keys used by the WAL itself.
+const reservedMetadataPrefix = "s3log-"
+
+// AppendWithMetadata appends data and stores metadata as S3 user metadata on
+// the record object, returned as Record.Metadata on read. S3 lower-cases
+// metadata keys. Metadata larger than S3 allows is rejected before the put
+// with ErrMetadataTooLarge, and keys starting with "s3log-" are reserved.
+func (w *S3WAL) AppendWithMetadata(ctx context.Context, metadata map[string]string, data []byte) (uint64, error) {
+	if err := validateMetadata(metadata); err != nil {
+		return 0, err
+	}
+	offset, _, err := w.append(ctx, frameHeader{}, metadata, data)
+	return offset, err
+}
+
+func validateMetadata(metadata map[string]string) error {
+	size := 0
+	for k, v := range metadata {
+		if strings.HasPrefix(strings.ToLower(k), reservedMetadataPrefix) {
+			return fmt.Errorf("metadata key %q uses the reserved prefix %q", k, reservedMetadataPrefix)
+		}
+		size += len(k) + len(v)
+	}
+	if size > maxUserMetadataSize {
+		return fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrMetadataTooLarge, size, maxUserMetadataSize)
+	}
+	return nil
+}
+
+// userMetadata strips the WAL's own keys from object metadata.
+func userMetadata(metadata map[string]string) map[string]string {
+	var user map[string]string
+	for k, v := range metadata {
+		if strings.HasPrefix(k, reservedMetadataPrefix) {
+			continue
+		}
+		if user == nil {
+			user = make(map[string]string)
+		}
+		user[k] = v
+	}
+	return user
+}
@@ -0,0 +1,48 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAppendWithMetadata(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	offset, err := wal.AppendWithMetadata(ctx, map[string]string{"source": "billing", "Trace-Id": "abc"}, []byte("invoice"))
+	if err != nil {
+		t.Fatalf("failed to append with metadata: %v", err)
+	}
+	record, err := wal.Read(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if record.Metadata["source"] != "billing" || record.Metadata["trace-id"] != "abc" || len(record.Metadata) != 2 {
+		t.Errorf("unexpected metadata: %v", record.Metadata)
+	}
+
+	tooLarge := map[string]string{"blob": strings.Repeat("x", maxUserMetadataSize)}
+	if _, err := wal.AppendWithMetadata(ctx, tooLarge, []byte("nope")); !errors.Is(err, ErrMetadataTooLarge) {
+		t.Errorf("expected ErrMetadataTooLarge, got %v", err)
+	}
+	if _, err := wal.AppendWithMetadata(ctx, map[string]string{"S3log-Micro-Batch": "1"}, []byte("nope")); err == nil {
+		t.Error("expected error for reserved metadata key, got nil")
+	}
+	if wal.length != offset {
+		t.Errorf("rejected appends must not advance the log, length is %d", wal.length)
+	}
+
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	record, err = wal.LastRecord(ctx)
+	if err != nil {
+		t.Fatalf("failed to get last record: %v", err)
+	}
+	if record.Metadata != nil {
+		t.Errorf("expected no user metadata on micro-batch record, got %v", record.Metadata)
+	}
+}
@@ -0,0 +1,97 @@
+package s3log
+
+import "time"
+
+// Metrics receives counts of events that do not fail an operation, such as
+// repaired or mismatching records. Implementations must be safe for
+// concurrent use.
+type Metrics interface {
+	IncCounter(name string, delta int64)
+	SetGauge(name string, value float64)
+}
+
+// HistogramMetrics is implemented by Metrics that also record distributions
+// of values, such as record sizes. The values are only reported to Metrics
+// that implement it.
+type HistogramMetrics interface {
+	Metrics
+	Observe(name string, value float64)
+}
+
+// LatencyMetrics is implemented by Metrics that also record how long
+// operations take. The latencies of Append and Read are only reported to
+// Metrics that implement it.
+type LatencyMetrics interface {
+	Metrics
+	ObserveLatency(name string, d time.Duration)
+}
+
+// Counter names reported to Metrics.
+const (
+	MetricReadRepairs = "s3log_read_repairs"
+	// MetricChecksumRetryFixed counts reads whose checksum mismatch went
+	// away when the object was downloaded again.
+	MetricChecksumRetryFixed = "s3log_checksum_retry_fixed"
+	MetricReadBackVerified   = "s3log_read_back_verified"
+	MetricReadBackMismatch   = "s3log_read_back_mismatch"
+	MetricMirroredRecords    = "s3log_mirrored_records"
+	// MetricMirrorLag is a gauge of the number of offsets the mirror
+	// destination is behind the source.
+	MetricMirrorLag = "s3log_mirror_lag"
+	// MetricAppends and MetricReads count successful appends and reads of
+	// single records; MetricAppendErrors and MetricReadErrors count failed
+	// ones. The latency metrics are reported for both.
+	MetricAppends       = "s3log_appends"
+	MetricAppendErrors  = "s3log_append_errors"
+	MetricAppendLatency = "s3log_append_latency"
+	MetricReads         = "s3log_reads"
+	MetricReadErrors    = "s3log_read_errors"
+	MetricReadLatency   = "s3log_read_latency"
+	// MetricEvictedObjects counts objects deleted to stay under the cap set
+	// by WithMaxTotalBytes.
+	MetricEvictedObjects = "s3log_evicted_objects"
+	// MetricRecordSize is a histogram of the payload sizes of appended
+	// records, including those rejected by WithRecordSizeBounds.
+	MetricRecordSize = "s3log_record_size"
+)
+
+// WithMetrics sets the Metrics that events are reported to. By default they
+// are only logged, if at all.
+func WithMetrics(m Metrics) Option {
+	return func(w *S3WAL) {
+		w.metrics = m
+	}
+}
+
+func (w *S3WAL) incCounter(name string, delta int64) {
+	if w.metrics != nil {
+		w.metrics.IncCounter(name, delta)
+	}
+}
+
+func (w *S3WAL) setGauge(name string, value float64) {
+	if w.metrics != nil {
+		w.metrics.SetGauge(name, value)
+	}
+}
+
+func (w *S3WAL) observeValue(name string, value float64) {
+	if hm, ok := w.metrics.(HistogramMetrics); ok {
+		hm.Observe(name, value)
+	}
+}
+
+// observe reports one operation that began at start and ended with err.
+func (w *S3WAL) observe(count, errs, latency string, start time.Time, err error) {
+	if w.metrics == nil {
+		return
+	}
+	if err != nil {
+		w.metrics.IncCounter(errs, 1)
+	} else {
+		w.metrics.IncCounter(count, 1)
+	}
+	if lm, ok := w.metrics.(LatencyMetrics); ok {
+		lm.ObserveLatency(latency, time.Since(start))
+	}
+}
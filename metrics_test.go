@@ -0,0 +1,55 @@
+package s3log
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type latencies struct {
+	counters
+	observed map[string]int
+}
+
+func (l *latencies) ObserveLatency(name string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.observed == nil {
+		l.observed = make(map[string]int)
+	}
+	l.observed[name]++
+}
+
+func TestOperationMetrics(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	m := &latencies{}
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithMetrics(m))
+	for range 2 {
+		if _, err := wal.Append(ctx, []byte("data")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.Read(ctx, 1); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if _, err := wal.Read(ctx, 5); err == nil {
+		t.Fatal("expected error reading a missing offset, got nil")
+	}
+
+	for name, want := range map[string]int64{
+		MetricAppends:      2,
+		MetricAppendErrors: 0,
+		MetricReads:        1,
+		MetricReadErrors:   1,
+	} {
+		if got := m.get(name); got != want {
+			t.Errorf("expected %s %d, got %d", name, want, got)
+		}
+	}
+	if m.observed[MetricAppendLatency] != 2 || m.observed[MetricReadLatency] != 2 {
+		t.Errorf("expected 2 latencies per operation, got %v", m.observed)
+	}
+}
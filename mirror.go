@@ -0,0 +1,84 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MirrorTo copies the records of the log, starting at fromOffset, to dst and
+// keeps following the log, copying records as they are appended, until ctx
+// is cancelled. On restart it resumes after dst's last record, so it can be
+// run with the same fromOffset every time.
+//
+// Records keep their offsets: MirrorTo fails if dst assigns a record an
+// offset other than its offset in the source, which happens if anything else
+// appends to dst. When dst is an *S3WAL, each record is written at its source
+// offset, so gaps in the source, such as a head removed by Truncate, carry
+// over to the mirror, and schema IDs and user metadata are copied too.
+// Otherwise only the data is copied, and a source with gaps fails.
+//
+// Mirrored records are counted as MetricMirroredRecords. While copying,
+// MetricMirrorLag is refreshed from the source's tail at most once per poll
+// interval, and it is set to zero whenever MirrorTo has caught up.
+func (w *S3WAL) MirrorTo(ctx context.Context, dst WAL, fromOffset uint64) error {
+	next := max(fromOffset, 1)
+	last, err := dst.LastRecord(ctx)
+	if err != nil && !errors.Is(err, ErrWALEmpty) {
+		return fmt.Errorf("failed to find the tail of the mirror: %w", err)
+	}
+	if err == nil {
+		next = max(next, last.Offset+1)
+	}
+
+	var lagUpdated time.Time
+	for {
+		records, err := w.recordsFrom(ctx, next)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			w.setGauge(MetricMirrorLag, 0)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(defaultPollInterval):
+			}
+			continue
+		}
+		for _, record := range records {
+			if record.Offset < next {
+				continue
+			}
+			offset, err := mirrorAppend(ctx, dst, record)
+			if err != nil {
+				return fmt.Errorf("failed to mirror offset %d: %w", record.Offset, err)
+			}
+			if offset != record.Offset {
+				return fmt.Errorf("mirror assigned offset %d to source offset %d", offset, record.Offset)
+			}
+			w.incCounter(MetricMirroredRecords, 1)
+			next = record.Offset + 1
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Since(lagUpdated) >= defaultPollInterval {
+			if tail, err := w.TailOffset(ctx); err == nil {
+				w.setGauge(MetricMirrorLag, float64(tail-min(tail, next-1)))
+			}
+			lagUpdated = time.Now()
+		}
+	}
+}
+
+func mirrorAppend(ctx context.Context, dst WAL, record Record) (uint64, error) {
+	w, ok := dst.(*S3WAL)
+	if !ok {
+		return dst.Append(ctx, record.Data)
+	}
+	w.raiseLength(record.Offset - 1)
+	offset, _, err := w.append(ctx, frameHeader{schemaID: record.SchemaID}, withPartitionKey(record.Metadata, record), record.Data)
+	return offset, err
+}
@@ -0,0 +1,126 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMirrorTo(t *testing.T) {
+	src, cleanup := getWAL(t)
+	defer cleanup()
+	dst, cleanupDst := getWAL(t)
+	defer cleanupDst()
+	ctx := context.Background()
+
+	defer func(d time.Duration) { defaultPollInterval = d }(defaultPollInterval)
+	defaultPollInterval = 10 * time.Millisecond
+	metrics := &counters{}
+	WithMetrics(metrics)(src)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := src.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := src.AppendWithMetadata(ctx, map[string]string{"k": "v"}, []byte("record 3")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	mirror := func(until uint64) {
+		t.Helper()
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- src.MirrorTo(runCtx, dst, 1) }()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if record, err := NewS3WAL(dst.client, dst.bucketName, dst.prefix).LastRecord(ctx); err == nil && record.Offset == until {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("mirror did not reach offset %d", until)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	}
+	mirror(3)
+
+	// resumes from the mirror's tail
+	if _, err := src.AppendMicroBatch(ctx, [][]byte{[]byte("record 4"), []byte("record 5")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	dst.length = 0
+	mirror(5)
+
+	for offset := uint64(1); offset <= 5; offset++ {
+		record, err := dst.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read mirrored offset %d: %v", offset, err)
+		}
+		if string(record.Data) != fmt.Sprintf("record %d", offset) {
+			t.Errorf("offset %d: unexpected data %q", offset, record.Data)
+		}
+	}
+	if record, _ := dst.Read(ctx, 3); record.Metadata["k"] != "v" {
+		t.Errorf("expected metadata to be mirrored, got %v", record.Metadata)
+	}
+	if n := metrics.get(MetricMirroredRecords); n != 5 {
+		t.Errorf("expected 5 mirrored records, got %d", n)
+	}
+}
+
+func TestMirrorToTruncatedSource(t *testing.T) {
+	src, cleanup := getWAL(t)
+	defer cleanup()
+	dst, cleanupDst := getWAL(t)
+	defer cleanupDst()
+	ctx := context.Background()
+
+	defer func(d time.Duration) { defaultPollInterval = d }(defaultPollInterval)
+	defaultPollInterval = 10 * time.Millisecond
+
+	for i := 1; i <= 6; i++ {
+		if _, err := src.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := src.Truncate(ctx, 4, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if _, err := src.DeleteRange(ctx, 5, 5, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- src.MirrorTo(runCtx, dst, 1) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if record, err := NewS3WAL(dst.client, dst.bucketName, dst.prefix).LastRecord(ctx); err == nil && record.Offset == 6 {
+			break
+		}
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("mirror did not reach offset 6: %v", <-done)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	for _, offset := range []uint64{4, 6} {
+		if record, err := dst.Read(ctx, offset); err != nil || string(record.Data) != fmt.Sprintf("record %d", offset) {
+			t.Errorf("offset %d: unexpected record %q (%v)", offset, record.Data, err)
+		}
+	}
+	if first, err := dst.FirstRecord(ctx); err != nil || first.Offset != 4 {
+		t.Errorf("expected the mirror to start at offset 4, got %d (%v)", first.Offset, err)
+	}
+}
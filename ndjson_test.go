@@ -0,0 +1,49 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDumpNDJSON(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Append(ctx, []byte("skipped")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte(`say "hi"`)); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendWithSchema(ctx, "v1", []byte{0xff, 0x00}); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte(""), []byte("last")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := wal.DumpNDJSON(ctx, 2, 100, &out); err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+	want := strings.Join([]string{
+		`{"offset":2,"data":"say \"hi\""}`,
+		`{"offset":3,"schema_id":"v1","data_base64":"/wA="}`,
+		`{"offset":4,"data":""}`,
+		`{"offset":5,"data":"last"}`,
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, out.String())
+	}
+
+	out.Reset()
+	if err := wal.DumpNDJSON(ctx, 4, 4, &out); err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+	if out.String() != `{"offset":4,"data":""}`+"\n" {
+		t.Errorf("expected only offset 4, got %q", out.String())
+	}
+}
@@ -0,0 +1,45 @@
+package s3log
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// WithCacheControl sets the Cache-Control header of every record object, e.g.
+// "public, max-age=31536000, immutable". Records never change once written,
+// so they can be cached indefinitely by a CDN fronting the bucket. It is unset
+// by default.
+func WithCacheControl(cacheControl string) Option {
+	return func(w *S3WAL) {
+		w.cacheControl = cacheControl
+	}
+}
+
+// WithContentMD5 sets the Content-MD5 header of every record upload sent as
+// a single PutObject, so that S3 verifies the body it received and rejects
+// the put if it was corrupted in transit. The checksum stored in the frame
+// still guards the record at rest. Uploads made with the upload manager or
+// AppendStream are sent in parts and are not covered. It is off by default.
+func WithContentMD5() Option {
+	return func(w *S3WAL) {
+		w.contentMD5 = true
+	}
+}
+
+// contentMD5 returns the base64 MD5 digest of the body of a PutObject, or
+// nil if the body cannot be read without consuming it.
+func contentMD5(body io.Reader) *string {
+	r, ok := body.(*bytes.Reader)
+	if !ok {
+		return nil
+	}
+	h := md5.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, r.Size())); err != nil {
+		return nil
+	}
+	return aws.String(base64.StdEncoding.EncodeToString(h.Sum(nil)))
+}
@@ -0,0 +1,82 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestCacheControl(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	const cacheControl = "public, max-age=31536000, immutable"
+	WithCacheControl(cacheControl)(wal)
+
+	offset, err := wal.Append(ctx, []byte("cacheable"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	head, err := wal.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(wal.getObjectKey(offset)),
+	})
+	if err != nil {
+		t.Fatalf("failed to head object: %v", err)
+	}
+	if aws.ToString(head.CacheControl) != cacheControl {
+		t.Errorf("expected Cache-Control %q, got %q", cacheControl, aws.ToString(head.CacheControl))
+	}
+}
+
+// tamperingClient records the Content-MD5 of every put and, while tamper is
+// set, flips a byte of the body after the digest was computed.
+type tamperingClient struct {
+	S3Client
+	tamper  bool
+	digests []string
+}
+
+func (c *tamperingClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.digests = append(c.digests, aws.ToString(params.ContentMD5))
+	if c.tamper {
+		body, err := io.ReadAll(params.Body)
+		if err != nil {
+			return nil, err
+		}
+		body[0] ^= 1
+		params.Body = bytes.NewReader(body)
+	}
+	return c.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func TestContentMD5(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client := &tamperingClient{S3Client: base.client}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithContentMD5())
+	if _, err := wal.Append(ctx, []byte("checked")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	frame, _ := prepareBody(1, []byte("checked"))
+	sum := md5.Sum(frame)
+	if want := base64.StdEncoding.EncodeToString(sum[:]); len(client.digests) != 1 || client.digests[0] != want {
+		t.Errorf("expected Content-MD5 %q, got %q", want, client.digests)
+	}
+
+	client.tamper = true
+	if _, err := wal.Append(ctx, []byte("corrupted in transit")); err == nil {
+		t.Error("expected S3 to reject a body that does not match its Content-MD5, got nil")
+	}
+	if _, err := base.Read(ctx, 2); err == nil {
+		t.Error("expected the rejected record not to be stored")
+	}
+}
@@ -0,0 +1,49 @@
+package s3log
+
+import "context"
+
+// OffsetIndex stores the tail offset of a log outside of S3, so that it can be
+// read without listing. Implementations must be safe for concurrent use.
+// See the dynamoindex package for one backed by DynamoDB.
+type OffsetIndex interface {
+	// GetTail returns the recorded tail offset, or 0 if none is recorded.
+	GetTail(ctx context.Context) (uint64, error)
+	// PutTail records offset as the tail unless a greater offset is already
+	// recorded.
+	PutTail(ctx context.Context, offset uint64) error
+}
+
+// WithOffsetIndex makes TailOffset and LastRecord read the tail from index
+// instead of listing S3, unless WithTailMode(TailCached) already answers
+// them. Every append updates the index after its upload succeeds. This is not
+// atomic with the upload: if updating the index fails, the error is logged,
+// the append still succeeds and the index lags behind until a later append
+// moves it forward. While the index is empty, e.g. for an existing log, the
+// tail is found by listing and then recorded in the index.
+func WithOffsetIndex(index OffsetIndex) Option {
+	return func(w *S3WAL) {
+		w.offsetIndex = index
+	}
+}
+
+// indexedTail returns the tail recorded in the offset index, if any.
+func (w *S3WAL) indexedTail(ctx context.Context) (uint64, bool, error) {
+	if w.offsetIndex == nil {
+		return 0, false, nil
+	}
+	tail, err := w.offsetIndex.GetTail(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	return tail, tail > 0, nil
+}
+
+// updateIndex records offset in the offset index, if any.
+func (w *S3WAL) updateIndex(ctx context.Context, offset uint64) {
+	if w.offsetIndex == nil {
+		return
+	}
+	if err := w.offsetIndex.PutTail(ctx, offset); err != nil {
+		w.logger.Warn("s3log: failed to update offset index", "offset", offset, "error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// memoryIndex is an OffsetIndex kept in memory.
+type memoryIndex struct {
+	mu   sync.Mutex
+	tail uint64
+	err  error
+}
+
+func (i *memoryIndex) GetTail(context.Context) (uint64, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.tail, i.err
+}
+
+func (i *memoryIndex) PutTail(_ context.Context, offset uint64) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.err != nil {
+		return i.err
+	}
+	i.tail = max(i.tail, offset)
+	return nil
+}
+
+func TestOffsetIndex(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// an existing log seeds an empty index by listing
+	for _, data := range []string{"one", "two"} {
+		if _, err := wal.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	index := &memoryIndex{}
+	WithOffsetIndex(index)(wal)
+	if tail, err := wal.TailOffset(ctx); err != nil || tail != 2 {
+		t.Fatalf("expected tail 2, got %d (%v)", tail, err)
+	}
+	if index.tail != 2 {
+		t.Errorf("expected the index to be seeded with 2, got %d", index.tail)
+	}
+
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("three"), []byte("four")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if index.tail != 4 {
+		t.Errorf("expected the index to follow appends, got %d", index.tail)
+	}
+	if _, err := wal.Append(ctx, []byte("five")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	reader := NewS3WAL(wal.client, wal.bucketName, wal.prefix, WithOffsetIndex(index))
+	lists := reader.UsageStats().ListRequests
+	if tail, err := reader.TailOffset(ctx); err != nil || tail != 5 {
+		t.Errorf("expected tail 5, got %d (%v)", tail, err)
+	}
+	record, err := reader.LastRecord(ctx)
+	if err != nil || record.Offset != 5 || string(record.Data) != "five" {
+		t.Errorf("expected the last record to be five, got %+v (%v)", record, err)
+	}
+	if n := reader.UsageStats().ListRequests - lists; n != 0 {
+		t.Errorf("expected no list requests with an index, got %d", n)
+	}
+
+	// failing to update the index does not fail the append
+	index.err = errors.New("index unavailable")
+	if _, err := wal.Append(ctx, []byte("six")); err != nil {
+		t.Errorf("expected the append to succeed, got %v", err)
+	}
+	if _, err := reader.TailOffset(ctx); !errors.Is(err, index.err) {
+		t.Errorf("expected the index error, got %v", err)
+	}
+}
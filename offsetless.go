@@ -0,0 +1,56 @@
+package s3log
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+)
+
+// metaNoOffsetHeader is the user metadata key marking a record written with
+// WithoutOffsetHeader, whose frame is just data || checksum.
+const metaNoOffsetHeader = "s3log-no-offset-header"
+
+// WithoutOffsetHeader stores plain records as data || checksum, dropping the
+// 8-byte offset header, since the offset is already encoded in the key. Such
+// records are marked in their user metadata, so logs mixing both framings
+// read correctly. Records that need an extended header (schema, timestamp or
+// chunking) keep their header, offset included, and so do micro-batches.
+//
+// Without the header, a record's offset comes from its key alone: an object
+// copied or renamed to the wrong key is no longer detected on read, and the
+// checksum covers only the data.
+func WithoutOffsetHeader() Option {
+	return func(w *S3WAL) {
+		w.noOffsetHeader = true
+	}
+}
+
+// frameRecord frames data for h.offset and returns it along with the user
+// metadata to store with it.
+func (w *S3WAL) frameRecord(h frameHeader, metadata map[string]string, data []byte) ([]byte, map[string]string, error) {
+	if !w.noOffsetHeader || h.flags() != 0 {
+		buf, err := encodeFrame(h, data)
+		return buf, metadata, err
+	}
+	buf := make([]byte, 0, len(data)+32)
+	buf = append(buf, data...)
+	checksum := calculateChecksum(bytes.NewBuffer(buf))
+	buf = append(buf, checksum[:]...)
+
+	metadata = maps.Clone(metadata)
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata[metaNoOffsetHeader] = "1"
+	return buf, metadata, nil
+}
+
+func decodeOffsetlessFrame(data []byte) ([]byte, error) {
+	if len(data) < checksumSize {
+		return nil, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+	}
+	if !validateChecksum(data) {
+		return nil, ErrChecksumMismatch
+	}
+	return data[:len(data)-checksumSize], nil
+}
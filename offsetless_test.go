@@ -0,0 +1,63 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithoutOffsetHeader(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Append(ctx, []byte("framed")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	WithoutOffsetHeader()(wal)
+	offset, err := wal.AppendWithMetadata(ctx, map[string]string{"k": "v"}, []byte("offsetless"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	// an offsetless record whose data looks like an extended frame header
+	if _, err := wal.Append(ctx, []byte{frameMagic, byte(flagTimestamp), 0, 0, 0, 0, 0, 0, 0, 3}); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendWithSchema(ctx, "v1", []byte("with schema")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	sizes, err := wal.ListRecordSizes(ctx)
+	if err != nil {
+		t.Fatalf("failed to list sizes: %v", err)
+	}
+	if sizes[offset] != int64(len("offsetless"))-8 {
+		t.Errorf("expected the offset header to be omitted, got size %d", sizes[offset])
+	}
+
+	reader := NewS3WAL(wal.client, wal.bucketName, wal.prefix)
+	for offset, data := range map[uint64]string{1: "framed", 2: "offsetless", 4: "with schema"} {
+		record, err := reader.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if record.Offset != offset || string(record.Data) != data {
+			t.Errorf("offset %d: expected %q, got %d %q", offset, data, record.Offset, record.Data)
+		}
+	}
+	record, err := reader.Read(ctx, 2)
+	if err != nil || len(record.Metadata) != 1 || record.Metadata["k"] != "v" {
+		t.Errorf("expected only user metadata, got %v (%v)", record.Metadata, err)
+	}
+	if record, err := reader.Read(ctx, 3); err != nil || len(record.Data) != 10 || !record.Timestamp.IsZero() {
+		t.Errorf("unexpected record at offset 3: %+v (%v)", record, err)
+	}
+	if tail, err := reader.ReadTail(ctx, 2, 4); err != nil || string(tail) != "less" {
+		t.Errorf("expected tail %q, got %q (%v)", "less", tail, err)
+	}
+
+	corruptRecord(t, wal, 2)
+	if _, err := reader.Read(ctx, 2); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+}
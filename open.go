@@ -0,0 +1,20 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+)
+
+// Open is NewS3WAL for a log that may already hold records: it finds the
+// tail the way TailOffset does, from the OffsetIndex if one is set and
+// otherwise by listing the log, and sets the length to it so the first
+// Append writes the next offset instead of failing on an existing key. A
+// log emptied by Truncate continues after the truncated offsets, and a log
+// that never held records keeps a length of 0.
+func Open(ctx context.Context, client S3Client, bucketName, prefix string, opts ...Option) (*S3WAL, error) {
+	w := NewS3WAL(client, bucketName, prefix, opts...)
+	if _, err := w.TailOffset(ctx); err != nil && !errors.Is(err, ErrWALEmpty) {
+		return nil, err
+	}
+	return w, nil
+}
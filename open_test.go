@@ -0,0 +1,41 @@
+package s3log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	empty, err := Open(ctx, base.client, base.bucketName, base.prefix)
+	if err != nil {
+		t.Fatalf("failed to open empty log: %v", err)
+	}
+	if empty.length != 0 {
+		t.Errorf("expected length 0 for an empty log, got %d", empty.length)
+	}
+
+	for _, data := range []string{"a", "b", "c"} {
+		if _, err := base.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := base.AppendMicroBatch(ctx, [][]byte{[]byte("d"), []byte("e")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+
+	wal, err := Open(ctx, base.client, base.bucketName, base.prefix)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	offset, err := wal.Append(ctx, []byte("f"))
+	if err != nil {
+		t.Fatalf("failed to append after reopening: %v", err)
+	}
+	if offset != 6 {
+		t.Errorf("expected offset 6, got %d", offset)
+	}
+}
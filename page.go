@@ -0,0 +1,149 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Cursor is the decoded form of the opaque token handed out by ReadPage.
+// StartAfter is the S3 list position (the key of the last object that was
+// fully consumed) and Next is the offset cursor within the listing. The zero
+// Cursor is the beginning of the log.
+//
+// A cursor only holds a list position and an offset, so its token stays
+// valid across process restarts and can be handed to clients of a stateless
+// API. With WithCursorSigningKey, tokens carry an HMAC-SHA256 of the cursor
+// so that clients cannot forge or alter them.
+type Cursor struct {
+	StartAfter string `json:"s,omitempty"`
+	Next       uint64 `json:"n"`
+}
+
+// Encode returns the cursor's token, signed with key unless key is empty.
+func (c Cursor) Encode(key []byte) string {
+	b, _ := json.Marshal(c)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if len(key) == 0 {
+		return token
+	}
+	return token + "." + base64.RawURLEncoding.EncodeToString(cursorMAC(key, token))
+}
+
+// Decode sets the cursor from a token returned by Encode with the same key.
+// It fails with ErrInvalidCursor if the token is malformed or, when key is
+// set, unsigned or not signed with key. The empty token is the zero Cursor.
+func (c *Cursor) Decode(token string, key []byte) error {
+	*c = Cursor{}
+	if token == "" {
+		return nil
+	}
+	payload, sig, signed := strings.Cut(token, ".")
+	if len(key) > 0 {
+		mac, err := base64.RawURLEncoding.DecodeString(sig)
+		if !signed || err != nil || !hmac.Equal(mac, cursorMAC(key, payload)) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+		}
+	} else if signed {
+		return fmt.Errorf("%w: unexpected signature", ErrInvalidCursor)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return nil
+}
+
+func cursorMAC(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// WithCursorSigningKey makes ReadPage sign the tokens it hands out with key
+// and reject tokens it did not sign. Every process paginating the same log
+// must use the same key.
+func WithCursorSigningKey(key []byte) Option {
+	if len(key) == 0 {
+		panic("s3log: cursor signing key must not be empty")
+	}
+	return func(w *S3WAL) {
+		w.cursorKey = bytes.Clone(key)
+	}
+}
+
+// ReadPage returns up to limit records in offset order together with an opaque
+// token from which the next call resumes. An empty token starts from the
+// beginning of the log and an empty nextToken signals the end. Tokens only
+// encode a list position and an offset, so the same token always yields the
+// same page for an unchanged log. A token is an encoded Cursor, signed with
+// WithCursorSigningKey if set.
+func (w *S3WAL) ReadPage(ctx context.Context, token string, limit int) ([]Record, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("page limit must be positive, got %d", limit)
+	}
+	var pos Cursor
+	if err := pos.Decode(token, w.cursorKey); err != nil {
+		return nil, "", err
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(w.bucketName),
+		Prefix:  aws.String(w.keyPrefix()),
+		MaxKeys: aws.Int32(int32(min(limit, 1000))),
+	}
+	if pos.StartAfter != "" {
+		input.StartAfter = aws.String(pos.StartAfter)
+	}
+	paginator := s3.NewListObjectsV2Paginator(w.client, input)
+
+	var records []Record
+	after := pos.StartAfter
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list objects from s3: %w", err)
+		}
+		for i, obj := range output.Contents {
+			offset, err := w.getOffsetFromKey(*obj.Key)
+			if err != nil {
+				// not a record, e.g. a marker or checkpoint
+				after = *obj.Key
+				continue
+			}
+			objRecords, err := w.readObject(ctx, offset)
+			if err != nil {
+				return nil, "", err
+			}
+			for _, record := range objRecords {
+				if record.Offset < pos.Next || w.skipRecord(record) {
+					continue
+				}
+				if len(records) == limit {
+					return records, Cursor{StartAfter: after, Next: record.Offset}.Encode(w.cursorKey), nil
+				}
+				records = append(records, record)
+			}
+			after = *obj.Key
+			if len(records) == limit {
+				if i == len(output.Contents)-1 && !paginator.HasMorePages() {
+					return records, "", nil
+				}
+				last := records[len(records)-1].Offset
+				return records, Cursor{StartAfter: after, Next: last + 1}.Encode(w.cursorKey), nil
+			}
+		}
+	}
+	return records, "", nil
+}
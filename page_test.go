@@ -0,0 +1,128 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestReadPage(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("tail %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	for _, limit := range []int{1, 2, 4, 9, 50} {
+		var offsets []uint64
+		token := ""
+		for {
+			records, next, err := wal.ReadPage(ctx, token, limit)
+			if err != nil {
+				t.Fatalf("limit %d: failed to read page: %v", limit, err)
+			}
+			if len(records) > limit {
+				t.Fatalf("limit %d: page has %d records", limit, len(records))
+			}
+			again, againNext, err := wal.ReadPage(ctx, token, limit)
+			if err != nil {
+				t.Fatalf("limit %d: failed to re-read page: %v", limit, err)
+			}
+			if len(again) != len(records) || againNext != next {
+				t.Fatalf("limit %d: token is not stable across calls", limit)
+			}
+			for _, record := range records {
+				offsets = append(offsets, record.Offset)
+			}
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		if len(offsets) != 9 {
+			t.Fatalf("limit %d: expected 9 records, got %v", limit, offsets)
+		}
+		for i, offset := range offsets {
+			if offset != uint64(i+1) {
+				t.Fatalf("limit %d: expected offset %d at position %d, got %v", limit, i+1, i, offsets)
+			}
+		}
+	}
+}
+
+func TestReadPageEmptyAndInvalid(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	records, next, err := wal.ReadPage(ctx, "", 10)
+	if err != nil {
+		t.Fatalf("failed to read page of empty WAL: %v", err)
+	}
+	if len(records) != 0 || next != "" {
+		t.Errorf("expected empty page and token, got %d records and %q", len(records), next)
+	}
+	if _, _, err := wal.ReadPage(ctx, "!!not-a-token", 10); err == nil {
+		t.Error("expected error for invalid token, got nil")
+	}
+	if _, _, err := wal.ReadPage(ctx, "", 0); err == nil {
+		t.Error("expected error for non-positive limit, got nil")
+	}
+}
+
+func TestReadPageSignedCursor(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := base.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	key := []byte("secret")
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithCursorSigningKey(key))
+	records, next, err := wal.ReadPage(ctx, "", 2)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("failed to read first page: %d records (%v)", len(records), err)
+	}
+
+	var cursor Cursor
+	if err := cursor.Decode(next, key); err != nil {
+		t.Fatalf("failed to decode cursor: %v", err)
+	}
+	if cursor.Next != 3 {
+		t.Errorf("expected cursor at offset 3, got %+v", cursor)
+	}
+
+	// a restarted server with the same key accepts the token
+	restarted := NewS3WAL(base.client, base.bucketName, base.prefix, WithCursorSigningKey(key))
+	records, _, err = restarted.ReadPage(ctx, next, 2)
+	if err != nil || len(records) != 2 || records[0].Offset != 3 {
+		t.Errorf("expected page starting at 3, got %d records (%v)", len(records), err)
+	}
+
+	forged := Cursor{Next: 5}.Encode([]byte("other"))
+	unsigned := Cursor{Next: 5}.Encode(nil)
+	for _, token := range []string{forged, unsigned, next + "x"} {
+		if _, _, err := wal.ReadPage(ctx, token, 2); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor for %q, got %v", token, err)
+		}
+	}
+	if _, _, err := base.ReadPage(ctx, next, 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor for a signed token without a key, got %v", err)
+	}
+}
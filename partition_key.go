@@ -0,0 +1,75 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// metaPartitionKey is the user metadata key holding a record's partition key.
+const metaPartitionKey = "s3log-partition-key"
+
+// AppendWithPartitionKey appends data with a partition key stored in the
+// record object's user metadata, returned as Record.PartitionKey on read and
+// by PartitionKey without downloading the record. The key must be non-empty
+// printable ASCII, since S3 does not return other metadata values unchanged,
+// and fit in S3's user metadata limit, or ErrMetadataTooLarge is returned.
+func (w *S3WAL) AppendWithPartitionKey(ctx context.Context, key string, data []byte) (uint64, error) {
+	if err := validatePartitionKey(key); err != nil {
+		return 0, err
+	}
+	offset, _, err := w.append(ctx, frameHeader{}, map[string]string{metaPartitionKey: key}, data)
+	return offset, err
+}
+
+func validatePartitionKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("partition key must not be empty")
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return fmt.Errorf("partition key %q is not printable ASCII", key)
+		}
+	}
+	if size := len(metaPartitionKey) + len(key); size > maxUserMetadataSize {
+		return fmt.Errorf("%w: partition key of %d bytes exceeds the limit of %d", ErrMetadataTooLarge, len(key), maxUserMetadataSize-len(metaPartitionKey))
+	}
+	return nil
+}
+
+// PartitionKey returns the partition key of the record at offset with a
+// single HeadObject request, or "" if the record has none. Records of a
+// micro-batch have none, and only the batch's first offset can be looked up.
+func (w *S3WAL) PartitionKey(ctx context.Context, offset uint64) (string, error) {
+	output, err := w.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.getObjectKey(offset)),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return "", fmt.Errorf("no record at offset %d", offset)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to head object in s3: %w", err)
+	}
+	return output.Metadata[metaPartitionKey], nil
+}
+
+// withPartitionKey returns metadata with the partition key of record added,
+// if it has one.
+func withPartitionKey(metadata map[string]string, record Record) map[string]string {
+	if record.PartitionKey == "" {
+		return metadata
+	}
+	metadata = maps.Clone(metadata)
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata[metaPartitionKey] = record.PartitionKey
+	return metadata
+}
@@ -0,0 +1,48 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAppendWithPartitionKey(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	offset, err := wal.AppendWithPartitionKey(ctx, "customer-42", []byte("order"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte("unkeyed")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	record, err := wal.Read(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if record.PartitionKey != "customer-42" || string(record.Data) != "order" || record.Metadata != nil {
+		t.Errorf("unexpected record %+v", record)
+	}
+	if key, err := wal.PartitionKey(ctx, offset); err != nil || key != "customer-42" {
+		t.Errorf("expected partition key customer-42, got %q (%v)", key, err)
+	}
+	if key, err := wal.PartitionKey(ctx, offset+1); err != nil || key != "" {
+		t.Errorf("expected no partition key, got %q (%v)", key, err)
+	}
+	if _, err := wal.PartitionKey(ctx, offset+2); err == nil {
+		t.Error("expected error for a missing record")
+	}
+
+	for _, key := range []string{"", "café", "line\nbreak"} {
+		if _, err := wal.AppendWithPartitionKey(ctx, key, []byte("x")); err == nil {
+			t.Errorf("expected error for partition key %q", key)
+		}
+	}
+	if _, err := wal.AppendWithPartitionKey(ctx, strings.Repeat("k", maxUserMetadataSize), []byte("x")); !errors.Is(err, ErrMetadataTooLarge) {
+		t.Errorf("expected ErrMetadataTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WithoutPreconditions stops sending If-None-Match on record uploads, for
+// S3-compatible stores that reject conditional puts. Instead a HeadObject
+// request checks that the key is free before each upload. This is racy: two
+// writers that both pass the check before either upload lands will both
+// succeed, and the later one silently overwrites the earlier record. Only use
+// it when a single writer appends to the prefix.
+func WithoutPreconditions() Option {
+	return func(w *S3WAL) {
+		w.noPreconditions = true
+	}
+}
+
+// errObjectExists is returned by ifNoneMatch when the key is already in use.
+var errObjectExists = errors.New("object already exists")
+
+// ifNoneMatch returns the If-None-Match value for an upload to key. Without
+// preconditions it returns nil once HeadObject has found the key unused.
+func (w *S3WAL) ifNoneMatch(ctx context.Context, key string) (*string, error) {
+	if !w.noPreconditions {
+		return aws.String("*"), nil
+	}
+	_, err := w.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to head object from S3: %w", err)
+	}
+	return nil, fmt.Errorf("%w: %s", errObjectExists, key)
+}
@@ -0,0 +1,54 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// unconditionalClient mimics a store without conditional-put support.
+type unconditionalClient struct {
+	S3Client
+}
+
+func (c *unconditionalClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if params.IfNoneMatch != nil {
+		return nil, errors.New("NotImplemented: If-None-Match is not supported")
+	}
+	return c.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func TestWithoutPreconditions(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client := &unconditionalClient{S3Client: setupMinioClient()}
+	if _, err := NewS3WAL(client, base.bucketName, base.prefix).Append(ctx, []byte("x")); err == nil {
+		t.Fatal("expected the default conditional put to be rejected")
+	}
+
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithoutPreconditions())
+	offset, err := wal.Append(ctx, []byte("first"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	record, err := wal.Read(ctx, offset)
+	if err != nil || string(record.Data) != "first" {
+		t.Fatalf("failed to read back record: %q, %v", record.Data, err)
+	}
+
+	wal.length = 0
+	if _, err := wal.Append(ctx, []byte("overwrite")); err == nil {
+		t.Error("expected append over an existing record to fail")
+	}
+	record, err = wal.Read(ctx, offset)
+	if err != nil || string(record.Data) != "first" {
+		t.Errorf("existing record was overwritten: %q, %v", record.Data, err)
+	}
+}
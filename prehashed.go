@@ -0,0 +1,28 @@
+package s3log
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+)
+
+// AppendPrehashed appends data using checksum, the caller's SHA-256 of data,
+// instead of hashing the payload again. The record is framed with its offset
+// as usual, but its trailer holds checksum, which covers the payload only.
+// The offset is still checked against the key on read, and with WithHashChain
+// the link to the previous record is checked by ValidateChain, but nothing
+// protects the rest of the header: a corrupted timestamp from WithTimestamps
+// is read back as is. Use Append where the header must be covered.
+//
+// The caller is responsible for checksum being correct: a wrong checksum is
+// stored as given and every later read of the record fails with
+// ErrChecksumMismatch. Building with the s3log_debug tag verifies checksum
+// before appending. Records large enough to be chunked are checksummed
+// normally.
+func (w *S3WAL) AppendPrehashed(ctx context.Context, data []byte, checksum [32]byte) (uint64, error) {
+	if verifyPrehashed && sha256.Sum256(data) != checksum {
+		return 0, fmt.Errorf("%w: precomputed checksum does not match data", ErrChecksumMismatch)
+	}
+	offset, _, err := w.append(ctx, frameHeader{checksum: &checksum}, nil, data)
+	return offset, err
+}
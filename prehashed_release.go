@@ -0,0 +1,6 @@
+//go:build !s3log_debug
+
+package s3log
+
+// verifyPrehashed makes AppendPrehashed check the caller's checksum.
+const verifyPrehashed = false
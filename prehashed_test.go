@@ -0,0 +1,51 @@
+package s3log
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestAppendPrehashed(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	data := []byte("hashed upstream")
+	offset, err := wal.AppendPrehashed(ctx, data, sha256.Sum256(data))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	record, err := wal.Read(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if string(record.Data) != string(data) {
+		t.Errorf("expected %q, got %q", data, record.Data)
+	}
+	if _, err := wal.ReadTail(ctx, offset, 6); err != nil {
+		t.Errorf("failed to read tail: %v", err)
+	}
+
+	// a corrupted payload no longer matches the stored checksum
+	corruptRecord(t, wal, offset)
+	if _, err := wal.Read(ctx, offset); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for corrupted record, got %v", err)
+	}
+
+	wrong := sha256.Sum256([]byte("something else"))
+	offset, err = wal.AppendPrehashed(ctx, data, wrong)
+	if verifyPrehashed {
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("expected ErrChecksumMismatch in debug builds, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.Read(ctx, offset); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch for a wrong checksum, got %v", err)
+	}
+}
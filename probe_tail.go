@@ -0,0 +1,112 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// WithProbeTailDiscovery makes LastRecord, TailOffset and Reconcile find the
+// tail with HeadObject requests instead of listing the log, for roles that
+// may read and write keys but are not allowed s3:ListBucket. It probes
+// offsets 1, 2, 4, 8, ... until one is missing, then binary searches the gap
+// after the last one found, which takes about
+// 2*log2(n) requests for n records: more round trips than the single list
+// request that covers a log of up to 1000 objects, but fewer requests than
+// listing a large one.
+//
+// Probing assumes one object per offset with no gaps, so it must not be used
+// with micro-batches except as the last object, with compaction, or after
+// records were deleted. Without s3:ListBucket, S3 answers HeadObject on a
+// missing key with 403 rather than 404, so an access denied response counts
+// as a missing record.
+func WithProbeTailDiscovery() Option {
+	return func(w *S3WAL) {
+		w.probeTail = true
+	}
+}
+
+// WithBinarySearchTail makes LastRecord, TailOffset and Reconcile find the
+// tail of a dense log, one with a record object at every offset from 1, by
+// probing it with HeadObject like WithProbeTailDiscovery, in about 2*log2(n)
+// requests instead of the n/1000 list requests of a full listing. Each probe
+// that finds an offset missing also checks the next offset, and if that one
+// exists, the log has a gap and the tail is found by listing instead, as it
+// is when offset 1 is missing. Gaps that probing does not hit, and
+// micro-batches other than the last object, can still make it stop early.
+func WithBinarySearchTail() Option {
+	return func(w *S3WAL) {
+		w.binarySearchTail = true
+	}
+}
+
+// errLogGap is returned by probeLastObject when it finds a missing offset
+// followed by an existing one.
+var errLogGap = errors.New("log has a gap")
+
+// probeExists reports whether the record object of offset exists.
+func (w *S3WAL) probeExists(ctx context.Context, offset uint64) (bool, error) {
+	exists, err := w.objectExists(ctx, w.getObjectKey(offset))
+	if w.probeTail && errors.Is(err, ErrAccessDenied) {
+		return false, nil
+	}
+	return exists, err
+}
+
+// probeLastObject returns the first offset of the last object of the log,
+// found by probing keys rather than listing. With checkGaps, every missing
+// offset is followed by a probe of the next one, failing with errLogGap if it
+// exists.
+func (w *S3WAL) probeLastObject(ctx context.Context, checkGaps bool) (uint64, error) {
+	probe := func(offset uint64) (bool, error) {
+		exists, err := w.probeExists(ctx, offset)
+		if err != nil || exists || !checkGaps {
+			return exists, err
+		}
+		next, err := w.probeExists(ctx, offset+1)
+		if err != nil {
+			return false, err
+		}
+		if next {
+			return false, fmt.Errorf("%w: offset %d is missing but %d exists", errLogGap, offset, offset+1)
+		}
+		return false, nil
+	}
+
+	lo := uint64(1)
+	exists, err := probe(lo)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrWALEmpty
+	}
+
+	// lo exists; gallop until lo+step is missing
+	step := uint64(1)
+	for {
+		exists, err := probe(lo + step)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			break
+		}
+		lo += step
+		step *= 2
+	}
+	hi := lo + step
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		exists, err := probe(mid)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
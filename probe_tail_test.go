@@ -0,0 +1,120 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// noListClient behaves like S3 for a role without s3:ListBucket: listing is
+// denied, and so is HeadObject on a missing key.
+type noListClient struct {
+	S3Client
+}
+
+var errNoList = &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+
+func (c noListClient) ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return nil, errNoList
+}
+
+func (c noListClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	output, err := c.S3Client.HeadObject(ctx, params, optFns...)
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return nil, errNoList
+	}
+	return output, err
+}
+
+func TestProbeTailDiscovery(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	newWAL := func() *S3WAL {
+		return NewS3WAL(noListClient{base.client}, base.bucketName, base.prefix, WithProbeTailDiscovery())
+	}
+	if _, err := newWAL().TailOffset(ctx); !errors.Is(err, ErrWALEmpty) {
+		t.Errorf("expected ErrWALEmpty, got %v", err)
+	}
+	if _, err := NewS3WAL(noListClient{base.client}, base.bucketName, base.prefix).TailOffset(ctx); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied when listing without probing, got %v", err)
+	}
+
+	for i := 1; i <= 13; i++ {
+		if _, err := base.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	wal := newWAL()
+	if tail, err := wal.TailOffset(ctx); err != nil || tail != 13 {
+		t.Errorf("expected tail 13, got %d (%v)", tail, err)
+	}
+	// 1, 2, 4, 8 and 16 gallop, 12, 14 and 13 search, plus objectEnd
+	if heads := wal.UsageStats().HeadRequests; heads != 9 {
+		t.Errorf("expected 9 HeadObject requests, got %d", heads)
+	}
+
+	if _, err := base.AppendMicroBatch(ctx, [][]byte{[]byte("record 14"), []byte("record 15")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	wal = newWAL()
+	if record, err := wal.LastRecord(ctx); err != nil || record.Offset != 15 || string(record.Data) != "record 15" {
+		t.Errorf("expected record 15, got %d %q (%v)", record.Offset, record.Data, err)
+	}
+	if offset, err := wal.Append(ctx, []byte("record 16")); err != nil || offset != 16 {
+		t.Errorf("expected offset 16, got %d (%v)", offset, err)
+	}
+	if tail, err := newWAL().Reconcile(ctx); err != nil || tail != 16 {
+		t.Errorf("expected reconciled tail 16, got %d (%v)", tail, err)
+	}
+}
+
+func TestBinarySearchTail(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	newWAL := func() *S3WAL {
+		return NewS3WAL(base.client, base.bucketName, base.prefix, WithBinarySearchTail())
+	}
+	if _, err := newWAL().TailOffset(ctx); !errors.Is(err, ErrWALEmpty) {
+		t.Errorf("expected ErrWALEmpty, got %v", err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		if _, err := base.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	wal := newWAL()
+	if tail, err := wal.TailOffset(ctx); err != nil || tail != 10 {
+		t.Errorf("expected tail 10, got %d (%v)", tail, err)
+	}
+	if lists := wal.UsageStats().ListRequests; lists != 0 {
+		t.Errorf("expected no list requests for a dense log, got %d", lists)
+	}
+
+	// 4 is probed while galloping; finding 5 behind it means a gap
+	_, err := base.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(base.bucketName),
+		Key:    aws.String(base.getObjectKey(4)),
+	})
+	if err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	wal = newWAL()
+	if record, err := wal.LastRecord(ctx); err != nil || record.Offset != 10 {
+		t.Errorf("expected record 10, got %d (%v)", record.Offset, err)
+	}
+	if lists := wal.UsageStats().ListRequests; lists == 0 {
+		t.Error("expected a gap to fall back to listing")
+	}
+}
@@ -0,0 +1,37 @@
+// Package proto appends and reads protobuf messages on an s3log.WAL. It lives
+// apart from s3log so that only programs storing protobuf records depend on
+// google.golang.org/protobuf.
+package proto
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	s3log "github.com/xmohamd/s3-log"
+)
+
+// Append marshals msg with protobuf and appends it to w. Marshalling failures
+// wrap s3log.ErrCodec.
+func Append(ctx context.Context, w s3log.WAL, msg proto.Message) (uint64, error) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to marshal protobuf message: %w", s3log.ErrCodec, err)
+	}
+	return w.Append(ctx, data)
+}
+
+// Read reads the record at offset from w and unmarshals its payload into msg.
+// Unmarshalling failures wrap s3log.ErrCodec, so they can be told apart from
+// failures to read the record from S3.
+func Read(ctx context.Context, w s3log.WAL, offset uint64, msg proto.Message) error {
+	record, err := w.Read(ctx, offset)
+	if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(record.Data, msg); err != nil {
+		return fmt.Errorf("%w: failed to unmarshal record %d: %w", s3log.ErrCodec, offset, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ReadRaw returns the stored bytes of the object holding the record at
+// offset, exactly as downloaded: the frame with its header and trailing
+// checksum, or the whole micro-batch for a record inside one. Nothing is
+// decoded or validated, so the result may be corrupt; it is meant for
+// inspecting frames when diagnosing format problems.
+func (w *S3WAL) ReadRaw(ctx context.Context, offset uint64) ([]byte, error) {
+	data, _, err := w.getBytes(ctx, w.getObjectKey(offset))
+	var nsk *types.NoSuchKey
+	if !errors.As(err, &nsk) {
+		return data, err
+	}
+	first, found, lerr := w.findMicroBatch(ctx, offset)
+	if lerr != nil {
+		return nil, lerr
+	}
+	if !found {
+		return nil, err
+	}
+	data, _, err = w.getBytes(ctx, w.getObjectKey(first))
+	return data, err
+}
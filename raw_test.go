@@ -0,0 +1,52 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestReadRaw(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Append(ctx, []byte("plain")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+
+	raw, err := wal.ReadRaw(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to read raw: %v", err)
+	}
+	expected, _ := prepareBody(1, []byte("plain"))
+	if !bytes.Equal(raw, expected) {
+		t.Errorf("expected frame %x, got %x", expected, raw)
+	}
+
+	batch, err := wal.ReadRaw(ctx, 3)
+	if err != nil {
+		t.Fatalf("failed to read raw: %v", err)
+	}
+	expected, _ = prepareBody(2, encodeMicroBatch(2, [][]byte{[]byte("a"), []byte("b")}))
+	if !bytes.Equal(batch, expected) {
+		t.Errorf("expected the micro-batch object, got %x", batch)
+	}
+
+	// corrupt records are returned as stored
+	corruptRecord(t, wal, 1)
+	if raw, err = wal.ReadRaw(ctx, 1); err != nil || bytes.Equal(raw, expected) || len(raw) != 8+5+32 {
+		t.Errorf("expected the corrupted frame, got %x (%v)", raw, err)
+	}
+
+	var nsk *types.NoSuchKey
+	if _, err := wal.ReadRaw(ctx, 4); !errors.As(err, &nsk) {
+		t.Errorf("expected NoSuchKey past the end, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// WithReadBackSample reads back a fraction rate, between 0 and 1, of the
+// records written by Append and its variants, and checks that what S3
+// returns matches what was written. Mismatches are logged and counted as
+// MetricReadBackMismatch; they do not fail the append, whose record is
+// already stored. Micro-batches and streamed appends are not sampled.
+func WithReadBackSample(rate float64) Option {
+	return func(w *S3WAL) {
+		w.readBackRate = rate
+	}
+}
+
+// WithDeterministicSampling makes the read-back sampling of
+// WithReadBackSample a function of the offset alone, so the records that
+// were checked can be recomputed with ReadBackSampled.
+func WithDeterministicSampling() Option {
+	return func(w *S3WAL) {
+		w.deterministicSampling = true
+	}
+}
+
+// ReadBackSampled reports whether the record at offset is read back after
+// being appended. Unless WithDeterministicSampling is set, the answer is
+// random and differs between calls.
+func (w *S3WAL) ReadBackSampled(offset uint64) bool {
+	switch {
+	case w.readBackRate <= 0:
+		return false
+	case w.readBackRate >= 1:
+		return true
+	case !w.deterministicSampling:
+		return rand.Float64() < w.readBackRate
+	}
+	return float64(mixOffset(offset))/math.MaxUint64 < w.readBackRate
+}
+
+// mixOffset hashes offset with the splitmix64 finalizer, which spreads
+// consecutive offsets evenly.
+func mixOffset(offset uint64) uint64 {
+	z := offset + 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// verifyReadBack reads the record at offset and reports whether its payload
+// differs from data.
+func (w *S3WAL) verifyReadBack(ctx context.Context, offset uint64, data []byte) {
+	err := w.readBack(ctx, offset, data)
+	if err != nil && !errors.Is(err, errReadBackMismatch) {
+		w.logger.Warn("s3log: failed to read back record", "key", w.getObjectKey(offset), "error", err)
+		return
+	}
+	if err == nil {
+		w.incCounter(MetricReadBackVerified, 1)
+		return
+	}
+	w.incCounter(MetricReadBackMismatch, 1)
+	w.logger.Error("s3log: record read back does not match what was written", "key", w.getObjectKey(offset), "error", err)
+}
+
+// errReadBackMismatch is returned by readBack when the stored record is
+// corrupt or differs from what was written.
+var errReadBackMismatch = errors.New("record read back does not match what was written")
+
+// readBack reads the record at offset and checks that it holds data.
+func (w *S3WAL) readBack(ctx context.Context, offset uint64, data []byte) error {
+	records, err := w.readObject(ctx, offset)
+	if errors.Is(err, ErrChecksumMismatch) || errors.Is(err, ErrInvalidRecord) {
+		return fmt.Errorf("%w: %w", errReadBackMismatch, err)
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(records[0].Data, data) {
+		return errReadBackMismatch
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type counters struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *counters) IncCounter(name string, delta int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[name] += delta
+}
+
+func (c *counters) SetGauge(name string, value float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[name] = int64(value)
+}
+
+func (c *counters) get(name string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[name]
+}
+
+// corruptingClient flips a bit in the body of every PutObject while corrupt
+// is set.
+type corruptingClient struct {
+	S3Client
+	corrupt bool
+}
+
+func (c *corruptingClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if c.corrupt {
+		body, err := io.ReadAll(params.Body)
+		if err != nil {
+			return nil, err
+		}
+		body[len(body)/2] ^= 1
+		params.Body = bytes.NewReader(body)
+	}
+	return c.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func TestReadBackSample(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	metrics := &counters{}
+	client := &corruptingClient{S3Client: setupMinioClient()}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithReadBackSample(1), WithMetrics(metrics))
+
+	if _, err := wal.Append(ctx, []byte("intact")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	client.corrupt = true
+	if _, err := wal.Append(ctx, []byte("corrupted in flight")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if n := metrics.get(MetricReadBackVerified); n != 1 {
+		t.Errorf("expected 1 verified record, got %d", n)
+	}
+	if n := metrics.get(MetricReadBackMismatch); n != 1 {
+		t.Errorf("expected 1 mismatch, got %d", n)
+	}
+}
+
+func TestReadBackSampled(t *testing.T) {
+	wal := NewS3WAL(nil, "bucket", "log", WithReadBackSample(0.25), WithDeterministicSampling())
+	sampled := 0
+	for offset := uint64(1); offset <= 10000; offset++ {
+		if wal.ReadBackSampled(offset) {
+			sampled++
+		}
+		if wal.ReadBackSampled(offset) != wal.ReadBackSampled(offset) {
+			t.Fatalf("sampling of offset %d is not deterministic", offset)
+		}
+	}
+	if sampled < 2000 || sampled > 3000 {
+		t.Errorf("expected about 2500 sampled offsets, got %d", sampled)
+	}
+	if NewS3WAL(nil, "bucket", "log").ReadBackSampled(1) {
+		t.Error("expected no sampling by default")
+	}
+}
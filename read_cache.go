@@ -0,0 +1,120 @@
+package s3log
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// CacheValidation selects whether hits in the read cache are confirmed with
+// S3 before being served.
+type CacheValidation int
+
+const (
+	// CacheStrict confirms every hit with a GetObject conditional on the
+	// cached ETag, which S3 answers with an empty 304 response if the object
+	// is unchanged. A changed object is downloaded and validated again, and a
+	// deleted one is evicted. A hit still costs a request, but no transfer.
+	CacheStrict CacheValidation = iota
+	// CacheRelaxed serves hits without contacting S3. Records are immutable,
+	// so this is only wrong if an object is overwritten or deleted behind the
+	// cache's back, e.g. by Compact, Truncate or a misbehaving writer.
+	CacheRelaxed
+)
+
+// WithReadCache keeps up to entries recently read objects in memory, keyed by
+// offset along with their ETag, and evicts the least recently used ones. A
+// micro-batch is one entry. Records repaired from a replica or read without a
+// valid checksum are not cached.
+func WithReadCache(entries int, validation CacheValidation) Option {
+	return func(w *S3WAL) {
+		w.readCache = &readCache{
+			capacity:   entries,
+			validation: validation,
+			entries:    make(map[uint64]*list.Element),
+			order:      list.New(),
+		}
+	}
+}
+
+type readCache struct {
+	capacity   int
+	validation CacheValidation
+
+	mu      sync.Mutex
+	entries map[uint64]*list.Element
+	order   *list.List // front is most recently used
+}
+
+type readCacheEntry struct {
+	offset uint64
+	etag   string
+	obj    fetchedObject
+}
+
+// get returns the cached entry for offset. It is safe to call on a nil cache.
+func (c *readCache) get(offset uint64) (readCacheEntry, bool) {
+	if c == nil {
+		return readCacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[offset]
+	if !ok {
+		return readCacheEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return *elem.Value.(*readCacheEntry), true
+}
+
+// put caches a copy of obj for offset. It is safe to call on a nil cache and
+// ignores objects without an ETag, which could never be validated.
+func (c *readCache) put(offset uint64, etag string, obj fetchedObject) {
+	if c == nil || c.capacity <= 0 || etag == "" {
+		return
+	}
+	obj.payload = bytes.Clone(obj.payload)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[offset]; ok {
+		elem.Value = &readCacheEntry{offset: offset, etag: etag, obj: obj}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[offset] = c.order.PushFront(&readCacheEntry{offset: offset, etag: etag, obj: obj})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*readCacheEntry).offset)
+	}
+}
+
+// remove evicts offset. It is safe to call on a nil cache.
+func (c *readCache) remove(offset uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[offset]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, offset)
+	}
+}
+
+// serve returns a copy of the cached object, so callers may modify the
+// records built from it.
+func (e readCacheEntry) serve() fetchedObject {
+	obj := e.obj
+	obj.payload = bytes.Clone(obj.payload)
+	return obj
+}
+
+// isNotModified reports whether err is S3's answer to a conditional GET whose
+// ETag still matches.
+func isNotModified(err error) bool {
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified
+}
@@ -0,0 +1,87 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// notModifiedCounter counts GetObject requests answered with a 304.
+type notModifiedCounter struct {
+	S3Client
+	notModified atomic.Int64
+}
+
+func (c *notModifiedCounter) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	output, err := c.S3Client.GetObject(ctx, params, optFns...)
+	if isNotModified(err) {
+		c.notModified.Add(1)
+	}
+	return output, err
+}
+
+// overwriteRecord replaces the record at offset with a valid record holding
+// data, as a misbehaving writer would.
+func overwriteRecord(t *testing.T, w *S3WAL, offset uint64, data []byte) {
+	t.Helper()
+	body, _ := prepareBody(offset, data)
+	_, err := w.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.getObjectKey(offset)),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		t.Fatalf("failed to overwrite offset %d: %v", offset, err)
+	}
+}
+
+func TestReadCache(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client := &notModifiedCounter{S3Client: base.client}
+	strict := NewS3WAL(client, base.bucketName, base.prefix, WithReadCache(1, CacheStrict))
+	relaxed := NewS3WAL(client, base.bucketName, base.prefix, WithReadCache(1, CacheRelaxed))
+	for _, data := range []string{"one", "two"} {
+		if _, err := strict.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	read := func(w *S3WAL, offset uint64, expected string) {
+		t.Helper()
+		record, err := w.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if string(record.Data) != expected {
+			t.Errorf("offset %d: expected %q, got %q", offset, expected, record.Data)
+		}
+		record.Data[0] = 'X'
+	}
+
+	read(strict, 1, "one")
+	read(relaxed, 1, "one")
+	gets := relaxed.UsageStats().GetRequests
+	read(strict, 1, "one")
+	read(relaxed, 1, "one")
+	if n := client.notModified.Load(); n != 1 {
+		t.Errorf("expected the strict hit to be confirmed by a 304, got %d", n)
+	}
+	if n := relaxed.UsageStats().GetRequests; n != gets {
+		t.Errorf("expected the relaxed hit to skip S3, got %d more requests", n-gets)
+	}
+
+	overwriteRecord(t, base, 1, []byte("uno"))
+	read(strict, 1, "uno")
+	read(relaxed, 1, "one")
+
+	// reading offset 2 evicts offset 1 from the single-entry cache
+	read(relaxed, 2, "two")
+	read(relaxed, 1, "uno")
+}
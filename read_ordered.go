@@ -0,0 +1,98 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// defaultReadAhead is the number of records ReadOrdered fetches ahead of the
+// handler unless WithReadAhead says otherwise.
+const defaultReadAhead = 16
+
+// WithReadAhead sets how many records ReadOrdered fetches concurrently ahead
+// of the record being handled. It is 16 by default.
+func WithReadAhead(n int) Option {
+	return func(w *S3WAL) {
+		w.readAhead = n
+	}
+}
+
+// ReadOrdered reads the records at offsets [start, end] concurrently and calls
+// handler with each of them strictly in ascending offset order. Up to
+// WithReadAhead records are fetched or held in a reorder buffer at a time;
+// once the buffer is full, fetching waits for handler to catch up. Every
+// offset is fetched with Read, so a missing offset fails the read. The first
+// error from a fetch or from handler stops delivery and is returned once the
+// fetches still in flight have been cancelled.
+func (w *S3WAL) ReadOrdered(ctx context.Context, start, end uint64, handler func(Record) error) error {
+	return w.readOrdered(ctx, start, end, handler, nil)
+}
+
+// readOrdered is ReadOrdered that passes fetch errors to onFetchErr, if set,
+// and skips the offset if it returns nil.
+func (w *S3WAL) readOrdered(ctx context.Context, start, end uint64, handler func(Record) error, onFetchErr func(offset uint64, err error) error) error {
+	if start > end {
+		return fmt.Errorf("start offset %d is after end offset %d", start, end)
+	}
+	readAhead := w.readAhead
+	if readAhead <= 0 {
+		readAhead = defaultReadAhead
+	}
+
+	type result struct {
+		offset uint64
+		record Record
+		err    error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var fetches sync.WaitGroup
+	defer fetches.Wait()
+	defer cancel()
+
+	// pending holds the results of the fetches started, in offset order; the
+	// dispatching goroutine holds one more while the channel is full
+	pending := make(chan chan result, readAhead-1)
+	fetches.Add(1)
+	go func() {
+		defer fetches.Done()
+		defer close(pending)
+		for offset := start; ; offset++ {
+			done := make(chan result, 1)
+			fetches.Add(1)
+			go func() {
+				defer fetches.Done()
+				record, err := w.Read(ctx, offset)
+				done <- result{offset: offset, record: record, err: err}
+			}()
+			select {
+			case pending <- done:
+			case <-ctx.Done():
+				return
+			}
+			if offset == end {
+				return
+			}
+		}
+	}()
+
+	for done := range pending {
+		res := <-done
+		if res.err != nil && onFetchErr != nil {
+			if err := onFetchErr(res.offset, res.err); err != nil {
+				return err
+			}
+			continue
+		}
+		if res.err != nil {
+			return res.err
+		}
+		if w.skipRecord(res.record) {
+			continue
+		}
+		if err := handler(res.record); err != nil {
+			return fmt.Errorf("handler failed on offset %d: %w", res.record.Offset, err)
+		}
+	}
+	return ctx.Err()
+}
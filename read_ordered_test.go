@@ -0,0 +1,91 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// slowEvenClient delays downloads of even offsets so that concurrent fetches
+// complete out of order, and tracks how many run at once.
+type slowEvenClient struct {
+	S3Client
+	inFlight, maxInFlight atomic.Int32
+}
+
+func (c *slowEvenClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		m := c.maxInFlight.Load()
+		if n <= m || c.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	key := aws.ToString(params.Key)
+	if strings.ContainsAny(key[len(key)-1:], "02468") {
+		time.Sleep(20 * time.Millisecond)
+	}
+	return c.S3Client.GetObject(ctx, params, optFns...)
+}
+
+func TestReadOrdered(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 20; i++ {
+		if _, err := base.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	client := &slowEvenClient{S3Client: base.client}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithReadAhead(4))
+
+	var got []uint64
+	err := wal.ReadOrdered(ctx, 3, 18, func(record Record) error {
+		if string(record.Data) != fmt.Sprintf("record %d", record.Offset) {
+			t.Errorf("offset %d: unexpected data %q", record.Offset, record.Data)
+		}
+		got = append(got, record.Offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if len(got) != 16 {
+		t.Fatalf("expected 16 records, got %d", len(got))
+	}
+	for i, offset := range got {
+		if offset != uint64(3+i) {
+			t.Fatalf("expected offsets in order, got %v", got)
+		}
+	}
+	if n := client.maxInFlight.Load(); n < 2 || n > 5 {
+		t.Errorf("expected between 2 and 5 concurrent fetches, got %d", n)
+	}
+
+	errStop := errors.New("stop")
+	delivered := 0
+	err = wal.ReadOrdered(ctx, 1, 20, func(record Record) error {
+		delivered++
+		if record.Offset == 5 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || delivered != 5 {
+		t.Errorf("expected delivery to stop at offset 5, got %d records (%v)", delivered, err)
+	}
+
+	if err := wal.ReadOrdered(ctx, 19, 25, func(Record) error { return nil }); err == nil {
+		t.Error("expected error reading past the end of the log, got nil")
+	}
+}
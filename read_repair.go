@@ -0,0 +1,73 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithReplica configures a read-only copy of the WAL, typically kept in sync
+// by S3 replication, under prefix in bucketName. The replica is never written
+// to by this WAL.
+func WithReplica(client S3Client, bucketName, prefix string) Option {
+	return func(w *S3WAL) {
+		w.replica = &S3WAL{client: client, bucketName: bucketName, prefix: prefix}
+	}
+}
+
+// WithReadRepair makes reads that hit a checksum mismatch fetch the record
+// from the replica configured with WithReplica. If the replica's copy is
+// intact it is returned and written back over the corrupted primary object;
+// if it is corrupted too, the original ErrChecksumMismatch is returned and
+// nothing is rewritten. Repairs are counted as MetricReadRepairs.
+func WithReadRepair() Option {
+	return func(w *S3WAL) {
+		w.readRepair = true
+	}
+}
+
+// WithRetryOnChecksumMismatch makes reads that hit a checksum mismatch
+// download the object again up to n times before giving up, for stores that
+// occasionally return a corrupted body. Mismatches that go away on retry are
+// counted as MetricChecksumRetryFixed. Only once all retries fail is the
+// record repaired from the replica, if WithReadRepair is set. Legacy records
+// accepted by WithChecksumOptional are too short for a checksum and are not
+// retried.
+func WithRetryOnChecksumMismatch(n int) Option {
+	return func(w *S3WAL) {
+		w.checksumRetries = n
+	}
+}
+
+// repairFromReplica fetches the object for offset from the replica and, if it
+// decodes cleanly, restores the primary object from it.
+func (w *S3WAL) repairFromReplica(ctx context.Context, offset uint64, cause error) (fetchedObject, error) {
+	data, metadata, err := w.replica.getBytes(ctx, w.replica.getObjectKey(offset))
+	if err != nil {
+		return fetchedObject{}, errors.Join(cause, fmt.Errorf("failed to read replica: %w", err))
+	}
+	h, payload, err := decodeFrame(data, offset)
+	if err != nil {
+		return fetchedObject{}, errors.Join(cause, fmt.Errorf("replica is also invalid: %w", err))
+	}
+
+	key := w.getObjectKey(offset)
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(w.bucketName),
+		Key:      aws.String(key),
+		Body:     bytes.NewReader(data),
+		Metadata: metadata,
+	}
+	w.applyPutOptions(input)
+	if _, err := w.client.PutObject(ctx, input); err != nil {
+		w.logger.Error("s3log: failed to rewrite corrupted record", "key", key, "error", err)
+	} else {
+		w.logger.Warn("s3log: repaired corrupted record from replica", "key", key)
+		w.incCounter(MetricReadRepairs, 1)
+	}
+	return fetchedObject{header: h, payload: payload, metadata: metadata}, nil
+}
@@ -0,0 +1,131 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func corruptRecord(t *testing.T, w *S3WAL, offset uint64) {
+	t.Helper()
+	ctx := context.Background()
+	data, metadata, err := w.getBytes(ctx, w.getObjectKey(offset))
+	if err != nil {
+		t.Fatalf("failed to read offset %d: %v", offset, err)
+	}
+	data[len(data)/2] ^= 0xFF
+	_, err = w.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:   aws.String(w.bucketName),
+		Key:      aws.String(w.getObjectKey(offset)),
+		Body:     bytes.NewReader(data),
+		Metadata: metadata,
+	})
+	if err != nil {
+		t.Fatalf("failed to corrupt offset %d: %v", offset, err)
+	}
+}
+
+func TestReadRepair(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	replica, cleanupReplica := getWAL(t)
+	defer cleanupReplica()
+	ctx := context.Background()
+
+	for _, w := range []*S3WAL{wal, replica} {
+		for _, data := range []string{"healable", "lost"} {
+			if _, err := w.Append(ctx, []byte(data)); err != nil {
+				t.Fatalf("failed to append: %v", err)
+			}
+		}
+	}
+	corruptRecord(t, wal, 1)
+	corruptRecord(t, wal, 2)
+	corruptRecord(t, replica, 2)
+
+	WithReplica(replica.client, replica.bucketName, replica.prefix)(wal)
+	if _, err := wal.Read(ctx, 1); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch without read repair, got %v", err)
+	}
+	WithReadRepair()(wal)
+
+	record, err := wal.Read(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to read with repair: %v", err)
+	}
+	if string(record.Data) != "healable" {
+		t.Errorf("expected %q, got %q", "healable", record.Data)
+	}
+	plain := NewS3WAL(wal.client, wal.bucketName, wal.prefix)
+	if _, err := plain.Read(ctx, 1); err != nil {
+		t.Errorf("expected primary to be rewritten, got %v", err)
+	}
+
+	if _, err := wal.Read(ctx, 2); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch when the replica is bad too, got %v", err)
+	}
+	if _, err := plain.Read(ctx, 2); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected corrupted primary to be left alone, got %v", err)
+	}
+}
+
+// flakyClient flips a bit in the body of the next glitches GetObject
+// responses, like a store that momentarily returns corrupted data.
+type flakyClient struct {
+	S3Client
+	glitches int
+}
+
+func (c *flakyClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	output, err := c.S3Client.GetObject(ctx, params, optFns...)
+	if err != nil || c.glitches == 0 {
+		return output, err
+	}
+	c.glitches--
+	body, err := io.ReadAll(output.Body)
+	output.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body[len(body)/2] ^= 1
+	output.Body = io.NopCloser(bytes.NewReader(body))
+	return output, nil
+}
+
+func TestRetryOnChecksumMismatch(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	metrics := &counters{}
+	client := &flakyClient{S3Client: setupMinioClient()}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithRetryOnChecksumMismatch(2), WithMetrics(metrics))
+	if _, err := wal.Append(ctx, []byte("glitchy store")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	client.glitches = 2
+	record, err := wal.Read(ctx, 1)
+	if err != nil {
+		t.Fatalf("expected the retries to fix the read, got %v", err)
+	}
+	if string(record.Data) != "glitchy store" {
+		t.Errorf("unexpected data %q", record.Data)
+	}
+	if n := metrics.get(MetricChecksumRetryFixed); n != 1 {
+		t.Errorf("expected 1 fixed read, got %d", n)
+	}
+
+	client.glitches = 3
+	if _, err := wal.Read(ctx, 1); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch once retries are exhausted, got %v", err)
+	}
+	if n := metrics.get(MetricChecksumRetryFixed); n != 1 {
+		t.Errorf("expected still 1 fixed read, got %d", n)
+	}
+}
@@ -0,0 +1,120 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxFrameHeaderLen is the longest possible frame header: magic, flags,
+// checksum format, a leading checksum, offset, timestamp, a 255 byte schema
+// ID and the previous record's checksum.
+const maxFrameHeaderLen = 3 + maxChecksumSize + 8 + 8 + 1 + 255 + 32
+
+// ReadTail returns the last n bytes of the record at offset, or the whole
+// record if it is shorter. Only the object's header and the requested bytes
+// are downloaded, so the record's checksum cannot be verified and the result
+// may be silently corrupt. Records small enough to be fetched whole by the
+// header request, micro-batched records and chunked records are read with
+// Read instead, and their checksums are verified.
+func (w *S3WAL) ReadTail(ctx context.Context, offset uint64, n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("tail length must not be negative")
+	}
+	key := w.getObjectKey(offset)
+	head, size, metadata, err := w.getRange(ctx, key, fmt.Sprintf("bytes=0-%d", maxFrameHeaderLen-1))
+	var nsk *types.NoSuchKey
+	if err != nil && !errors.As(err, &nsk) {
+		return nil, err
+	}
+	if err != nil || int64(len(head)) == size || metadata[metaMicroBatch] != "" {
+		return w.readTailWhole(ctx, offset, n)
+	}
+
+	headerLen, trailerLen := 8, int64(checksumSize)
+	if metadata[metaNoOffsetHeader] != "" {
+		headerLen = 0
+	} else if head[0] == frameMagic {
+		h, pos, err := parseExtendedHeader(head)
+		if err != nil {
+			return nil, err
+		}
+		if h.chunked {
+			return w.readTailWhole(ctx, offset, n)
+		}
+		if h.offset != offset {
+			return nil, fmt.Errorf("%w: offset mismatch: expected %d, got %d", ErrInvalidRecord, offset, h.offset)
+		}
+		headerLen = pos
+		trailerLen = int64(h.checksumLen())
+		if h.leadingChecksum {
+			trailerLen = 0
+		}
+	} else if ok, _ := validateOffset(head, offset); !ok {
+		return nil, fmt.Errorf("%w: offset mismatch: expected %d", ErrInvalidRecord, offset)
+	}
+
+	end := size - trailerLen
+	if end < int64(headerLen) {
+		return nil, fmt.Errorf("%w: data too short", ErrInvalidRecord)
+	}
+	start := max(end-int64(n), int64(headerLen))
+	if start == end {
+		return []byte{}, nil
+	}
+	tail, _, _, err := w.getRange(ctx, key, fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(tail)) != end-start {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrTruncatedRead, end-start, len(tail))
+	}
+	return tail, nil
+}
+
+func (w *S3WAL) readTailWhole(ctx context.Context, offset uint64, n int) ([]byte, error) {
+	record, err := w.Read(ctx, offset)
+	if err != nil {
+		return nil, err
+	}
+	return record.Data[max(len(record.Data)-n, 0):], nil
+}
+
+// getRange downloads byteRange of the object stored under key and returns it
+// along with the size of the whole object and its user metadata.
+func (w *S3WAL) getRange(ctx context.Context, key, byteRange string) ([]byte, int64, map[string]string, error) {
+	result, err := w.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(key),
+		Range:  aws.String(byteRange),
+	})
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to get object from s3: %w", err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, 0, nil, fmt.Errorf("%w: %v", ErrTruncatedRead, err)
+	}
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to read object body: %w", err)
+	}
+	if err := checkContentLength(result.ContentLength, len(data)); err != nil {
+		return nil, 0, nil, err
+	}
+	// Content-Range is "bytes start-end/size"
+	contentRange := aws.ToString(result.ContentRange)
+	size, err := strconv.ParseInt(contentRange[strings.LastIndexByte(contentRange, '/')+1:], 10, 64)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to parse content range %q: %w", contentRange, err)
+	}
+	return data, size, result.Metadata, nil
+}
@@ -0,0 +1,58 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestReadTail(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	large := bytes.Repeat([]byte("0123456789"), 100)
+	small := []byte("short record")
+	for _, data := range [][]byte{large, small} {
+		if _, err := wal.Append(ctx, data); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendWithSchema(ctx, "v1", large); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("batched")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+
+	tests := []struct {
+		offset uint64
+		n      int
+		want   []byte
+	}{
+		{1, 15, large[len(large)-15:]},
+		{1, 0, []byte{}},
+		{1, 5000, large},
+		{2, 5, []byte("ecord")},
+		{2, 100, small},
+		{3, 990, large[10:]},
+		{3, 2000, large},
+		{5, 3, []byte("hed")},
+	}
+	for _, tt := range tests {
+		got, err := wal.ReadTail(ctx, tt.offset, tt.n)
+		if err != nil {
+			t.Fatalf("ReadTail(%d, %d): %v", tt.offset, tt.n, err)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("ReadTail(%d, %d): expected %q, got %q", tt.offset, tt.n, tt.want, got)
+		}
+	}
+
+	if _, err := wal.ReadTail(ctx, 6, 1); err == nil {
+		t.Error("expected error for missing offset, got nil")
+	}
+	if _, err := wal.ReadTail(ctx, 1, -1); err == nil {
+		t.Error("expected error for negative length, got nil")
+	}
+}
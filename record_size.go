@@ -0,0 +1,59 @@
+package s3log
+
+import (
+	"fmt"
+	"io"
+)
+
+// WithRecordSizeBounds rejects appended records whose payload is shorter than
+// min bytes with ErrRecordTooSmall, or longer than max bytes with
+// ErrRecordTooLarge. Either bound is disabled by passing 0. The bounds apply
+// to single-record appends, to every record of a micro-batch and to
+// AppendStream, which fails and aborts its upload as soon as the stream
+// exceeds max, or at the end of a stream shorter than min.
+// WithRecordSizeBounds panics if a bound is negative or min exceeds max.
+func WithRecordSizeBounds(min, max int) Option {
+	if min < 0 || max < 0 || (max > 0 && min > max) {
+		panic(fmt.Sprintf("s3log: invalid record size bounds [%d, %d]", min, max))
+	}
+	return func(w *S3WAL) {
+		w.minRecordSize = min
+		w.maxRecordSize = max
+	}
+}
+
+// checkSize reports the size of a record about to be appended as
+// MetricRecordSize and checks it against WithRecordSizeBounds.
+func (w *S3WAL) checkSize(size int) error {
+	w.observeValue(MetricRecordSize, float64(size))
+	if size < w.minRecordSize {
+		return fmt.Errorf("%w: %d bytes, minimum is %d", ErrRecordTooSmall, size, w.minRecordSize)
+	}
+	if w.maxRecordSize > 0 && size > w.maxRecordSize {
+		return fmt.Errorf("%w: %d bytes, maximum is %d", ErrRecordTooLarge, size, w.maxRecordSize)
+	}
+	return nil
+}
+
+// sizeCheckedReader applies checkSize to a streamed record as it is read. It
+// fails as soon as more than the maximum has been read, and at EOF with the
+// error of checkSize, if any.
+type sizeCheckedReader struct {
+	w *S3WAL
+	r io.Reader
+	n int
+}
+
+func (s *sizeCheckedReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.n += n
+	if s.w.maxRecordSize > 0 && s.n > s.w.maxRecordSize {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrRecordTooLarge, s.w.maxRecordSize)
+	}
+	if err == io.EOF {
+		if sizeErr := s.w.checkSize(s.n); sizeErr != nil {
+			return n, sizeErr
+		}
+	}
+	return n, err
+}
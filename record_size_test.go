@@ -0,0 +1,93 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type histograms struct {
+	counters
+	samples map[string][]float64
+}
+
+func (h *histograms) Observe(name string, value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.samples == nil {
+		h.samples = make(map[string][]float64)
+	}
+	h.samples[name] = append(h.samples[name], value)
+}
+
+func TestRecordSizeBounds(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	metrics := &histograms{}
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithRecordSizeBounds(2, 5), WithMetrics(metrics))
+	if _, err := wal.Append(ctx, []byte("x")); !errors.Is(err, ErrRecordTooSmall) {
+		t.Errorf("expected ErrRecordTooSmall, got %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte("toolong")); !errors.Is(err, ErrRecordTooLarge) {
+		t.Errorf("expected ErrRecordTooLarge, got %v", err)
+	}
+	if offset, err := wal.Append(ctx, []byte("ok")); err != nil || offset != 1 {
+		t.Errorf("expected offset 1, got %d (%v)", offset, err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("fine"), []byte("")}); !errors.Is(err, ErrRecordTooSmall) {
+		t.Errorf("expected ErrRecordTooSmall for a micro-batch record, got %v", err)
+	}
+	if want := []float64{1, 7, 2, 4, 0}; !slices.Equal(metrics.samples[MetricRecordSize], want) {
+		t.Errorf("expected size samples %v, got %v", want, metrics.samples[MetricRecordSize])
+	}
+
+	upperOnly := NewS3WAL(base.client, base.bucketName, base.prefix+"-upper", WithRecordSizeBounds(0, 3))
+	if _, err := upperOnly.Append(ctx, nil); err != nil {
+		t.Errorf("expected an empty record to pass without a lower bound, got %v", err)
+	}
+	lowerOnly := NewS3WAL(base.client, base.bucketName, base.prefix+"-lower", WithRecordSizeBounds(1, 0))
+	if _, err := lowerOnly.Append(ctx, make([]byte, 1<<10)); err != nil {
+		t.Errorf("expected a large record to pass without an upper bound, got %v", err)
+	}
+}
+
+func TestWithRecordSizeBoundsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for min above max")
+		}
+	}()
+	WithRecordSizeBounds(10, 5)
+}
+
+func TestRecordSizeBoundsStream(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for name, opts := range map[string][]Option{
+		"multipart": {WithRecordSizeBounds(3, 10)},
+		"uploader":  {WithRecordSizeBounds(3, 10), WithUploadManager(1, 5<<20)},
+	} {
+		wal := NewS3WAL(base.client, base.bucketName, base.prefix+"-"+name, opts...)
+		if _, err := wal.AppendStream(ctx, strings.NewReader("far too long a record")); !errors.Is(err, ErrRecordTooLarge) {
+			t.Errorf("%s: expected ErrRecordTooLarge, got %v", name, err)
+		}
+		if _, err := wal.AppendStream(ctx, strings.NewReader("ab")); !errors.Is(err, ErrRecordTooSmall) {
+			t.Errorf("%s: expected ErrRecordTooSmall, got %v", name, err)
+		}
+		if _, err := wal.LastRecord(ctx); !errors.Is(err, ErrWALEmpty) {
+			t.Errorf("%s: expected rejected streams not to be written, got %v", name, err)
+		}
+		if offset, err := wal.AppendStream(ctx, strings.NewReader("fits")); err != nil || offset != 1 {
+			t.Errorf("%s: expected append at 1, got %d (%v)", name, offset, err)
+		}
+		if _, err := wal.DeleteRange(ctx, 1, 1, DeleteOptions{}); err != nil {
+			t.Fatalf("%s: failed to clean up: %v", name, err)
+		}
+	}
+}
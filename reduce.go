@@ -0,0 +1,37 @@
+package s3log
+
+import "context"
+
+// Reduce folds every record from offset start to the end of the log into an
+// accumulator, beginning with init, and returns the final value. Records are
+// fetched one object at a time, so memory use does not grow with the length
+// of the log. Reduce stops at the first error returned by fn and returns it
+// together with the accumulator at that point.
+//
+// Reduce is a function rather than a method because Go methods cannot have
+// type parameters.
+func Reduce[A any](ctx context.Context, w *S3WAL, start uint64, init A, fn func(acc A, rec Record) (A, error)) (A, error) {
+	acc := init
+	next := max(start, 1)
+	for {
+		if err := ctx.Err(); err != nil {
+			return acc, err
+		}
+		records, err := w.recordsFrom(ctx, next)
+		if err != nil {
+			return acc, err
+		}
+		if len(records) == 0 {
+			return acc, nil
+		}
+		for _, record := range records {
+			if record.Offset < next {
+				continue
+			}
+			if acc, err = fn(acc, record); err != nil {
+				return acc, err
+			}
+		}
+		next = records[len(records)-1].Offset + 1
+	}
+}
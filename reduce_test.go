@@ -0,0 +1,60 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestReduce(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := wal.Append(ctx, []byte(strconv.Itoa(i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("4"), []byte("5")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+
+	sum := func(acc int, rec Record) (int, error) {
+		n, err := strconv.Atoi(string(rec.Data))
+		return acc + n, err
+	}
+	total, err := Reduce(ctx, wal, 0, 0, sum)
+	if err != nil {
+		t.Fatalf("Reduce failed: %v", err)
+	}
+	if total != 15 {
+		t.Errorf("expected sum 15, got %d", total)
+	}
+
+	// starting inside a micro-batch skips its earlier records
+	total, err = Reduce(ctx, wal, 5, 100, sum)
+	if err != nil {
+		t.Fatalf("Reduce failed: %v", err)
+	}
+	if total != 105 {
+		t.Errorf("expected 105, got %d", total)
+	}
+
+	total, err = Reduce(ctx, wal, 10, 7, sum)
+	if err != nil || total != 7 {
+		t.Errorf("expected init value past the end, got %d, %v", total, err)
+	}
+
+	stop := errors.New("stop")
+	count, err := Reduce(ctx, wal, 1, 0, func(acc int, rec Record) (int, error) {
+		if rec.Offset == 3 {
+			return acc, stop
+		}
+		return acc + 1, nil
+	})
+	if !errors.Is(err, stop) || count != 2 {
+		t.Errorf("expected to stop after 2 records with the fn error, got %d, %v", count, err)
+	}
+}
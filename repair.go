@@ -0,0 +1,109 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// RepairOptions selects which actions Repair performs. Each action can be
+// enabled independently.
+type RepairOptions struct {
+	// DryRun computes and reports everything without writing to S3 or
+	// changing the in-memory length.
+	DryRun bool
+	// RecomputeLength resets the in-memory length to the true max offset.
+	RecomputeLength bool
+	// ReportGaps lists ranges of missing offsets below the max offset. Offsets
+	// below the first record of the log, or below the first offset kept by
+	// Truncate, were deleted on purpose and are not gaps.
+	ReportGaps bool
+	// FillGaps writes an empty tombstone record at every missing offset so
+	// the log becomes dense again.
+	FillGaps bool
+}
+
+// Gap is an inclusive range of missing offsets.
+type Gap struct {
+	Start uint64
+	End   uint64
+}
+
+// RepairReport describes what Repair found and, unless it was a dry run, did.
+type RepairReport struct {
+	PreviousLength uint64
+	MaxOffset      uint64
+	Gaps           []Gap
+	Filled         []uint64
+}
+
+// Repair scans the log in S3 to recover from a drifted in-memory length or
+// missing offsets. Run it with DryRun first to see what it would change.
+func (w *S3WAL) Repair(ctx context.Context, opts RepairOptions) (RepairReport, error) {
+	if opts.FillGaps && w.idGenerator != nil {
+		return RepairReport{}, fmt.Errorf("cannot fill gaps in a log with generated offsets")
+	}
+	w.mu.Lock()
+	report := RepairReport{PreviousLength: w.length}
+	w.mu.Unlock()
+
+	before, _, _, err := w.getMarker(ctx, w.truncationKey())
+	if err != nil {
+		return report, err
+	}
+	var gaps []Gap
+	var next, last uint64
+	err = w.forEachObject(ctx, func(offset uint64, _ types.Object) error {
+		if last == 0 {
+			next = offset
+		}
+		if offset > next {
+			// the previous object may be a micro-batch covering the range
+			if last != 0 {
+				end, err := w.objectEnd(ctx, last)
+				if err != nil {
+					return err
+				}
+				next = end + 1
+			}
+			if start := max(next, before); offset > start {
+				gaps = append(gaps, Gap{Start: start, End: offset - 1})
+			}
+		}
+		last = offset
+		next = offset + 1
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	if last != 0 {
+		end, err := w.objectEnd(ctx, last)
+		if err != nil {
+			return report, err
+		}
+		report.MaxOffset = end
+	}
+
+	if opts.ReportGaps || opts.FillGaps {
+		report.Gaps = gaps
+	}
+	if opts.FillGaps {
+		for _, gap := range gaps {
+			for offset := gap.Start; offset <= gap.End; offset++ {
+				if !opts.DryRun {
+					if _, err := w.putRecord(ctx, frameHeader{offset: offset}, nil, nil); err != nil {
+						return report, err
+					}
+					w.bloomAdd(offset, offset)
+				}
+				report.Filled = append(report.Filled, offset)
+			}
+		}
+	}
+	if opts.RecomputeLength && !opts.DryRun {
+		w.setLength(report.MaxOffset)
+	}
+	return report, nil
+}
@@ -0,0 +1,106 @@
+package s3log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := wal.Append(ctx, []byte("record")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	// leave offsets 4 and 5 missing
+	wal.length = 5
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	wal.length = 8
+	if _, err := wal.Append(ctx, []byte("tail")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	// simulate a drifted in-memory length
+	wal.length = 2
+
+	all := RepairOptions{RecomputeLength: true, ReportGaps: true, FillGaps: true}
+	dryRun := all
+	dryRun.DryRun = true
+	report, err := wal.Repair(ctx, dryRun)
+	if err != nil {
+		t.Fatalf("failed to repair (dry run): %v", err)
+	}
+	if report.MaxOffset != 9 || report.PreviousLength != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(report.Gaps) != 2 || report.Gaps[0] != (Gap{4, 5}) || report.Gaps[1] != (Gap{8, 8}) {
+		t.Errorf("unexpected gaps: %+v", report.Gaps)
+	}
+	if len(report.Filled) != 3 {
+		t.Errorf("expected 3 offsets to fill, got %v", report.Filled)
+	}
+	if wal.length != 2 {
+		t.Errorf("dry run changed length to %d", wal.length)
+	}
+	if _, err := wal.Read(ctx, 4); err == nil {
+		t.Error("dry run filled a gap")
+	}
+
+	if _, err := wal.Repair(ctx, all); err != nil {
+		t.Fatalf("failed to repair: %v", err)
+	}
+	if wal.length != 9 {
+		t.Errorf("expected length 9 after repair, got %d", wal.length)
+	}
+	for _, offset := range []uint64{4, 5, 8} {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read filled offset %d: %v", offset, err)
+		}
+		if len(record.Data) != 0 {
+			t.Errorf("expected empty tombstone at %d, got %q", offset, record.Data)
+		}
+	}
+
+	report, err = wal.Repair(ctx, RepairOptions{ReportGaps: true})
+	if err != nil {
+		t.Fatalf("failed to repair: %v", err)
+	}
+	if len(report.Gaps) != 0 {
+		t.Errorf("expected no gaps after repair, got %+v", report.Gaps)
+	}
+}
+
+func TestRepairSkipsDeletedHead(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 6; i++ {
+		if _, err := wal.Append(ctx, []byte("record")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.Truncate(ctx, 3, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	// offsets 1 and 2 are gone below the first record, and offset 4 below
+	// the marker of a truncation to 6 whose deletes did not all happen
+	if err := wal.raiseMarker(ctx, wal.truncationKey(), 6); err != nil {
+		t.Fatalf("failed to raise marker: %v", err)
+	}
+	if _, err := wal.DeleteRange(ctx, 4, 4, DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	report, err := wal.Repair(ctx, RepairOptions{ReportGaps: true, FillGaps: true})
+	if err != nil {
+		t.Fatalf("failed to repair: %v", err)
+	}
+	if len(report.Gaps) != 0 || len(report.Filled) != 0 {
+		t.Errorf("expected deleted offsets not to be gaps, got %+v", report)
+	}
+}
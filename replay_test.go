@@ -0,0 +1,44 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 6; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	var got []uint64
+	stats, err := wal.Replay(ctx, 1, 6, func(record Record) error {
+		got = append(got, record.Offset)
+		return nil
+	}, ReplayOptions{RecordsPerSecond: 50})
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if fmt.Sprint(got) != "[1 2 3 4 5 6]" || stats.Records != 6 {
+		t.Errorf("expected offsets 1 to 6, got %v (%d records)", got, stats.Records)
+	}
+	// six records at 50 per second are five 20ms intervals apart
+	if stats.Elapsed < 100*time.Millisecond || stats.Rate() > 60 {
+		t.Errorf("expected replay to be paced, took %v at %.1f records/s", stats.Elapsed, stats.Rate())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	stats, err = wal.Replay(ctx, 1, 6, func(Record) error { return nil }, ReplayOptions{RecordsPerSecond: 1})
+	if !errors.Is(err, context.DeadlineExceeded) || stats.Records != 1 {
+		t.Errorf("expected the deadline to stop the replay after 1 record, got %d (%v)", stats.Records, err)
+	}
+}
@@ -5,37 +5,215 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"log/slog"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type S3WAL struct {
-	client     *s3.Client
-	bucketName string
-	prefix     string
-	length     uint64
+	client            S3Client
+	usage             *usageCounters
+	bucketName        string
+	prefix            string
+	length            uint64
+	allowedSchemas    map[string]struct{}
+	blobHash          BlobHash
+	kmsKeyID          string
+	bucketKey         bool
+	tailMode          TailMode
+	maxObjectSize     int64
+	partSize          int
+	timestamps        bool
+	now               func() time.Time
+	logger            *slog.Logger
+	optChecksum       bool
+	cacheControl      string
+	contentMD5        bool
+	minRecordSize     int
+	maxRecordSize     int
+	probeTail         bool
+	binarySearchTail  bool
+	appendSink        AppendSink
+	strictSink        bool
+	leadingChecksum   bool
+	bloom             *bloomFilter
+	spillDir          string
+	skipSnapshots     bool
+	appendConcurrency int
+	keyFormatter      KeyFormatter
+	keyParser         KeyParser
+	keySuffix         string
+	cursorKey         []byte
+	checksummer       Checksummer
+
+	noPreconditions bool
+	replica         *S3WAL
+	readRepair      bool
+	contentKeying   bool
+	noOffsetHeader  bool
+	compaction      compactionCounters
+	stored          storedObjects
+	metrics         Metrics
+	idGenerator     IDGenerator
+	readCache       *readCache
+	hashChain       bool
+	uploader        *manager.Uploader
+	checksumRetries int
+	listPrefetch    int
+	offsetIndex     OffsetIndex
+	bulkSpread      int
+	maxTotalBytes   int64
+	readAhead       int
+	defaultTimeout  time.Duration
+	// bulkStart is the first offset appended during a bulk-load session,
+	// or 0 outside of one.
+	bulkStart atomic.Uint64
+	// sealed is set once the log is known to be sealed.
+	sealed atomic.Bool
+	// microBatches is set once the log is known to hold micro-batches.
+	microBatches atomic.Bool
+	// noBatchDelete is set when deletes must not use DeleteObjects.
+	noBatchDelete atomic.Bool
+	// truncatedBefore is the first offset kept by Truncate, as far as known.
+	truncatedBefore atomic.Uint64
+	// truncationCheckedAt is when the truncation marker was last read, in
+	// Unix nanoseconds.
+	truncationCheckedAt atomic.Int64
+
+	maxDelimitedRecordSize int
+
+	readBackRate          float64
+	deterministicSampling bool
+
+	// mu serializes appends, which assign offsets from length, and guards
+	// length against concurrent reads.
+	mu        sync.Mutex
+	chainTail chainLink // guarded by mu
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	workers   sync.WaitGroup
 }
 
-func NewS3WAL(client *s3.Client, bucketName, prefix string) *S3WAL {
-	return &S3WAL{
-		client:     client,
-		bucketName: bucketName,
-		prefix:     prefix,
-		length:     0,
+// Option configures optional behaviour of an S3WAL.
+type Option func(*S3WAL)
+
+func NewS3WAL(client S3Client, bucketName, prefix string, opts ...Option) *S3WAL {
+	usage := &usageCounters{}
+	w := &S3WAL{
+		usage:         usage,
+		bucketName:    bucketName,
+		prefix:        prefix,
+		length:        0,
+		maxObjectSize: maxSinglePutSize,
+		partSize:      minMultipartPartSize,
+		now:           time.Now,
+		logger:        slog.Default(),
+		closing:       make(chan struct{}),
+	}
+	client = timeoutClient{S3Client: client, timeout: &w.defaultTimeout}
+	w.client = usageClient{S3Client: accessClient{client}, usage: usage}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.checkKeyScheme()
+	w.checkChecksum()
+	return w
+}
+
+func (w *S3WAL) setLength(length uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.length = length
+}
+
+// WithLogger sets the logger used to report unusual conditions that do not
+// fail an operation. It defaults to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *S3WAL) {
+		w.logger = logger
 	}
 }
 
 func (w *S3WAL) getObjectKey(offset uint64) string {
-	return w.prefix + "/" + fmt.Sprintf("%020d", offset)
+	if start := w.bulkStart.Load(); start != 0 && offset >= start {
+		return w.bulkKey(offset)
+	}
+	return w.sequentialKey(offset)
 }
 
+// keyPrefix returns what every object key of the log starts with: the prefix
+// followed by "/", or nothing for a log stored at the bucket root. Such a log
+// lists the whole bucket and takes any key ending in digits for a record, so
+// it needs the bucket to itself.
+func (w *S3WAL) keyPrefix() string {
+	if w.prefix == "" {
+		return ""
+	}
+	return w.prefix + "/"
+}
+
+func (w *S3WAL) sequentialKey(offset uint64) string {
+	if w.keyFormatter != nil {
+		return w.keyFormatter(w.prefix, offset)
+	}
+	return w.keyPrefix() + fmt.Sprintf("%020d", offset) + w.keySuffix
+}
+
+// getOffsetFromKey parses the offset from the digits that follow the last
+// non-digit character of key. This accepts keys written with any separator
+// and padding width, such as "p/42", "p/00000042" or "p-42", not just the
+// ones produced by getObjectKey. With an empty prefix, keys made of digits
+// alone, such as "00000042", are accepted too, while the keys of side
+// objects, which then share the listing, are not. With WithKeySuffix, keys
+// must end in the suffix, which is removed first. WithKeyParser replaces it.
 func (w *S3WAL) getOffsetFromKey(key string) (uint64, error) {
-	numStr := key[len(w.prefix)+1:]
-	return strconv.ParseUint(numStr, 10, 64)
+	if w.keyParser != nil {
+		return w.keyParser(key)
+	}
+	if w.prefix == "" && isSideKey(key) {
+		return 0, fmt.Errorf("key %q is not a record", key)
+	}
+	if w.keySuffix != "" {
+		trimmed, ok := strings.CutSuffix(key, w.keySuffix)
+		if !ok {
+			return 0, fmt.Errorf("key %q does not end in %q", key, w.keySuffix)
+		}
+		key = trimmed
+	}
+	sep := strings.LastIndexFunc(key, func(r rune) bool { return r < '0' || r > '9' })
+	if (sep < 0 && w.prefix != "") || sep == len(key)-1 {
+		return 0, fmt.Errorf("key %q does not end in an offset", key)
+	}
+	return strconv.ParseUint(key[sep+1:], 10, 64)
+}
+
+// sideKeyPrefixes are the namespaces of the objects kept next to the log
+// rather than under it: chunks, content claims and consumer checkpoints. With
+// an empty prefix they sit at the bucket root, among the records.
+var sideKeyPrefixes = []string{".chunks/", ".blobs/", ".checkpoints/"}
+
+// isSideKey reports whether key names a side object of a log stored at the
+// bucket root.
+func isSideKey(key string) bool {
+	for _, prefix := range sideKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func calculateChecksum(buf *bytes.Buffer) [32]byte {
@@ -50,17 +228,23 @@ func validateChecksum(data []byte) bool {
 }
 
 func prepareBody(offset uint64, data []byte) ([]byte, error) {
-	bufferLen := 8 + len(data) + 32
-	buf := bytes.NewBuffer(make([]byte, 0, bufferLen))
-	if err := binary.Write(buf, binary.BigEndian, offset); err != nil {
-		return nil, err
+	return appendPlainFrame(make([]byte, 0, plainFrameOverhead+len(data)), offset, data), nil
+}
+
+// checkContentLength compares the n bytes of a response body with the
+// response's ContentLength, if known, so that a body cut short or padded by a
+// misbehaving store or proxy is not reported as a checksum mismatch.
+func checkContentLength(contentLength *int64, n int) error {
+	if contentLength == nil {
+		return nil
 	}
-	if _, err := buf.Write(data); err != nil {
-		return nil, err
+	if int64(n) < *contentLength {
+		return fmt.Errorf("%w: got %d of %d bytes", ErrTruncatedRead, n, *contentLength)
+	}
+	if int64(n) > *contentLength {
+		return fmt.Errorf("%w: got %d bytes, expected %d", ErrUnexpectedLength, n, *contentLength)
 	}
-	checksum := calculateChecksum(buf)
-	_, err := buf.Write(checksum[:])
-	return buf.Bytes(), err
+	return nil
 }
 
 func validateOffset(data []byte, offset uint64) (bool, error) {
@@ -75,84 +259,579 @@ func validateOffset(data []byte, offset uint64) (bool, error) {
 }
 
 func (w *S3WAL) Append(ctx context.Context, data []byte) (uint64, error) {
-	nextOffset := w.length + 1
+	offset, _, err := w.append(ctx, frameHeader{}, nil, data)
+	return offset, err
+}
+
+func (w *S3WAL) append(ctx context.Context, h frameHeader, metadata map[string]string, data []byte) (uint64, []byte, error) {
+	start := time.Now()
+	offset, checksum, err := w.appendFrame(ctx, h, metadata, data)
+	w.observe(MetricAppends, MetricAppendErrors, MetricAppendLatency, start, err)
+	if err == nil {
+		w.evictAfterAppend(ctx)
+	}
+	return offset, checksum, err
+}
 
-	buf, err := prepareBody(nextOffset, data)
+func (w *S3WAL) appendFrame(ctx context.Context, h frameHeader, metadata map[string]string, data []byte) (uint64, []byte, error) {
+	if err := w.checkOpen(); err != nil {
+		return 0, nil, err
+	}
+	if err := w.checkSchema(h.schemaID); err != nil {
+		return 0, nil, err
+	}
+	if err := w.checkSize(len(data)); err != nil {
+		return 0, nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkOpen(); err != nil {
+		return 0, nil, err
+	}
+	nextOffset, err := w.nextOffset()
 	if err != nil {
-		return 0, fmt.Errorf("failed to prepare object body: %w", err)
+		return 0, nil, err
+	}
+	h.offset = nextOffset
+	if w.timestamps && h.timestamp.IsZero() {
+		h.timestamp = w.now()
+	}
+	h.leadingChecksum = w.leadingChecksum
+	if h.checksum == nil {
+		h.sum = w.checksummer
 	}
+	if w.hashChain {
+		prev, err := w.chainPrev(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		h.prevChecksum = prev
+	}
+	if w.contentKeying {
+		existing, claimed, err := w.claimContent(ctx, nextOffset, data)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !claimed {
+			checksum, err := w.leafChecksum(ctx, existing)
+			if err != nil {
+				return 0, nil, err
+			}
+			return existing, checksum, nil
+		}
+	}
+
+	record := data
+	if w.needsChunking(h, len(data)) {
+		manifest, err := w.putChunks(ctx, nextOffset, data)
+		if err != nil {
+			return 0, nil, err
+		}
+		h.chunked = true
+		// a precomputed checksum covers the data, not the manifest
+		h.checksum = nil
+		data = manifest
+	}
+	if w.spillPending() {
+		return w.spill(h, metadata, data)
+	}
+	checksum, err := w.putRecord(ctx, h, metadata, data)
+	if err != nil && !errors.Is(err, ErrSinkFailed) {
+		if w.spillDir == "" || !isTransportError(err) {
+			return 0, nil, err
+		}
+		w.logger.Warn("s3log: spilling record to local file", "offset", nextOffset, "error", err)
+		return w.spill(h, metadata, data)
+	}
+	if w.ReadBackSampled(nextOffset) {
+		w.verifyReadBack(ctx, nextOffset, record)
+	}
+	w.chainTail = chainLink{offset: nextOffset, checksum: checksum}
+	w.length = nextOffset
+	w.updateIndex(ctx, nextOffset)
+	w.bloomAdd(nextOffset, nextOffset)
+	return nextOffset, checksum, err
+}
 
+// putRecord frames data and writes it at h.offset along with the given user
+// metadata, failing if an object already exists there, and passes the frame
+// to the append sink. It returns the checksum stored in the frame's trailer,
+// which is also returned along with an ErrSinkFailed error.
+func (w *S3WAL) putRecord(ctx context.Context, h frameHeader, metadata map[string]string, data []byte) ([]byte, error) {
+	frame, checksum, err := w.writeRecord(ctx, h, metadata, data)
+	if err != nil {
+		return nil, err
+	}
+	return checksum, w.ship(ctx, h.offset, frame)
+}
+
+// writeRecord is putRecord without the append sink. It returns the frame, or
+// nil for a pooled frame when there is no sink to pass it to, and the stored
+// checksum.
+func (w *S3WAL) writeRecord(ctx context.Context, h frameHeader, metadata map[string]string, data []byte) ([]byte, []byte, error) {
+	if h.flags() == 0 && !w.noOffsetHeader && len(data) <= smallRecordSize {
+		bufp := smallFramePool.Get().(*[]byte)
+		defer smallFramePool.Put(bufp)
+		*bufp = appendPlainFrame((*bufp)[:0], h.offset, data)
+		if err := w.putFrame(ctx, h.offset, *bufp, metadata); err != nil {
+			return nil, nil, err
+		}
+		checksum := bytes.Clone((*bufp)[len(*bufp)-32:])
+		if w.appendSink != nil {
+			return bytes.Clone(*bufp), checksum, nil
+		}
+		return nil, checksum, nil
+	}
+	buf, metadata, err := w.frameRecord(h, metadata, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare object body: %w", err)
+	}
+	if err := w.putFrame(ctx, h.offset, buf, metadata); err != nil {
+		return nil, nil, err
+	}
+	return buf, h.storedChecksum(buf), nil
+}
+
+// putFrame writes an already framed record at offset.
+func (w *S3WAL) putFrame(ctx context.Context, offset uint64, buf []byte, metadata map[string]string) error {
+	key := w.getObjectKey(offset)
+	ifNoneMatch, err := w.ifNoneMatch(ctx, key)
+	if err != nil {
+		return err
+	}
 	input := &s3.PutObjectInput{
 		Bucket:      aws.String(w.bucketName),
-		Key:         aws.String(w.getObjectKey(nextOffset)),
+		Key:         aws.String(key),
 		Body:        bytes.NewReader(buf),
-		IfNoneMatch: aws.String("*"),
+		IfNoneMatch: ifNoneMatch,
+		Metadata:    metadata,
 	}
+	w.applyPutOptions(input)
 
-	if _, err = w.client.PutObject(ctx, input); err != nil {
-		return 0, fmt.Errorf("failed to put object to S3: %w", err)
+	if w.useUploader(len(buf)) {
+		// a digest of the whole body does not match any single part
+		input.ContentMD5 = nil
+		if err := w.upload(ctx, input); err != nil {
+			return err
+		}
+	} else if _, err := w.client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	w.noteStored(offset, int64(len(buf)))
+	return nil
+}
+
+// applyPutOptions sets the configured object attributes on a record upload.
+func (w *S3WAL) applyPutOptions(input *s3.PutObjectInput) {
+	if w.kmsKeyID != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(w.kmsKeyID)
+	}
+	if w.bucketKey {
+		input.BucketKeyEnabled = aws.Bool(true)
+	}
+	if w.cacheControl != "" {
+		input.CacheControl = aws.String(w.cacheControl)
+	}
+	if w.contentMD5 {
+		input.ContentMD5 = contentMD5(input.Body)
+	}
+}
+
+// applyMultipartOptions is applyPutOptions for multipart record uploads.
+func (w *S3WAL) applyMultipartOptions(input *s3.CreateMultipartUploadInput) {
+	if w.kmsKeyID != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(w.kmsKeyID)
+	}
+	if w.bucketKey {
+		input.BucketKeyEnabled = aws.Bool(true)
+	}
+	if w.cacheControl != "" {
+		input.CacheControl = aws.String(w.cacheControl)
 	}
-	w.length = nextOffset
-	return nextOffset, nil
 }
 
 func (w *S3WAL) Read(ctx context.Context, offset uint64) (Record, error) {
+	start := time.Now()
+	record, err := w.read(ctx, offset)
+	if err != nil {
+		err = w.truncationError(ctx, offset, err)
+	}
+	w.observe(MetricReads, MetricReadErrors, MetricReadLatency, start, err)
+	return record, err
+}
+
+// errStopRange stops the ReadOrdered call behind a ReadRange whose consumer
+// stopped iterating.
+var errStopRange = errors.New("range iteration stopped")
+
+// ReadRange returns an iterator over the records at offsets [start, end] in
+// offset order, fetched ahead concurrently like ReadOrdered, whose
+// WithReadAhead setting it shares. Every record goes through Read's
+// validation. The first error, such as a missing offset, a checksum mismatch
+// or ctx being cancelled, is yielded with a zero Record and ends the
+// iteration. Fetches still in flight are cancelled and waited for when the
+// iteration ends, including when the consumer breaks out of the loop early.
+func (w *S3WAL) ReadRange(ctx context.Context, start, end uint64) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		err := w.ReadOrdered(ctx, start, end, func(record Record) error {
+			if !yield(record, nil) {
+				return errStopRange
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopRange) {
+			yield(Record{}, err)
+		}
+	}
+}
+
+func (w *S3WAL) read(ctx context.Context, offset uint64) (Record, error) {
+	if w.bloomAbsent(offset) {
+		return Record{}, errBloomAbsent(offset)
+	}
+	records, err := w.readObject(ctx, offset)
+	if err == nil {
+		return records[0], nil
+	}
+	var nsk *types.NoSuchKey
+	if !errors.As(err, &nsk) {
+		return Record{}, err
+	}
+	first, found, lerr := w.findMicroBatch(ctx, offset)
+	if lerr != nil {
+		return Record{}, lerr
+	}
+	if !found {
+		return Record{}, err
+	}
+	batch, berr := w.readObject(ctx, first)
+	if berr != nil {
+		return Record{}, berr
+	}
+	for _, record := range batch {
+		if record.Offset == offset {
+			return record, nil
+		}
+	}
+	return Record{}, err
+}
+
+// readObject fetches the object keyed by offset and returns every record it
+// holds: a single record for a regular object, or all records of a
+// micro-batch.
+func (w *S3WAL) readObject(ctx context.Context, offset uint64) ([]Record, error) {
+	obj, err := w.fetchObject(ctx, offset)
+	if err != nil {
+		return nil, err
+	}
+	if obj.isMicroBatch() {
+		records, err := decodeMicroBatch(offset, obj.payload)
+		for i := range records {
+			records[i].Timestamp = obj.header.timestamp
+			records[i].LastModified = obj.lastModified
+		}
+		return records, err
+	}
+	deps, err := decodeDependencies(obj.metadata)
+	if err != nil {
+		return nil, err
+	}
+	snapshotOf, err := decodeSnapshotOf(obj.metadata)
+	if err != nil {
+		return nil, err
+	}
+	data := obj.payload
+	if obj.header.chunked {
+		if data, err = w.readChunks(ctx, offset, obj.payload); err != nil {
+			return nil, err
+		}
+	}
+	return []Record{{
+		Offset:       offset,
+		Data:         data,
+		SchemaID:     obj.header.schemaID,
+		Timestamp:    obj.header.timestamp,
+		Metadata:     userMetadata(obj.metadata),
+		LastModified: obj.lastModified,
+		PartitionKey: obj.metadata[metaPartitionKey],
+		DependsOn:    deps,
+		SnapshotOf:   snapshotOf,
+	}}, nil
+}
+
+// fetchedObject is a downloaded object whose frame has been validated.
+type fetchedObject struct {
+	header       frameHeader
+	payload      []byte
+	metadata     map[string]string
+	lastModified time.Time
+}
+
+func (o fetchedObject) isMicroBatch() bool {
+	_, ok := o.metadata[metaMicroBatch]
+	return ok
+}
+
+func (w *S3WAL) fetchObject(ctx context.Context, offset uint64) (fetchedObject, error) {
+	cached, hit := w.readCache.get(offset)
+	if hit && w.readCache.validation == CacheRelaxed {
+		return cached.serve(), nil
+	}
 	key := w.getObjectKey(offset)
+	got, err := w.getObject(ctx, key, cached.etag)
+	if hit && isNotModified(err) {
+		return cached.serve(), nil
+	}
+	if err != nil {
+		w.readCache.remove(offset)
+		return fetchedObject{}, err
+	}
+	obj, err := decodeObject(offset, got)
+	for attempt := 0; attempt < w.checksumRetries && errors.Is(err, ErrChecksumMismatch); attempt++ {
+		if got, err = w.getObject(ctx, key, ""); err != nil {
+			return fetchedObject{}, err
+		}
+		if obj, err = decodeObject(offset, got); err == nil {
+			w.incCounter(MetricChecksumRetryFixed, 1)
+		}
+	}
+	if got.metadata[metaNoOffsetHeader] == "" {
+		if errors.Is(err, ErrChecksumMismatch) && w.readRepair && w.replica != nil {
+			return w.repairFromReplica(ctx, offset, err)
+		}
+		if err != nil && w.optChecksum {
+			if legacy, lerr := decodeUnchecksummedFrame(got.data, offset); lerr == nil {
+				w.logger.Warn("s3log: returning record without a valid checksum", "key", key, "error", err)
+				return fetchedObject{header: frameHeader{offset: offset}, payload: legacy, metadata: got.metadata, lastModified: got.lastModified}, nil
+			}
+		}
+	}
+	if err == nil {
+		err = w.checkChecksumAlgorithm(obj.header)
+	}
+	if err != nil {
+		return fetchedObject{}, err
+	}
+	w.readCache.put(offset, got.etag, obj)
+	return obj, nil
+}
+
+// decodeObject validates the frame of an object downloaded from the key of
+// offset.
+func decodeObject(offset uint64, got downloadedObject) (fetchedObject, error) {
+	obj := fetchedObject{header: frameHeader{offset: offset}, metadata: got.metadata, lastModified: got.lastModified}
+	var err error
+	if got.metadata[metaNoOffsetHeader] != "" {
+		obj.payload, err = decodeOffsetlessFrame(got.data)
+	} else {
+		obj.header, obj.payload, err = decodeFrame(got.data, offset)
+	}
+	if err != nil {
+		return fetchedObject{}, err
+	}
+	return obj, nil
+}
+
+// downloadedObject is the body of an object along with the response fields
+// read from GetObject.
+type downloadedObject struct {
+	data         []byte
+	metadata     map[string]string
+	etag         string
+	lastModified time.Time
+}
+
+// getBytes downloads the whole object stored under key.
+func (w *S3WAL) getBytes(ctx context.Context, key string) ([]byte, map[string]string, error) {
+	got, err := w.getObject(ctx, key, "")
+	return got.data, got.metadata, err
+}
+
+// getObject is getBytes that also returns the object's ETag and LastModified
+// time. If ifNoneMatch is set and the object's ETag still matches it, S3
+// answers with a 304, which is returned as an error satisfying isNotModified.
+func (w *S3WAL) getObject(ctx context.Context, key, ifNoneMatch string) (downloadedObject, error) {
 	input := &s3.GetObjectInput{
 		Bucket: aws.String(w.bucketName),
 		Key:    aws.String(key),
 	}
+	if ifNoneMatch != "" {
+		input.IfNoneMatch = aws.String(ifNoneMatch)
+	}
 	result, err := w.client.GetObject(ctx, input)
 	if err != nil {
-		return Record{}, fmt.Errorf("failed to get object from s3: %w", err)
+		return downloadedObject{}, fmt.Errorf("failed to get object from s3: %w", err)
 	}
 	defer result.Body.Close()
 
 	data, err := io.ReadAll(result.Body)
-	if err != nil {
-		return Record{}, fmt.Errorf("failed to read object body: %w", err)
-	}
-	if len(data) < 40 {
-		return Record{}, fmt.Errorf("invalid record: data too short")
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return downloadedObject{}, fmt.Errorf("%w: %v", ErrTruncatedRead, err)
 	}
-	if ok, err := validateOffset(data, offset); !ok {
-		return Record{}, fmt.Errorf("offset mismatch: %w", err)
+	if err != nil {
+		return downloadedObject{}, fmt.Errorf("failed to read object body: %w", err)
 	}
-	if !validateChecksum(data) {
-		return Record{}, fmt.Errorf("checksum mismatch")
+	if err := checkContentLength(result.ContentLength, len(data)); err != nil {
+		return downloadedObject{}, err
 	}
-	return Record{
-		Offset: offset,
-		Data:   data[8 : len(data)-32],
+	return downloadedObject{
+		data:         data,
+		metadata:     result.Metadata,
+		etag:         aws.ToString(result.ETag),
+		lastModified: aws.ToTime(result.LastModified),
 	}, nil
 }
 
-func (w *S3WAL) LastRecord(ctx context.Context) (Record, error) {
+// forEachObject lists every record object under the WAL prefix in key order,
+// which is also offset order for keys written by getObjectKey, and calls fn
+// with the offset parsed from each key. Keys that do not parse as an offset,
+// such as markers or checkpoints, are skipped. If two keys parse to the same
+// offset, it fails with ErrDuplicateOffset rather than picking one.
+func (w *S3WAL) forEachObject(ctx context.Context, fn func(offset uint64, obj types.Object) error) error {
+	return w.scanObjects(ctx, func(offset uint64, obj types.Object, duplicateOf string) error {
+		if duplicateOf != "" {
+			return fmt.Errorf("%w: offset %d is stored under both %s and %s", ErrDuplicateOffset, offset, duplicateOf, aws.ToString(obj.Key))
+		}
+		return fn(offset, obj)
+	})
+}
+
+// scanObjects is forEachObject, passing objects whose offset was already seen
+// under another key to fn along with that key.
+//
+// Keys written by getObjectKey are unique per offset, so duplicates need keys
+// in another format, such as those of an older key scheme. The offsets of
+// such foreign keys are remembered to catch duplicates among them, and once
+// both kinds of keys have been seen, a foreign key is also checked against
+// the getObjectKey key of its offset, which may have been listed before it.
+func (w *S3WAL) scanObjects(ctx context.Context, fn func(offset uint64, obj types.Object, duplicateOf string) error) error {
 	input := &s3.ListObjectsV2Input{
 		Bucket: aws.String(w.bucketName),
+		Prefix: aws.String(w.keyPrefix()),
 	}
-	paginator := s3.NewListObjectsV2Paginator(w.client, input)
 
-	var maxOffset uint64 = 0
-	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(ctx)
-		if err != nil {
-			return Record{}, fmt.Errorf("failed to list objects from s3: %w", err)
-		}
+	var foreign map[uint64]string
+	sawCanonical := false
+	return w.listPages(ctx, input, func(output *s3.ListObjectsV2Output) error {
 		for _, obj := range output.Contents {
 			key := *obj.Key
 			offset, err := w.getOffsetFromKey(key)
 			if err != nil {
-				return Record{}, fmt.Errorf("failed to parse offset from key: %w", err)
+				continue
+			}
+			duplicateOf := foreign[offset]
+			if canonical := w.getObjectKey(offset); key == canonical {
+				sawCanonical = true
+			} else {
+				if duplicateOf == "" && sawCanonical {
+					exists, err := w.objectExists(ctx, canonical)
+					if err != nil {
+						return err
+					}
+					if exists {
+						duplicateOf = canonical
+					}
+				}
+				if foreign == nil {
+					foreign = make(map[uint64]string)
+				}
+				if duplicateOf == "" {
+					foreign[offset] = key
+				}
 			}
-			if offset > maxOffset {
-				maxOffset = offset
+			if err := fn(offset, obj, duplicateOf); err != nil {
+				return err
 			}
 		}
+		return nil
+	})
+}
+
+func (w *S3WAL) objectExists(ctx context.Context, key string) (bool, error) {
+	_, err := w.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
 	}
-	if maxOffset == 0 {
-		return Record{}, fmt.Errorf("WAL is empty")
+	if err != nil {
+		return false, fmt.Errorf("failed to head object from S3: %w", err)
+	}
+	return true, nil
+}
+
+// objectEnd returns the last offset held by the object keyed by first, which
+// is first itself unless the object is a micro-batch.
+func (w *S3WAL) objectEnd(ctx context.Context, first uint64) (uint64, error) {
+	output, err := w.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.getObjectKey(first)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to head object in s3: %w", err)
+	}
+	count, ok := output.Metadata[metaMicroBatch]
+	if !ok {
+		return first, nil
+	}
+	n, err := strconv.ParseUint(count, 10, 64)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("%w: bad micro-batch record count %q", ErrInvalidRecord, count)
+	}
+	return first + n - 1, nil
+}
+
+// FirstRecord returns the record with the smallest offset in the log, which
+// is not 1 once older records have been compacted away. It fails with
+// ErrWALEmpty if the log has no records.
+func (w *S3WAL) FirstRecord(ctx context.Context) (Record, error) {
+	var minOffset uint64
+	err := w.forEachObject(ctx, func(offset uint64, _ types.Object) error {
+		if minOffset == 0 || offset < minOffset {
+			minOffset = offset
+		}
+		return nil
+	})
+	if err != nil {
+		return Record{}, err
+	}
+	if minOffset == 0 {
+		return Record{}, ErrWALEmpty
+	}
+	records, err := w.readObject(ctx, minOffset)
+	if err != nil {
+		return Record{}, err
+	}
+	return records[0], nil
+}
+
+// LastRecord returns the record with the largest offset in the log. It fails
+// with ErrWALEmpty if the log has no records.
+func (w *S3WAL) LastRecord(ctx context.Context) (Record, error) {
+	if length, ok := w.cachedTail(); ok {
+		return w.Read(ctx, length)
+	}
+	if tail, ok, err := w.indexedTail(ctx); err != nil || ok {
+		if err != nil {
+			return Record{}, err
+		}
+		w.setLength(tail)
+		return w.Read(ctx, tail)
+	}
+	maxOffset, err := w.lastObject(ctx)
+	if err != nil {
+		return Record{}, err
+	}
+	records, err := w.readObject(ctx, maxOffset)
+	if err != nil {
+		return Record{}, err
 	}
-	w.length = maxOffset
-	return w.Read(ctx, maxOffset)
+	last := records[len(records)-1]
+	w.setLength(last.Offset)
+	w.updateIndex(ctx, last.Offset)
+	return last, nil
 }
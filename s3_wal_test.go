@@ -1,12 +1,16 @@
 package s3log
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
@@ -128,6 +132,34 @@ func TestAppendAndReadSingle(t *testing.T) {
 	}
 }
 
+func TestReadLastModified(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	before := time.Now().Truncate(time.Second).Add(-time.Minute)
+	if _, err := wal.Append(ctx, []byte("one")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("two"), []byte("three")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	after := time.Now().Add(time.Minute)
+
+	for offset := uint64(1); offset <= 3; offset++ {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if record.LastModified.Before(before) || record.LastModified.After(after) {
+			t.Errorf("offset %d: expected LastModified near now, got %v", offset, record.LastModified)
+		}
+		if !record.Timestamp.IsZero() {
+			t.Errorf("offset %d: expected zero Timestamp without WithTimestamps, got %v", offset, record.Timestamp)
+		}
+	}
+}
+
 func TestAppendMultiple(t *testing.T) {
 	wal, cleanup := getWAL(t)
 	defer cleanup()
@@ -253,8 +285,8 @@ func TestLastRecord(t *testing.T) {
 	ctx := context.Background()
 
 	record, err := wal.LastRecord(ctx)
-	if err == nil {
-		t.Error("expected error when getting last record from empty WAL, got nil")
+	if !errors.Is(err, ErrWALEmpty) {
+		t.Errorf("expected ErrWALEmpty when getting last record from empty WAL, got %v", err)
 	}
 
 	var lastData []byte
@@ -279,3 +311,231 @@ func TestLastRecord(t *testing.T) {
 		t.Errorf("data mismatch: expected %q, got %q", lastData, record.Data)
 	}
 }
+
+func TestFirstRecord(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.FirstRecord(ctx); !errors.Is(err, ErrWALEmpty) {
+		t.Errorf("expected ErrWALEmpty for first record of empty WAL, got %v", err)
+	}
+	for _, data := range []string{"one", "two", "three"} {
+		if _, err := wal.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append record: %v", err)
+		}
+	}
+	if record, err := wal.FirstRecord(ctx); err != nil || record.Offset != 1 || string(record.Data) != "one" {
+		t.Errorf("expected record one at offset 1, got %d %q (%v)", record.Offset, record.Data, err)
+	}
+	if err := wal.deleteObject(ctx, 1); err != nil {
+		t.Fatalf("failed to delete record: %v", err)
+	}
+	if record, err := wal.FirstRecord(ctx); err != nil || record.Offset != 2 || string(record.Data) != "two" {
+		t.Errorf("expected record two at offset 2, got %d %q (%v)", record.Offset, record.Data, err)
+	}
+}
+
+func TestGetOffsetFromKey(t *testing.T) {
+	wal := NewS3WAL(nil, "bucket", "p")
+	for key, want := range map[string]uint64{
+		"p/42":                   42,
+		"p/00000042":             42,
+		"p-42":                   42,
+		wal.getObjectKey(123456): 123456,
+	} {
+		got, err := wal.getOffsetFromKey(key)
+		if err != nil || got != want {
+			t.Errorf("getOffsetFromKey(%q): expected %d, got %d (%v)", key, want, got, err)
+		}
+	}
+	for _, key := range []string{"p/", "p/checkpoint", "p/42.marker", "42", "p/99999999999999999999999"} {
+		if _, err := wal.getOffsetFromKey(key); err == nil {
+			t.Errorf("getOffsetFromKey(%q): expected error, got nil", key)
+		}
+	}
+}
+
+func TestEmptyPrefix(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	wal := NewS3WAL(base.client, base.bucketName, "")
+	defer emptyBucket(ctx, setupMinioClient(), base.bucketName, "")
+	if key := wal.getObjectKey(7); key != "00000000000000000007" {
+		t.Errorf("expected a key without separator, got %q", key)
+	}
+	for key, want := range map[string]uint64{"00000000000000000007": 7, "42": 42} {
+		if got, err := wal.getOffsetFromKey(key); err != nil || got != want {
+			t.Errorf("getOffsetFromKey(%q): expected %d, got %d (%v)", key, want, got, err)
+		}
+	}
+	if _, err := wal.getOffsetFromKey(""); err == nil {
+		t.Error("getOffsetFromKey(\"\"): expected error, got nil")
+	}
+
+	for _, data := range []string{"a", "b", "c"} {
+		if _, err := wal.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	record, err := wal.Read(ctx, 2)
+	if err != nil || string(record.Data) != "b" {
+		t.Errorf("expected %q at offset 2, got %q (%v)", "b", record.Data, err)
+	}
+	last, err := wal.LastRecord(ctx)
+	if err != nil || last.Offset != 3 || string(last.Data) != "c" {
+		t.Errorf("expected last record c at offset 3, got %+v (%v)", last, err)
+	}
+
+	reopened, err := Open(ctx, base.client, base.bucketName, "")
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if offset, err := reopened.Append(ctx, []byte("d")); err != nil || offset != 4 {
+		t.Errorf("expected to append at offset 4, got %d (%v)", offset, err)
+	}
+	if err := reopened.Seal(ctx); err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if last, err := reopened.LastRecord(ctx); err != nil || last.Offset != 4 {
+		t.Errorf("expected the seal marker not to be taken for a record, got %+v (%v)", last, err)
+	}
+}
+
+func TestEmptyPrefixSideObjects(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	wal := NewS3WAL(base.client, base.bucketName, "", WithChunkedChecksums(100), WithContentKeying())
+	defer emptyBucket(ctx, setupMinioClient(), base.bucketName, "")
+	large := bytes.Repeat([]byte("x"), 250)
+	for _, data := range [][]byte{large, []byte("small"), large} {
+		if _, err := wal.Append(ctx, data); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	var seen int
+	err := wal.Consume(runCtx, func(r Record) error {
+		if seen++; seen == 2 {
+			cancel()
+		}
+		return nil
+	}, ConsumeOptions{Name: "worker-7", CommitEvery: 1, PollInterval: 10 * time.Millisecond})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	last, err := wal.LastRecord(ctx)
+	if err != nil || last.Offset != 2 || string(last.Data) != "small" {
+		t.Errorf("expected last record small at offset 2, got %d (%v)", last.Offset, err)
+	}
+	if first, last, records, err := wal.TailInfo(ctx); err != nil || first != 1 || last != 2 || records != 2 {
+		t.Errorf("expected offsets 1 to 2 in 2 records, got %d to %d in %d (%v)", first, last, records, err)
+	}
+	if checkpoint, err := wal.Checkpoint(ctx, "worker-7"); err != nil || checkpoint != 2 {
+		t.Errorf("expected checkpoint 2, got %d (%v)", checkpoint, err)
+	}
+}
+
+// paddingClient appends garbage to every GetObject body without changing the
+// reported ContentLength, like a buggy gateway.
+type paddingClient struct {
+	S3Client
+}
+
+func (c paddingClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	output, err := c.S3Client.GetObject(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	output.Body = io.NopCloser(io.MultiReader(output.Body, strings.NewReader("garbage")))
+	return output, nil
+}
+
+func TestReadUnexpectedLength(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Append(ctx, []byte("record")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	reader := NewS3WAL(paddingClient{setupMinioClient()}, wal.bucketName, wal.prefix)
+	if _, err := reader.Read(ctx, 1); !errors.Is(err, ErrUnexpectedLength) {
+		t.Errorf("expected ErrUnexpectedLength, got %v", err)
+	}
+}
+
+func TestCheckContentLength(t *testing.T) {
+	if err := checkContentLength(aws.Int64(100), 100); err != nil {
+		t.Errorf("expected no error for complete body, got %v", err)
+	}
+	if err := checkContentLength(nil, 10); err != nil {
+		t.Errorf("expected no error for unknown content length, got %v", err)
+	}
+	err := checkContentLength(aws.Int64(100), 60)
+	if !errors.Is(err, ErrTruncatedRead) {
+		t.Errorf("expected ErrTruncatedRead, got %v", err)
+	}
+	if errors.Is(err, ErrChecksumMismatch) {
+		t.Error("truncated read must not be reported as a checksum mismatch")
+	}
+	err = checkContentLength(aws.Int64(100), 120)
+	if !errors.Is(err, ErrUnexpectedLength) {
+		t.Errorf("expected ErrUnexpectedLength, got %v", err)
+	}
+	if errors.Is(err, ErrChecksumMismatch) || errors.Is(err, ErrTruncatedRead) {
+		t.Error("extra bytes must not be reported as a checksum mismatch or truncation")
+	}
+}
+
+func TestReadRange(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 20; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	next := uint64(5)
+	for record, err := range wal.ReadRange(ctx, 5, 15) {
+		if err != nil {
+			t.Fatalf("failed to read range: %v", err)
+		}
+		if record.Offset != next || string(record.Data) != fmt.Sprintf("record %d", next) {
+			t.Fatalf("expected record %d, got %d %q", next, record.Offset, record.Data)
+		}
+		next++
+	}
+	if next != 16 {
+		t.Errorf("expected to read through offset 15, stopped before %d", next)
+	}
+
+	for record := range wal.ReadRange(ctx, 1, 20) {
+		if record.Offset == 3 {
+			break
+		}
+	}
+
+	var last uint64
+	var rangeErr error
+	for record, err := range wal.ReadRange(ctx, 18, 22) {
+		if err != nil {
+			rangeErr = err
+			continue
+		}
+		last = record.Offset
+	}
+	var nsk *types.NoSuchKey
+	if last != 20 || !errors.As(rangeErr, &nsk) {
+		t.Errorf("expected records through 20 then NoSuchKey, got %d and %v", last, rangeErr)
+	}
+}
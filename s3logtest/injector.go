@@ -0,0 +1,267 @@
+// Package s3logtest provides helpers for testing code built on s3log without
+// a flaky S3, chiefly FailureInjector.
+package s3logtest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	s3log "github.com/xmohamd/s3-log"
+)
+
+// Op names an S3 operation of s3log.S3Client, such as "GetObject".
+type Op string
+
+// Operations of s3log.S3Client.
+const (
+	PutObject               Op = "PutObject"
+	GetObject               Op = "GetObject"
+	HeadObject              Op = "HeadObject"
+	ListObjectsV2           Op = "ListObjectsV2"
+	CreateMultipartUpload   Op = "CreateMultipartUpload"
+	UploadPart              Op = "UploadPart"
+	CompleteMultipartUpload Op = "CompleteMultipartUpload"
+	AbortMultipartUpload    Op = "AbortMultipartUpload"
+	CopyObject              Op = "CopyObject"
+	DeleteObject            Op = "DeleteObject"
+	DeleteObjects           Op = "DeleteObjects"
+)
+
+// SlowDown returns the error S3 answers with when throttling requests: a 503
+// response with the SlowDown code, which s3log treats as transient.
+func SlowDown() error {
+	return responseError(http.StatusServiceUnavailable, "SlowDown", "Please reduce your request rate.")
+}
+
+// InternalError returns a 500 response with the InternalError code.
+func InternalError() error {
+	return responseError(http.StatusInternalServerError, "InternalError", "We encountered an internal error. Please try again.")
+}
+
+// Timeout returns the error of a request that could not be sent or answered
+// in time, as the SDK reports it.
+func Timeout() error {
+	return &smithyhttp.RequestSendError{Err: context.DeadlineExceeded}
+}
+
+func responseError(status int, code, message string) error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+		Err:      &smithy.GenericAPIError{Code: code, Message: message},
+	}
+}
+
+// FailureInjector wraps an s3log.S3Client and deterministically fails,
+// corrupts or delays chosen requests. Requests of each operation are counted
+// from 1, and every request is counted whether or not it fails. The zero
+// configuration passes everything through. It is safe for concurrent use,
+// and may be reconfigured while in use.
+type FailureInjector struct {
+	s3log.S3Client
+
+	mu       sync.Mutex
+	calls    map[Op]int
+	failures map[Op]map[int]error
+	always   map[Op]error
+	corrupt  map[int]bool
+	corruptN bool
+	latency  map[Op]time.Duration
+}
+
+var _ s3log.S3Client = (*FailureInjector)(nil)
+
+// New returns a FailureInjector passing requests on to client.
+func New(client s3log.S3Client) *FailureInjector {
+	return &FailureInjector{
+		S3Client: client,
+		calls:    make(map[Op]int),
+		failures: make(map[Op]map[int]error),
+		always:   make(map[Op]error),
+		corrupt:  make(map[int]bool),
+		latency:  make(map[Op]time.Duration),
+	}
+}
+
+// FailNth makes the nth request of op fail with err without reaching the
+// wrapped client.
+func (f *FailureInjector) FailNth(op Op, n int, err error) *FailureInjector {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures[op] == nil {
+		f.failures[op] = make(map[int]error)
+	}
+	f.failures[op][n] = err
+	return f
+}
+
+// FailAll makes every later request of op fail with err, or stops doing so
+// if err is nil.
+func (f *FailureInjector) FailAll(op Op, err error) *FailureInjector {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.always, op)
+	} else {
+		f.always[op] = err
+	}
+	return f
+}
+
+// CorruptNthGet flips a bit in the middle of the body returned by the nth
+// GetObject request.
+func (f *FailureInjector) CorruptNthGet(n int) *FailureInjector {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.corrupt[n] = true
+	return f
+}
+
+// CorruptGets flips a bit in the body of every later GetObject request, or
+// stops doing so.
+func (f *FailureInjector) CorruptGets(enabled bool) *FailureInjector {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.corruptN = enabled
+	return f
+}
+
+// Delay makes every later request of op wait d before it is sent, or fail
+// with the context's error if it is done first.
+func (f *FailureInjector) Delay(op Op, d time.Duration) *FailureInjector {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latency[op] = d
+	return f
+}
+
+// Calls returns the number of requests of op made so far.
+func (f *FailureInjector) Calls(op Op) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[op]
+}
+
+// before counts a request of op, waits for its delay and returns the error it
+// must fail with, if any, and whether its body must be corrupted.
+func (f *FailureInjector) before(ctx context.Context, op Op) (bool, error) {
+	f.mu.Lock()
+	f.calls[op]++
+	n := f.calls[op]
+	err := f.failures[op][n]
+	if err == nil {
+		err = f.always[op]
+	}
+	corrupt := op == GetObject && (f.corruptN || f.corrupt[n])
+	delay := f.latency[op]
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return corrupt, err
+}
+
+func (f *FailureInjector) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if _, err := f.before(ctx, PutObject); err != nil {
+		return nil, err
+	}
+	return f.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	corrupt, err := f.before(ctx, GetObject)
+	if err != nil {
+		return nil, err
+	}
+	output, err := f.S3Client.GetObject(ctx, params, optFns...)
+	if err != nil || !corrupt {
+		return output, err
+	}
+	body, err := io.ReadAll(output.Body)
+	output.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > 0 {
+		body[len(body)/2] ^= 1
+	}
+	output.Body = io.NopCloser(bytes.NewReader(body))
+	return output, nil
+}
+
+func (f *FailureInjector) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if _, err := f.before(ctx, HeadObject); err != nil {
+		return nil, err
+	}
+	return f.S3Client.HeadObject(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if _, err := f.before(ctx, ListObjectsV2); err != nil {
+		return nil, err
+	}
+	return f.S3Client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	if _, err := f.before(ctx, CreateMultipartUpload); err != nil {
+		return nil, err
+	}
+	return f.S3Client.CreateMultipartUpload(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if _, err := f.before(ctx, UploadPart); err != nil {
+		return nil, err
+	}
+	return f.S3Client.UploadPart(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	if _, err := f.before(ctx, CompleteMultipartUpload); err != nil {
+		return nil, err
+	}
+	return f.S3Client.CompleteMultipartUpload(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	if _, err := f.before(ctx, AbortMultipartUpload); err != nil {
+		return nil, err
+	}
+	return f.S3Client.AbortMultipartUpload(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if _, err := f.before(ctx, CopyObject); err != nil {
+		return nil, err
+	}
+	return f.S3Client.CopyObject(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if _, err := f.before(ctx, DeleteObject); err != nil {
+		return nil, err
+	}
+	return f.S3Client.DeleteObject(ctx, params, optFns...)
+}
+
+func (f *FailureInjector) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	if _, err := f.before(ctx, DeleteObjects); err != nil {
+		return nil, err
+	}
+	return f.S3Client.DeleteObjects(ctx, params, optFns...)
+}
@@ -0,0 +1,90 @@
+package s3logtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	s3log "github.com/xmohamd/s3-log"
+)
+
+// memClient stores objects in memory and supports only what appending and
+// reading single records needs.
+type memClient struct {
+	s3log.S3Client
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (c *memClient) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects[aws.ToString(params.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (c *memClient) GetObject(_ context.Context, params *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	body, ok := c.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: aws.Int64(int64(len(body))),
+	}, nil
+}
+
+func TestFailureInjector(t *testing.T) {
+	ctx := context.Background()
+	injector := New(&memClient{objects: make(map[string][]byte)})
+	wal := s3log.NewS3WAL(injector, "bucket", "log")
+
+	injector.FailNth(PutObject, 2, SlowDown())
+	if _, err := wal.Append(ctx, []byte("first")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte("second")); err == nil {
+		t.Error("expected the second put to fail")
+	}
+	if offset, err := wal.Append(ctx, []byte("second")); err != nil || offset != 2 {
+		t.Errorf("expected offset 2, got %d (%v)", offset, err)
+	}
+	if injector.Calls(PutObject) != 3 {
+		t.Errorf("expected 3 puts, got %d", injector.Calls(PutObject))
+	}
+
+	injector.CorruptNthGet(1)
+	if _, err := wal.Read(ctx, 1); !errors.Is(err, s3log.ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if record, err := wal.Read(ctx, 1); err != nil || string(record.Data) != "first" {
+		t.Errorf("expected the next read to be intact, got %q (%v)", record.Data, err)
+	}
+
+	injector.FailAll(GetObject, Timeout())
+	if _, err := wal.Read(ctx, 2); err == nil {
+		t.Error("expected reads to time out")
+	}
+	injector.FailAll(GetObject, nil)
+
+	injector.Delay(GetObject, time.Second)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := wal.Read(timeoutCtx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the delay to outlast the deadline, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithAllowedSchemas restricts appends to the given schema IDs. Once set,
+// every append, including plain Append and AppendMicroBatch which carry no
+// schema, is rejected with ErrUnknownSchema unless its schema is listed.
+func WithAllowedSchemas(schemaIDs []string) Option {
+	return func(w *S3WAL) {
+		w.allowedSchemas = make(map[string]struct{}, len(schemaIDs))
+		for _, id := range schemaIDs {
+			w.allowedSchemas[id] = struct{}{}
+		}
+	}
+}
+
+// AppendWithSchema appends data tagged with schemaID. The schema ID is stored
+// in the frame header, covered by the checksum, and returned as
+// Record.SchemaID on read.
+func (w *S3WAL) AppendWithSchema(ctx context.Context, schemaID string, data []byte) (uint64, error) {
+	if schemaID == "" {
+		return 0, fmt.Errorf("%w: empty schema id", ErrUnknownSchema)
+	}
+	offset, _, err := w.append(ctx, frameHeader{schemaID: schemaID}, nil, data)
+	return offset, err
+}
+
+func (w *S3WAL) checkSchema(schemaID string) error {
+	if w.allowedSchemas == nil {
+		return nil
+	}
+	if _, ok := w.allowedSchemas[schemaID]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownSchema, schemaID)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAppendWithSchema(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	WithAllowedSchemas([]string{"order.v1", "order.v2"})(wal)
+
+	offset, err := wal.AppendWithSchema(ctx, "order.v2", []byte("order placed"))
+	if err != nil {
+		t.Fatalf("failed to append with schema: %v", err)
+	}
+
+	record, err := wal.Read(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if record.SchemaID != "order.v2" {
+		t.Errorf("expected schema %q, got %q", "order.v2", record.SchemaID)
+	}
+	if string(record.Data) != "order placed" {
+		t.Errorf("data mismatch: expected %q, got %q", "order placed", record.Data)
+	}
+
+	if _, err := wal.AppendWithSchema(ctx, "order.v3", []byte("nope")); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("expected ErrUnknownSchema, got %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte("untagged")); !errors.Is(err, ErrUnknownSchema) {
+		t.Errorf("expected ErrUnknownSchema for untagged append, got %v", err)
+	}
+	if wal.length != offset {
+		t.Errorf("rejected appends must not advance the log, length is %d", wal.length)
+	}
+}
+
+func TestAppendWithSchemaMixedFrames(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	plain, err := wal.Append(ctx, []byte("plain"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	tagged, err := wal.AppendWithSchema(ctx, "event.v1", []byte("tagged"))
+	if err != nil {
+		t.Fatalf("failed to append with schema: %v", err)
+	}
+
+	record, err := wal.Read(ctx, plain)
+	if err != nil {
+		t.Fatalf("failed to read plain record: %v", err)
+	}
+	if record.SchemaID != "" || string(record.Data) != "plain" {
+		t.Errorf("unexpected plain record: %+v", record)
+	}
+	record, err = wal.Read(ctx, tagged)
+	if err != nil {
+		t.Fatalf("failed to read tagged record: %v", err)
+	}
+	if record.SchemaID != "event.v1" || string(record.Data) != "tagged" {
+		t.Errorf("unexpected tagged record: %+v", record)
+	}
+}
@@ -0,0 +1,58 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// sealMarker is the name of the object marking a log as sealed, stored under
+// the log prefix. It does not end in digits, so listings never take it for a
+// record.
+const sealMarker = "sealed"
+
+func (w *S3WAL) sealKey() string {
+	return w.keyPrefix() + sealMarker
+}
+
+// Seal marks the log as finished by writing a marker object holding the last
+// offset appended by this S3WAL. Sealing is permanent: once this S3WAL is
+// sealed, or has seen the marker through IsSealed, its appends fail with
+// ErrSealed. Other writers are not stopped until they check IsSealed. Sealing
+// a log that is already sealed is not an error.
+func (w *S3WAL) Seal(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(w.bucketName),
+		Key:         aws.String(w.sealKey()),
+		Body:        bytes.NewReader([]byte(strconv.FormatUint(w.length, 10))),
+		IfNoneMatch: aws.String("*"),
+	})
+	if err != nil && !isPreconditionFailed(err) {
+		return fmt.Errorf("failed to put object to S3: %w", err)
+	}
+	w.sealed.Store(true)
+	return nil
+}
+
+// IsSealed reports whether the log has been sealed with Seal. Sealing is
+// permanent, so once the marker has been found, later calls return true
+// without a request.
+func (w *S3WAL) IsSealed(ctx context.Context) (bool, error) {
+	if w.sealed.Load() {
+		return true, nil
+	}
+	exists, err := w.objectExists(ctx, w.sealKey())
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		w.sealed.Store(true)
+	}
+	return exists, nil
+}
@@ -0,0 +1,74 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSeal(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := wal.Append(ctx, []byte("data")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	reader := NewS3WAL(wal.client, wal.bucketName, wal.prefix)
+	if sealed, err := reader.IsSealed(ctx); err != nil || sealed {
+		t.Errorf("expected an unsealed log, got %v (%v)", sealed, err)
+	}
+
+	if err := wal.Seal(ctx); err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if err := wal.Seal(ctx); err != nil {
+		t.Errorf("expected sealing twice to succeed, got %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte("late")); !errors.Is(err, ErrSealed) {
+		t.Errorf("expected ErrSealed, got %v", err)
+	}
+
+	if sealed, err := reader.IsSealed(ctx); err != nil || !sealed {
+		t.Errorf("expected a sealed log, got %v (%v)", sealed, err)
+	}
+	heads := reader.UsageStats().HeadRequests
+	if sealed, _ := reader.IsSealed(ctx); !sealed || reader.UsageStats().HeadRequests != heads {
+		t.Error("expected the sealed state to be cached")
+	}
+
+	// the marker is not mistaken for a record
+	if tail, err := reader.TailOffset(ctx); err != nil || tail != 3 {
+		t.Errorf("expected tail 3, got %d (%v)", tail, err)
+	}
+	if stats, err := reader.Stats(ctx); err != nil || stats.Objects != 3 {
+		t.Errorf("expected 3 objects, got %+v (%v)", stats, err)
+	}
+}
+
+func TestSealWhileAppendWaits(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// an append that passed its first check and is waiting for the lock when
+	// Seal completes
+	wal.mu.Lock()
+	done := make(chan error)
+	go func() {
+		_, err := wal.Append(ctx, []byte("late"))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	wal.sealed.Store(true)
+	wal.mu.Unlock()
+	if err := <-done; !errors.Is(err, ErrSealed) {
+		t.Errorf("expected ErrSealed, got %v", err)
+	}
+	if _, err := wal.LastRecord(ctx); !errors.Is(err, ErrWALEmpty) {
+		t.Errorf("expected no record to be written, got %v", err)
+	}
+}
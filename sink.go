@@ -0,0 +1,42 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+)
+
+// AppendSink receives every record the log writes, as the exact framed bytes
+// uploaded to S3. framed is not reused by the log and may be retained.
+type AppendSink func(ctx context.Context, offset uint64, framed []byte) error
+
+// WithAppendSink calls sink after each successful upload of a record object,
+// so that it can be shipped elsewhere, e.g. to Kafka, without reading it back
+// from S3. A micro-batch is passed once, at its first offset, and records
+// spilled by WithLocalSpill are passed when Drain uploads them. The records
+// of a BatchAppend are passed in order once the batch is settled. AppendStream
+// fails with a sink, which would need the whole stream in memory. Sink errors
+// are logged unless strict is set, in which case they are returned wrapping
+// ErrSinkFailed; the record is in the log by then, so the append also returns
+// its offset, and appending the record again would duplicate it.
+func WithAppendSink(sink AppendSink, strict bool) Option {
+	return func(w *S3WAL) {
+		w.appendSink = sink
+		w.strictSink = strict
+	}
+}
+
+// ship passes the frame uploaded at offset to the append sink, if any.
+func (w *S3WAL) ship(ctx context.Context, offset uint64, framed []byte) error {
+	if w.appendSink == nil {
+		return nil
+	}
+	err := w.appendSink(ctx, offset, framed)
+	if err == nil {
+		return nil
+	}
+	if w.strictSink {
+		return fmt.Errorf("%w: offset %d: %w", ErrSinkFailed, offset, err)
+	}
+	w.logger.Warn("s3log: append sink failed", "offset", offset, "error", err)
+	return nil
+}
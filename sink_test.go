@@ -0,0 +1,62 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestAppendSink(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	shipped := make(map[uint64][]byte)
+	var fail error
+	sink := func(_ context.Context, offset uint64, framed []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		shipped[offset] = framed
+		return fail
+	}
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithAppendSink(sink, true))
+
+	if _, err := wal.Append(ctx, []byte("small")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendWithSchema(ctx, "v1", []byte("with schema")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	if len(shipped) != 3 {
+		t.Fatalf("expected 3 shipped frames, got %d", len(shipped))
+	}
+	for _, offset := range []uint64{1, 2, 3} {
+		raw, err := wal.ReadRaw(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read raw: %v", err)
+		}
+		if !bytes.Equal(shipped[offset], raw) {
+			t.Errorf("offset %d: shipped frame does not match the stored object", offset)
+		}
+	}
+
+	fail = errors.New("broker unavailable")
+	offset, err := wal.Append(ctx, []byte("strict"))
+	if !errors.Is(err, ErrSinkFailed) || offset != 5 {
+		t.Errorf("expected offset 5 with ErrSinkFailed, got %d (%v)", offset, err)
+	}
+	if record, err := wal.Read(ctx, 5); err != nil || string(record.Data) != "strict" {
+		t.Errorf("expected the record to be written, got %q (%v)", record.Data, err)
+	}
+
+	WithAppendSink(sink, false)(wal)
+	if offset, err := wal.Append(ctx, []byte("best effort")); err != nil || offset != 6 {
+		t.Errorf("expected offset 6, got %d (%v)", offset, err)
+	}
+}
@@ -0,0 +1,48 @@
+package s3log
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Sizes of the fixed fields of a frame.
+const (
+	offsetHeaderSize = 8
+	checksumSize     = 32
+)
+
+// plainFrameOverhead is the framing added to every record stored as a plain
+// frame: the 8-byte offset header and the 32-byte checksum.
+const plainFrameOverhead = offsetHeaderSize + checksumSize
+
+// extendedFrameOverhead is the framing of an extended frame without any
+// optional header field: magic, flags, offset and checksum.
+const extendedFrameOverhead = 2 + offsetHeaderSize + checksumSize
+
+// ForEachRecordSize calls fn with the payload size of every record object,
+// in offset order, using only the sizes reported by listing. Sizes are the
+// object size minus the plain framing overhead, so records with extra header
+// fields (schema, timestamp) report those few bytes as payload, and a
+// micro-batch is reported once, at its first offset, with the size of all its
+// records plus their index. Marker and checkpoint keys are skipped.
+func (w *S3WAL) ForEachRecordSize(ctx context.Context, fn func(offset uint64, size int64) error) error {
+	return w.forEachObject(ctx, func(offset uint64, obj types.Object) error {
+		return fn(offset, max(aws.ToInt64(obj.Size)-plainFrameOverhead, 0))
+	})
+}
+
+// ListRecordSizes returns the payload size of every record object keyed by
+// offset. See ForEachRecordSize, which avoids building the map for huge logs.
+func (w *S3WAL) ListRecordSizes(ctx context.Context) (map[uint64]int64, error) {
+	sizes := make(map[uint64]int64)
+	err := w.ForEachRecordSize(ctx, func(offset uint64, size int64) error {
+		sizes[offset] = size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
@@ -0,0 +1,48 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestListRecordSizes(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, size := range []int{0, 10, 1000} {
+		if _, err := wal.Append(ctx, make([]byte, size)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	_, err := wal.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(wal.prefix + "/checkpoint"),
+		Body:   bytes.NewReader(make([]byte, 5000)),
+	})
+	if err != nil {
+		t.Fatalf("failed to put checkpoint: %v", err)
+	}
+
+	sizes, err := wal.ListRecordSizes(ctx)
+	if err != nil {
+		t.Fatalf("failed to list record sizes: %v", err)
+	}
+	expected := map[uint64]int64{1: 0, 2: 10, 3: 1000}
+	if len(sizes) != len(expected) {
+		t.Fatalf("expected %d sizes, got %v", len(expected), sizes)
+	}
+	for offset, size := range expected {
+		if sizes[offset] != size {
+			t.Errorf("offset %d: expected size %d, got %d", offset, size, sizes[offset])
+		}
+	}
+
+	if _, err := wal.LastRecord(ctx); err != nil {
+		t.Errorf("checkpoint key broke LastRecord: %v", err)
+	}
+}
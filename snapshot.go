@@ -0,0 +1,104 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// metaSnapshotOf is the user metadata key marking a record as a snapshot,
+// holding the offset up to which the snapshot covers the log.
+const metaSnapshotOf = "s3log-snapshot-of"
+
+// snapshotPointer is the name of the object holding the offset of the latest
+// snapshot record, stored under the log prefix. It does not end in digits, so
+// listings never take it for a record.
+const snapshotPointer = "snapshot"
+
+func (w *S3WAL) snapshotKey() string {
+	return w.keyPrefix() + snapshotPointer
+}
+
+// WithSkipSnapshots makes ReadOrdered, ReadPage, Consume and DumpNDJSON, and
+// everything built on them such as Replay, pass over records written with
+// AppendSnapshot. Read still returns a snapshot record asked for by offset.
+func WithSkipSnapshots() Option {
+	return func(w *S3WAL) {
+		w.skipSnapshots = true
+	}
+}
+
+// AppendSnapshot appends state as a snapshot of the log's records up to and
+// including upToOffset, e.g. a rolling aggregate from which a reader can
+// resume instead of replaying the whole log. The record is tagged as a
+// snapshot, returned with Record.SnapshotOf set, and a pointer object is
+// moved forward to it so LatestSnapshot finds it without a scan.
+func (w *S3WAL) AppendSnapshot(ctx context.Context, state []byte, upToOffset uint64) (uint64, error) {
+	if upToOffset == 0 {
+		return 0, fmt.Errorf("snapshot must cover at least offset 1")
+	}
+	metadata := map[string]string{metaSnapshotOf: strconv.FormatUint(upToOffset, 10)}
+	offset, _, err := w.append(ctx, frameHeader{}, metadata, state)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.advanceSnapshotPointer(ctx, offset); err != nil {
+		return offset, err
+	}
+	return offset, nil
+}
+
+// advanceSnapshotPointer points the snapshot pointer at offset unless it
+// already points at a later snapshot.
+func (w *S3WAL) advanceSnapshotPointer(ctx context.Context, offset uint64) error {
+	return w.raiseMarker(ctx, w.snapshotKey(), offset)
+}
+
+// snapshotOffset returns the offset held by the snapshot pointer, or
+// ErrNoSnapshot if there is no pointer.
+func (w *S3WAL) snapshotOffset(ctx context.Context) (uint64, error) {
+	offset, _, found, err := w.getMarker(ctx, w.snapshotKey())
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrNoSnapshot
+	}
+	return offset, nil
+}
+
+// LatestSnapshot returns the state and covered offset of the most recent
+// snapshot written with AppendSnapshot, or ErrNoSnapshot if there is none.
+func (w *S3WAL) LatestSnapshot(ctx context.Context) ([]byte, uint64, error) {
+	offset, err := w.snapshotOffset(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	record, err := w.Read(ctx, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if record.SnapshotOf == 0 {
+		return nil, 0, fmt.Errorf("%w: snapshot pointer names offset %d, which is not a snapshot", ErrInvalidRecord, offset)
+	}
+	return record.Data, record.SnapshotOf, nil
+}
+
+// decodeSnapshotOf returns the offset covered by a snapshot record, or 0 for
+// other records.
+func decodeSnapshotOf(metadata map[string]string) (uint64, error) {
+	encoded, ok := metadata[metaSnapshotOf]
+	if !ok {
+		return 0, nil
+	}
+	offset, err := strconv.ParseUint(encoded, 10, 64)
+	if err != nil || offset == 0 {
+		return 0, fmt.Errorf("%w: bad snapshot offset %q", ErrInvalidRecord, encoded)
+	}
+	return offset, nil
+}
+
+// skipRecord reports whether iteration passes over record.
+func (w *S3WAL) skipRecord(record Record) bool {
+	return w.skipSnapshots && record.SnapshotOf != 0
+}
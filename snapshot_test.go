@@ -0,0 +1,93 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, _, err := wal.LatestSnapshot(ctx); !errors.Is(err, ErrNoSnapshot) {
+		t.Fatalf("expected ErrNoSnapshot, got %v", err)
+	}
+	for _, data := range []string{"a", "b"} {
+		if _, err := wal.Append(ctx, []byte(data)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	first, err := wal.AppendSnapshot(ctx, []byte("sum=2"), 2)
+	if err != nil {
+		t.Fatalf("failed to append snapshot: %v", err)
+	}
+	if _, err := wal.Append(ctx, []byte("c")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	second, err := wal.AppendSnapshot(ctx, []byte("sum=3"), 4)
+	if err != nil {
+		t.Fatalf("failed to append snapshot: %v", err)
+	}
+
+	state, upTo, err := wal.LatestSnapshot(ctx)
+	if err != nil {
+		t.Fatalf("failed to get latest snapshot: %v", err)
+	}
+	if string(state) != "sum=3" || upTo != 4 {
+		t.Errorf("expected snapshot sum=3 up to 4, got %q up to %d", state, upTo)
+	}
+
+	// an older snapshot never moves the pointer backwards
+	if err := wal.advanceSnapshotPointer(ctx, first); err != nil {
+		t.Fatalf("failed to advance pointer: %v", err)
+	}
+	if offset, err := wal.snapshotOffset(ctx); err != nil || offset != second {
+		t.Errorf("expected pointer at %d, got %d (%v)", second, offset, err)
+	}
+
+	record, err := wal.Read(ctx, first)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if record.SnapshotOf != 2 || record.Metadata != nil {
+		t.Errorf("unexpected snapshot record %+v", record)
+	}
+
+	var all []string
+	err = wal.ReadOrdered(ctx, 1, second, func(r Record) error {
+		all = append(all, string(r.Data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read ordered: %v", err)
+	}
+	if len(all) != 5 {
+		t.Errorf("expected snapshots to be included, got %q", all)
+	}
+
+	skipping := NewS3WAL(wal.client, wal.bucketName, wal.prefix, WithSkipSnapshots())
+	var data []string
+	err = skipping.ReadOrdered(ctx, 1, second, func(r Record) error {
+		data = append(data, string(r.Data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read ordered: %v", err)
+	}
+	if len(data) != 3 || data[0] != "a" || data[1] != "b" || data[2] != "c" {
+		t.Errorf("expected snapshots to be skipped, got %q", data)
+	}
+	page, _, err := skipping.ReadPage(ctx, "", 10)
+	if err != nil {
+		t.Fatalf("failed to read page: %v", err)
+	}
+	if len(page) != 3 {
+		t.Errorf("expected 3 records on the page, got %d", len(page))
+	}
+
+	if _, err := wal.AppendSnapshot(ctx, []byte("x"), 0); err == nil {
+		t.Error("expected error for a snapshot covering no offsets")
+	}
+}
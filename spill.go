@@ -0,0 +1,200 @@
+package s3log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// WithLocalSpill makes Append write records to a local append-only file in
+// dir when S3 cannot be reached, instead of failing. A spilled record is
+// assigned its offset when it is spilled and is acknowledged like any other
+// append; Drain later writes it to S3 at that same offset. Once a record has
+// been spilled, later appends are spilled too until Drain succeeds, so that
+// records reach S3 in offset order. Micro-batches and streamed records
+// cannot be spilled: AppendMicroBatch and AppendStream fail with
+// ErrSpillPending until Drain succeeds, and fail as before when S3 cannot be
+// reached.
+//
+// A spill file left behind by a previous process holds offsets assigned by
+// that process, so call Drain before appending after a restart.
+func WithLocalSpill(dir string) Option {
+	return func(w *S3WAL) {
+		w.spillDir = dir
+	}
+}
+
+// isTransportError reports whether err means S3 could not be reached or
+// failed server-side, as opposed to rejecting the request.
+func isTransportError(err error) bool {
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &sendErr) {
+		return true
+	}
+	var respErr interface{ HTTPStatusCode() int }
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() >= 500
+}
+
+func (w *S3WAL) spillPath() string {
+	return filepath.Join(w.spillDir, url.PathEscape(w.bucketName+"/"+w.prefix)+".spill")
+}
+
+func (w *S3WAL) spillPending() bool {
+	if w.spillDir == "" {
+		return false
+	}
+	info, err := os.Stat(w.spillPath())
+	return err == nil && info.Size() > 0
+}
+
+// spill appends the framed record to the spill file as offset || frameLen ||
+// frame || metadataLen || metadata (JSON) and syncs it.
+func (w *S3WAL) spill(h frameHeader, metadata map[string]string, data []byte) (uint64, []byte, error) {
+	frame, metadata, err := w.frameRecord(h, metadata, data)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to prepare object body: %w", err)
+	}
+	meta, err := json.Marshal(metadata)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	entry := binary.BigEndian.AppendUint64(nil, h.offset)
+	entry = binary.BigEndian.AppendUint32(entry, uint32(len(frame)))
+	entry = append(entry, frame...)
+	entry = binary.BigEndian.AppendUint32(entry, uint32(len(meta)))
+	entry = append(entry, meta...)
+
+	f, err := os.OpenFile(w.spillPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open spill file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(entry); err != nil {
+		return 0, nil, fmt.Errorf("failed to write spill file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return 0, nil, fmt.Errorf("failed to sync spill file: %w", err)
+	}
+	checksum := h.storedChecksum(frame)
+	w.chainTail = chainLink{offset: h.offset, checksum: checksum}
+	w.length = h.offset
+	w.bloomAdd(h.offset, h.offset)
+	return h.offset, checksum, nil
+}
+
+type spillEntry struct {
+	offset   uint64
+	frame    []byte
+	metadata map[string]string
+}
+
+// readSpill returns the entries of the spill file in the order they were
+// spilled.
+func (w *S3WAL) readSpill() ([]spillEntry, error) {
+	f, err := os.Open(w.spillPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spill file: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	readChunk := func() ([]byte, error) {
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, n)
+		_, err := io.ReadFull(r, buf)
+		return buf, err
+	}
+
+	var entries []spillEntry
+	for {
+		var entry spillEntry
+		err := binary.Read(r, binary.BigEndian, &entry.offset)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spill file: %w", err)
+		}
+		if entry.frame, err = readChunk(); err != nil {
+			return nil, fmt.Errorf("failed to read spill file: %w", err)
+		}
+		meta, err := readChunk()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spill file: %w", err)
+		}
+		if err := json.Unmarshal(meta, &entry.metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode spilled metadata: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// Drain writes the records spilled by WithLocalSpill to S3 in order, at the
+// offsets they were assigned, and returns how many it wrote. Records already
+// present in S3 with identical contents, e.g. from an interrupted Drain, are
+// skipped. The spill file is removed once every record is in S3.
+func (w *S3WAL) Drain(ctx context.Context) (int, error) {
+	drained, _, err := w.drain(ctx)
+	return drained, err
+}
+
+// drain is Drain, additionally returning the offsets of the spilled records
+// that are not known to be in S3 when it fails.
+func (w *S3WAL) drain(ctx context.Context) (int, []uint64, error) {
+	if w.spillDir == "" {
+		return 0, nil, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	entries, err := w.readSpill()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(entries) == 0 {
+		return 0, nil, nil
+	}
+
+	drained := 0
+	for i, entry := range entries {
+		if err := w.putFrame(ctx, entry.offset, entry.frame, entry.metadata); err != nil {
+			existing, _, gerr := w.getBytes(ctx, w.getObjectKey(entry.offset))
+			if gerr != nil || !bytes.Equal(existing, entry.frame) {
+				pending := make([]uint64, 0, len(entries)-i)
+				for _, e := range entries[i:] {
+					pending = append(pending, e.offset)
+				}
+				return drained, pending, fmt.Errorf("failed to drain offset %d: %w", entry.offset, err)
+			}
+			continue
+		}
+		drained++
+		if err := w.ship(ctx, entry.offset, entry.frame); err != nil {
+			pending := make([]uint64, 0, len(entries)-i-1)
+			for _, e := range entries[i+1:] {
+				pending = append(pending, e.offset)
+			}
+			return drained, pending, err
+		}
+	}
+	w.updateIndex(ctx, entries[len(entries)-1].offset)
+	if err := os.Remove(w.spillPath()); err != nil {
+		return drained, nil, fmt.Errorf("failed to remove spill file: %w", err)
+	}
+	return drained, nil, nil
+}
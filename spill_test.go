@@ -0,0 +1,112 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// unreachableClient fails every PutObject with a transport error while down
+// is set.
+type unreachableClient struct {
+	S3Client
+	down bool
+}
+
+func (c *unreachableClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if c.down {
+		return nil, &smithyhttp.RequestSendError{Err: errors.New("connection refused")}
+	}
+	return c.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func TestLocalSpill(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	client := &unreachableClient{S3Client: setupMinioClient()}
+	wal := NewS3WAL(client, base.bucketName, base.prefix, WithLocalSpill(t.TempDir()))
+
+	if _, err := wal.Append(ctx, []byte("one")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	client.down = true
+	for i, data := range []string{"two", "three"} {
+		offset, err := wal.Append(ctx, []byte(data))
+		if err != nil {
+			t.Fatalf("expected append to spill, got %v", err)
+		}
+		if offset != uint64(i+2) {
+			t.Errorf("expected spilled offset %d, got %d", i+2, offset)
+		}
+	}
+	if _, err := wal.Read(ctx, 2); err == nil {
+		t.Error("spilled record must not be in S3 before Drain")
+	}
+
+	client.down = false
+	offset, err := wal.AppendWithMetadata(ctx, map[string]string{"k": "v"}, []byte("four"))
+	if err != nil || offset != 4 {
+		t.Fatalf("expected offset 4, got %d (%v)", offset, err)
+	}
+	if _, err := wal.Read(ctx, 4); err == nil {
+		t.Error("appends after a spill must be spilled until Drain")
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("five")}); !errors.Is(err, ErrSpillPending) {
+		t.Errorf("expected ErrSpillPending for a micro-batch, got %v", err)
+	}
+	if _, err := wal.AppendStream(ctx, strings.NewReader("five")); !errors.Is(err, ErrSpillPending) {
+		t.Errorf("expected ErrSpillPending for a stream, got %v", err)
+	}
+
+	n, err := wal.Drain(ctx)
+	if err != nil {
+		t.Fatalf("failed to drain: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 drained records, got %d", n)
+	}
+	for offset, data := range map[uint64]string{1: "one", 2: "two", 3: "three", 4: "four"} {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if string(record.Data) != data {
+			t.Errorf("offset %d: expected %q, got %q", offset, data, record.Data)
+		}
+	}
+	if record, _ := wal.Read(ctx, 4); record.Metadata["k"] != "v" {
+		t.Errorf("spilled metadata was lost: %v", record.Metadata)
+	}
+
+	if offset, err := wal.Append(ctx, []byte("five")); err != nil || offset != 5 {
+		t.Fatalf("expected offset 5, got %d (%v)", offset, err)
+	}
+	if _, err := wal.Read(ctx, 5); err != nil {
+		t.Errorf("append after drain should go to S3: %v", err)
+	}
+}
+
+func TestLocalSpillIgnoresRejections(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	WithLocalSpill(t.TempDir())(wal)
+
+	if _, err := wal.Append(ctx, []byte("first")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	wal.length = 0
+	if _, err := wal.Append(ctx, []byte("conflict")); err == nil {
+		t.Error("expected a conflicting append to fail rather than spill")
+	}
+	if wal.spillPending() {
+		t.Error("a rejected append must not be spilled")
+	}
+}
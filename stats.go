@@ -0,0 +1,71 @@
+package s3log
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// StorageStats summarizes the objects currently stored for a log.
+type StorageStats struct {
+	Objects             uint64
+	Bytes               int64
+	BytesByStorageClass map[string]int64
+	// FramingOverheadBytes estimates how many of Bytes are framing rather
+	// than payload: FramingOverhead times Objects. It assumes every object was
+	// written with the current configuration; a micro-batch is framed once,
+	// and its index is counted as payload.
+	FramingOverheadBytes int64
+}
+
+// FramingOverhead returns the bytes every record object carries in addition
+// to its payload under the current configuration: the 32-byte checksum, the
+// 8-byte offset header unless WithoutOffsetHeader applies, and with
+// WithTimestamps, WithHashChain or ChecksumLeading the extended frame's magic
+// and flags plus the timestamp and the previous record's checksum. With
+// WithChecksum, the checksum has the algorithm's size and the extended frame
+// a format byte. Records appended with a schema ID carry one byte plus the ID
+// on top of this.
+func (w *S3WAL) FramingOverhead() int {
+	switch {
+	case w.checksummer != nil:
+		n := 3 + 8 + w.checksummer.Size()
+		if w.timestamps {
+			n += 8
+		}
+		return n
+	case w.timestamps || w.hashChain || w.leadingChecksum:
+		n := 2 + 8 + 32
+		if w.timestamps {
+			n += 8
+		}
+		if w.hashChain {
+			n += 32
+		}
+		return n
+	case w.noOffsetHeader:
+		return 32
+	default:
+		return plainFrameOverhead
+	}
+}
+
+// Stats lists the log and reports its object count and stored bytes. Objects
+// without a reported storage class are counted as STANDARD.
+func (w *S3WAL) Stats(ctx context.Context) (StorageStats, error) {
+	stats := StorageStats{BytesByStorageClass: make(map[string]int64)}
+	err := w.forEachObject(ctx, func(_ uint64, obj types.Object) error {
+		class := string(obj.StorageClass)
+		if class == "" {
+			class = string(types.ObjectStorageClassStandard)
+		}
+		size := aws.ToInt64(obj.Size)
+		stats.Objects++
+		stats.Bytes += size
+		stats.BytesByStorageClass[class] += size
+		return nil
+	})
+	stats.FramingOverheadBytes = int64(stats.Objects) * int64(w.FramingOverhead())
+	return stats, err
+}
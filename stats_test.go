@@ -0,0 +1,47 @@
+package s3log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFramingOverhead(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name     string
+		opt      Option
+		overhead int
+	}{
+		{"plain", func(*S3WAL) {}, 40},
+		{"without offset header", WithoutOffsetHeader(), 32},
+		{"timestamps", WithTimestamps(), 50},
+		{"hash chain", WithHashChain(), 74},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			wal, cleanup := getWAL(t)
+			defer cleanup()
+			tc.opt(wal)
+			if got := wal.FramingOverhead(); got != tc.overhead {
+				t.Fatalf("expected overhead %d, got %d", tc.overhead, got)
+			}
+
+			var payload int64
+			for _, data := range []string{"a", "bb", "ccc"} {
+				if _, err := wal.Append(ctx, []byte(data)); err != nil {
+					t.Fatalf("failed to append: %v", err)
+				}
+				payload += int64(len(data))
+			}
+			stats, err := wal.Stats(ctx)
+			if err != nil {
+				t.Fatalf("failed to get stats: %v", err)
+			}
+			if stats.FramingOverheadBytes != 3*int64(tc.overhead) {
+				t.Errorf("expected %d overhead bytes, got %d", 3*tc.overhead, stats.FramingOverheadBytes)
+			}
+			if stats.Bytes-payload != stats.FramingOverheadBytes {
+				t.Errorf("stored %d bytes for %d payload bytes, but overhead is %d", stats.Bytes, payload, stats.FramingOverheadBytes)
+			}
+		})
+	}
+}
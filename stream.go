@@ -0,0 +1,227 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// minMultipartPartSize is the smallest part S3 accepts for every part of a
+// multipart upload except the last.
+const minMultipartPartSize = 5 << 20
+
+// maxMultipartPartSize is the largest part S3 accepts.
+const maxMultipartPartSize int64 = 5 << 30
+
+// maxMultipartParts is the largest number of parts of a multipart upload.
+const maxMultipartParts = 10000
+
+// WithMultipartPartSize sets the size of the parts uploaded by AppendStream,
+// which buffers one part in memory at a time. Larger parts mean fewer
+// requests, which helps on links with high bandwidth and high latency. The
+// default and minimum is 5 MiB, the smallest part S3 accepts, and the maximum
+// is 5 GiB. WithMultipartPartSize panics if size is out of that range.
+func WithMultipartPartSize(size int) Option {
+	if size < minMultipartPartSize || int64(size) > maxMultipartPartSize {
+		panic(fmt.Sprintf("s3log: multipart part size %d is outside the range S3 accepts [%d, %d]", size, minMultipartPartSize, maxMultipartPartSize))
+	}
+	return func(w *S3WAL) {
+		w.partSize = size
+	}
+}
+
+// AppendStream appends a record whose payload is read from r until EOF,
+// without knowing its length in advance. The framed record is uploaded with a
+// multipart upload: the offset header opens the first part, the payload is
+// buffered into part-sized chunks as it arrives, and the checksum is computed
+// incrementally and written at the end of the last part. The resulting object
+// is indistinguishable from one written by Append, timestamp included with
+// WithTimestamps, and is counted in the append metrics. Configurations whose
+// frame cannot be written that way, listed by checkStreamable, make it fail.
+//
+// S3 accepts at most 10,000 parts, so a stream may hold up to 10,000 times
+// the part size set by WithMultipartPartSize, less the framing; a longer one
+// fails before its last part is uploaded.
+func (w *S3WAL) AppendStream(ctx context.Context, r io.Reader) (uint64, error) {
+	start := time.Now()
+	offset, err := w.appendStream(ctx, r)
+	w.observe(MetricAppends, MetricAppendErrors, MetricAppendLatency, start, err)
+	if err == nil {
+		w.evictAfterAppend(ctx)
+	}
+	return offset, err
+}
+
+func (w *S3WAL) appendStream(ctx context.Context, r io.Reader) (uint64, error) {
+	if err := w.checkOpen(); err != nil {
+		return 0, err
+	}
+	if err := w.checkSchema(""); err != nil {
+		return 0, err
+	}
+	if err := w.checkStreamable(); err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.checkOpen(); err != nil {
+		return 0, err
+	}
+	if w.spillPending() {
+		return 0, fmt.Errorf("%w: drain them before appending a stream", ErrSpillPending)
+	}
+	nextOffset, err := w.nextOffset()
+	if err != nil {
+		return 0, err
+	}
+	h := frameHeader{offset: nextOffset}
+	if w.timestamps {
+		h.timestamp = w.now()
+	}
+	header, err := streamHeader(h)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare object body: %w", err)
+	}
+	sized := &sizeCheckedReader{w: w, r: r}
+	r = sized
+	if w.uploader != nil {
+		offset, err := w.appendStreamUploader(ctx, nextOffset, header, r)
+		if err == nil {
+			w.noteStored(offset, int64(len(header)+sized.n+checksumSize))
+		}
+		return offset, err
+	}
+	key := w.getObjectKey(nextOffset)
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(key),
+	}
+	w.applyMultipartOptions(input)
+	created, err := w.client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	parts, err := w.uploadStreamParts(ctx, key, created.UploadId, header, r)
+	var ifNoneMatch *string
+	if err == nil {
+		ifNoneMatch, err = w.ifNoneMatch(ctx, key)
+	}
+	if err == nil {
+		_, err = w.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(w.bucketName),
+			Key:             aws.String(key),
+			UploadId:        created.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+			IfNoneMatch:     ifNoneMatch,
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to complete multipart upload: %w", err)
+		}
+	}
+	if err != nil {
+		_, abortErr := w.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(w.bucketName),
+			Key:      aws.String(key),
+			UploadId: created.UploadId,
+		})
+		if abortErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to abort multipart upload: %w", abortErr))
+		}
+		return 0, err
+	}
+	w.noteStored(nextOffset, int64(len(header)+sized.n+checksumSize))
+	w.length = nextOffset
+	w.updateIndex(ctx, nextOffset)
+	w.bloomAdd(nextOffset, nextOffset)
+	return nextOffset, nil
+}
+
+// streamHeader returns the part of the frame for h that precedes the payload.
+// h must not need anything computed from the payload.
+func streamHeader(h frameHeader) ([]byte, error) {
+	frame, err := encodeFrame(h, nil)
+	if err != nil {
+		return nil, err
+	}
+	return frame[:len(frame)-checksumSize], nil
+}
+
+// checkStreamable returns an error if appended records need a frame that
+// AppendStream cannot write, since it writes a frame whose trailing SHA-256
+// is only known once the whole stream has been read, or if they must be
+// passed to an append sink, which takes the whole frame at once.
+func (w *S3WAL) checkStreamable() error {
+	if w.leadingChecksum {
+		return fmt.Errorf("AppendStream cannot write a leading checksum, which precedes the payload")
+	}
+	if w.checksummer != nil {
+		return fmt.Errorf("AppendStream cannot write checksum algorithm %d, only SHA-256", w.checksummer.ID())
+	}
+	if w.hashChain {
+		return fmt.Errorf("AppendStream cannot link records into a hash chain")
+	}
+	if w.appendSink != nil {
+		return fmt.Errorf("AppendStream cannot pass records to an append sink")
+	}
+	return nil
+}
+
+func (w *S3WAL) uploadStreamParts(ctx context.Context, key string, uploadID *string, header []byte, r io.Reader) ([]types.CompletedPart, error) {
+	partSize := w.partSize
+	hash := sha256.New()
+	buf := make([]byte, partSize)
+	n := copy(buf, header)
+
+	var parts []types.CompletedPart
+	upload := func(body []byte) error {
+		partNumber := aws.Int32(int32(len(parts) + 1))
+		output, err := w.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(w.bucketName),
+			Key:        aws.String(key),
+			UploadId:   uploadID,
+			PartNumber: partNumber,
+			Body:       bytes.NewReader(body),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d: %w", *partNumber, err)
+		}
+		parts = append(parts, types.CompletedPart{ETag: output.ETag, PartNumber: partNumber})
+		return nil
+	}
+
+	for {
+		read, err := io.ReadFull(r, buf[n:])
+		n += read
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream: %w", err)
+		}
+		// the last part, holding at least the checksum, is still to come
+		if len(parts) == maxMultipartParts-1 {
+			return nil, fmt.Errorf("stream needs more than %d parts of %d bytes", maxMultipartParts, partSize)
+		}
+		hash.Write(buf[:n])
+		if err := upload(buf[:n]); err != nil {
+			return nil, err
+		}
+		n = 0
+	}
+	hash.Write(buf[:n])
+	last := hash.Sum(buf[:n])
+	if err := upload(last); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
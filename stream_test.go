@@ -0,0 +1,155 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// patternReader yields n bytes of a repeating pattern without exposing its
+// length, like an unbounded generator cut off at n.
+type patternReader struct {
+	n, pos int
+}
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	if r.pos >= r.n {
+		return 0, io.EOF
+	}
+	m := min(len(p), r.n-r.pos, 4096)
+	for i := 0; i < m; i++ {
+		p[i] = byte((r.pos + i) % 251)
+	}
+	r.pos += m
+	return m, nil
+}
+
+func TestAppendStream(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, size := range []int{0, 100, minMultipartPartSize - 8, 2*minMultipartPartSize + 12345} {
+		offset, err := wal.AppendStream(ctx, &patternReader{n: size})
+		if err != nil {
+			t.Fatalf("size %d: failed to append stream: %v", size, err)
+		}
+
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("size %d: failed to read: %v", size, err)
+		}
+		expected, _ := io.ReadAll(&patternReader{n: size})
+		if !bytes.Equal(record.Data, expected) {
+			t.Errorf("size %d: data mismatch, got %d bytes", size, len(record.Data))
+		}
+	}
+
+	offset, err := wal.Append(ctx, []byte("after stream"))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if offset != 5 {
+		t.Errorf("expected offset 5, got %d", offset)
+	}
+}
+
+func TestAppendStreamSameOffset(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := wal.Append(ctx, []byte("first")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	wal.length = 0
+	if _, err := wal.AppendStream(ctx, bytes.NewReader([]byte("second"))); err == nil {
+		t.Error("expected error when streaming to an existing offset, got nil")
+	}
+}
+
+func TestAppendStreamUnsupportedFrames(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for name, opt := range map[string]Option{
+		"leading checksum": WithChecksumPosition(ChecksumLeading),
+		"crc32c":           WithChecksum(CRC32CChecksum),
+		"hash chain":       WithHashChain(),
+		"append sink":      WithAppendSink(func(context.Context, uint64, []byte) error { return nil }, false),
+	} {
+		wal := NewS3WAL(base.client, base.bucketName, base.prefix, opt)
+		if _, err := wal.AppendStream(ctx, bytes.NewReader([]byte("data"))); err == nil {
+			t.Errorf("%s: expected AppendStream to fail", name)
+		}
+	}
+	if _, err := base.LastRecord(ctx); !errors.Is(err, ErrWALEmpty) {
+		t.Errorf("expected nothing to be written, got %v", err)
+	}
+}
+
+func TestAppendStreamTimestampsAndMetrics(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	metrics := &counters{}
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithTimestamps(), WithMetrics(metrics))
+	offset, err := wal.AppendStream(ctx, &patternReader{n: minMultipartPartSize + 100})
+	if err != nil {
+		t.Fatalf("failed to append stream: %v", err)
+	}
+	record, err := wal.Read(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if len(record.Data) != minMultipartPartSize+100 || record.Timestamp.IsZero() {
+		t.Errorf("expected %d bytes with a timestamp, got %d at %v", minMultipartPartSize+100, len(record.Data), record.Timestamp)
+	}
+	if got := metrics.get(MetricAppends); got != 1 {
+		t.Errorf("expected 1 append counted, got %d", got)
+	}
+}
+
+func TestWithMultipartPartSize(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	partSize := minMultipartPartSize + 1<<20
+	WithMultipartPartSize(partSize)(wal)
+	size := 2*partSize + 100
+	before := wal.UsageStats().PutRequests
+	offset, err := wal.AppendStream(ctx, &patternReader{n: size})
+	if err != nil {
+		t.Fatalf("failed to append stream: %v", err)
+	}
+	// create, three parts and complete
+	if puts := wal.UsageStats().PutRequests - before; puts != 5 {
+		t.Errorf("expected 5 put requests, got %d", puts)
+	}
+	record, err := wal.Read(ctx, offset)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	expected, _ := io.ReadAll(&patternReader{n: size})
+	if !bytes.Equal(record.Data, expected) {
+		t.Errorf("data mismatch, got %d bytes", len(record.Data))
+	}
+}
+
+func TestWithMultipartPartSizePanics(t *testing.T) {
+	for _, size := range []int{0, minMultipartPartSize - 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for part size %d", size)
+				}
+			}()
+			WithMultipartPartSize(size)
+		}()
+	}
+}
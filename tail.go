@@ -0,0 +1,170 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// TailMode selects how LastRecord and TailOffset find the end of the log.
+type TailMode int
+
+const (
+	// TailAuthoritative lists S3 on every call. It is accurate across any
+	// number of writers and is the default.
+	TailAuthoritative TailMode = iota
+	// TailCached trusts the in-memory length, costing no list requests. The
+	// cached tail only reflects appends made through this S3WAL and the
+	// result of the last authoritative scan, so records appended by other
+	// writers stay invisible until the next authoritative call; the staleness
+	// window is unbounded. While nothing is cached yet (length 0) it falls
+	// back to listing.
+	TailCached
+)
+
+// WithTailMode sets the TailMode used by LastRecord and TailOffset.
+func WithTailMode(mode TailMode) Option {
+	return func(w *S3WAL) {
+		w.tailMode = mode
+	}
+}
+
+// cachedTail returns the in-memory length if it may be used as the tail.
+func (w *S3WAL) cachedTail() (uint64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.length, w.tailMode == TailCached && w.length > 0
+}
+
+// TailOffset returns the offset of the last record in the log, or fails with
+// ErrWALEmpty if the log has no records. If Truncate has deleted every
+// record, it returns the last deleted offset, so that appends do not reuse
+// truncated offsets.
+func (w *S3WAL) TailOffset(ctx context.Context) (uint64, error) {
+	if length, ok := w.cachedTail(); ok {
+		return length, nil
+	}
+	if tail, ok, err := w.indexedTail(ctx); err != nil || ok {
+		if err != nil {
+			return 0, err
+		}
+		w.setLength(tail)
+		return tail, nil
+	}
+	end, err := w.listTail(ctx)
+	if err != nil {
+		return 0, err
+	}
+	w.setLength(end)
+	w.updateIndex(ctx, end)
+	return end, nil
+}
+
+// listTail finds the offset of the last record by listing the log, or by
+// probing it with WithProbeTailDiscovery. A log emptied by Truncate ends at
+// the last truncated offset.
+func (w *S3WAL) listTail(ctx context.Context) (uint64, error) {
+	maxOffset, err := w.lastObject(ctx)
+	if errors.Is(err, ErrWALEmpty) {
+		return w.truncatedTail(ctx)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return w.objectEnd(ctx, maxOffset)
+}
+
+// lastObject returns the first offset of the last object of the log, found
+// by listing the log or by probing it with WithProbeTailDiscovery.
+func (w *S3WAL) lastObject(ctx context.Context) (uint64, error) {
+	if w.probeTail {
+		return w.probeLastObject(ctx, false)
+	}
+	if w.binarySearchTail {
+		maxOffset, err := w.probeLastObject(ctx, true)
+		if err == nil {
+			return maxOffset, nil
+		}
+		if !errors.Is(err, errLogGap) && !errors.Is(err, ErrWALEmpty) {
+			return 0, err
+		}
+		w.logger.Debug("s3log: falling back to listing the log", "reason", err)
+	}
+	var maxOffset uint64 = 0
+	err := w.forEachObject(ctx, func(offset uint64, _ types.Object) error {
+		if offset > maxOffset {
+			maxOffset = offset
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if maxOffset == 0 {
+		return 0, ErrWALEmpty
+	}
+	return maxOffset, nil
+}
+
+// Reconcile lists the log and resets the in-memory length to its actual
+// tail, which it returns, or 0 for an empty log. Unlike the other tail
+// lookups it may lower the length, when records at the end of the log were
+// deleted behind this S3WAL's back, e.g. by a lifecycle rule or another
+// process; otherwise the next append would leave a gap and reads of the
+// cached tail would fail. Appends wait while Reconcile lists.
+//
+// Call it when a read of the tail under TailCached fails with NoSuchKey, or
+// periodically if lifecycle rules expire records. The tail recorded in an
+// OffsetIndex only moves forward, so Reconcile does not lower it.
+func (w *S3WAL) Reconcile(ctx context.Context) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tail, err := w.listTail(ctx)
+	if err != nil && !errors.Is(err, ErrWALEmpty) {
+		return 0, err
+	}
+	w.length = tail
+	return tail, nil
+}
+
+// TailInfo returns the smallest and largest offsets in the log and the number
+// of records it holds, from a single listing pass. The offsets need not be
+// contiguous. Every record of a micro-batch is counted, so each object
+// followed by a gap in the listing, and the last one, is inspected with a
+// HeadObject request to find where it ends.
+func (w *S3WAL) TailInfo(ctx context.Context) (first, last, records uint64, err error) {
+	var prev uint64
+	// count adds the records of the object at prev, which next follows
+	count := func(next uint64) error {
+		end := prev
+		if next != prev+1 {
+			var err error
+			if end, err = w.objectEnd(ctx, prev); err != nil {
+				return err
+			}
+		}
+		records += end - prev + 1
+		last = end
+		return nil
+	}
+	err = w.forEachObject(ctx, func(offset uint64, _ types.Object) error {
+		if prev == 0 {
+			first = offset
+		} else if err := count(offset); err != nil {
+			return err
+		}
+		prev = offset
+		return nil
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if prev == 0 {
+		return 0, 0, 0, ErrWALEmpty
+	}
+	if err := count(0); err != nil {
+		return 0, 0, 0, err
+	}
+	return first, last, records, nil
+}
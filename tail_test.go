@@ -0,0 +1,129 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestTailMode(t *testing.T) {
+	writer, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := writer.TailOffset(ctx); !errors.Is(err, ErrWALEmpty) {
+		t.Errorf("expected ErrWALEmpty for tail of empty WAL, got %v", err)
+	}
+
+	cached := NewS3WAL(writer.client, writer.bucketName, writer.prefix, WithTailMode(TailCached))
+	authoritative := NewS3WAL(writer.client, writer.bucketName, writer.prefix)
+
+	if _, err := writer.Append(ctx, []byte("one")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	// nothing is cached yet, so the cached reader lists once
+	if tail, err := cached.TailOffset(ctx); err != nil || tail != 1 {
+		t.Fatalf("expected tail 1, got %d (%v)", tail, err)
+	}
+
+	if _, err := writer.AppendMicroBatch(ctx, [][]byte{[]byte("two"), []byte("three")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+
+	lists := cached.UsageStats().ListRequests
+	if tail, err := cached.TailOffset(ctx); err != nil || tail != 1 {
+		t.Errorf("expected stale cached tail 1, got %d (%v)", tail, err)
+	}
+	record, err := cached.LastRecord(ctx)
+	if err != nil || record.Offset != 1 {
+		t.Errorf("expected stale cached last record 1, got %d (%v)", record.Offset, err)
+	}
+	if cached.UsageStats().ListRequests != lists {
+		t.Error("cached tail mode issued a list request")
+	}
+
+	if tail, err := authoritative.TailOffset(ctx); err != nil || tail != 3 {
+		t.Errorf("expected authoritative tail 3, got %d (%v)", tail, err)
+	}
+	record, err = authoritative.LastRecord(ctx)
+	if err != nil || record.Offset != 3 || string(record.Data) != "three" {
+		t.Errorf("expected authoritative last record 3, got %+v (%v)", record, err)
+	}
+}
+
+func TestTailInfo(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, _, _, err := wal.TailInfo(ctx); err == nil {
+		t.Error("expected error for empty WAL, got nil")
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := wal.Append(ctx, []byte("record")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+	_, err := setupMinioClient().DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(wal.getObjectKey(1)),
+	})
+	if err != nil {
+		t.Fatalf("failed to delete record: %v", err)
+	}
+
+	lists := wal.UsageStats().ListRequests
+	first, last, records, err := wal.TailInfo(ctx)
+	if err != nil {
+		t.Fatalf("failed to get tail info: %v", err)
+	}
+	if first != 2 || last != 5 || records != 4 {
+		t.Errorf("expected (2, 5, 4), got (%d, %d, %d)", first, last, records)
+	}
+	if n := wal.UsageStats().ListRequests - lists; n != 1 {
+		t.Errorf("expected a single list request, got %d", n)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	WithTailMode(TailCached)(wal)
+
+	if tail, err := wal.Reconcile(ctx); err != nil || tail != 0 {
+		t.Errorf("expected tail 0 for an empty log, got %d (%v)", tail, err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := wal.Append(ctx, []byte("record")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	// expire the last two records behind the WAL's back
+	for _, offset := range []uint64{3, 4} {
+		if err := wal.deleteObject(ctx, offset); err != nil {
+			t.Fatalf("failed to delete offset %d: %v", offset, err)
+		}
+	}
+	var nsk *types.NoSuchKey
+	if _, err := wal.LastRecord(ctx); !errors.As(err, &nsk) {
+		t.Fatalf("expected the cached tail to be gone, got %v", err)
+	}
+	if tail, err := wal.Reconcile(ctx); err != nil || tail != 2 {
+		t.Fatalf("expected tail 2, got %d (%v)", tail, err)
+	}
+	if record, err := wal.LastRecord(ctx); err != nil || record.Offset != 2 {
+		t.Errorf("expected last record 2, got %d (%v)", record.Offset, err)
+	}
+	if offset, err := wal.Append(ctx, []byte("record")); err != nil || offset != 3 {
+		t.Errorf("expected the next append at 3, got %d (%v)", offset, err)
+	}
+}
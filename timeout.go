@@ -0,0 +1,119 @@
+package s3log
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithDefaultTimeout bounds every S3 request made with a context that has no
+// deadline to d, so that a hung request fails instead of stalling the caller
+// forever. Contexts that already have a deadline are left alone. The timeout
+// applies to each request separately, e.g. to each page of a listing and each
+// part of a multipart upload, and for GetObject it also covers reading the
+// body. Zero, the default, disables it.
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(w *S3WAL) {
+		w.defaultTimeout = d
+	}
+}
+
+// timeoutClient applies the default timeout of an S3WAL to the requests it
+// passes to the wrapped client. It reads the timeout on every request, since
+// it is wrapped before options are applied.
+type timeoutClient struct {
+	S3Client
+	timeout *time.Duration
+}
+
+func (c timeoutClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || *c.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, *c.timeout)
+}
+
+// cancelOnClose releases the context of a GetObject request once its body is
+// closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func (c timeoutClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.PutObject(ctx, params, optFns...)
+}
+
+func (c timeoutClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	output, err := c.S3Client.GetObject(ctx, params, optFns...)
+	if err != nil {
+		cancel()
+		return output, err
+	}
+	output.Body = cancelOnClose{ReadCloser: output.Body, cancel: cancel}
+	return output, nil
+}
+
+func (c timeoutClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.HeadObject(ctx, params, optFns...)
+}
+
+func (c timeoutClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func (c timeoutClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.CreateMultipartUpload(ctx, params, optFns...)
+}
+
+func (c timeoutClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.UploadPart(ctx, params, optFns...)
+}
+
+func (c timeoutClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.CompleteMultipartUpload(ctx, params, optFns...)
+}
+
+func (c timeoutClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.AbortMultipartUpload(ctx, params, optFns...)
+}
+
+func (c timeoutClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.CopyObject(ctx, params, optFns...)
+}
+
+func (c timeoutClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.DeleteObject(ctx, params, optFns...)
+}
+
+func (c timeoutClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.S3Client.DeleteObjects(ctx, params, optFns...)
+}
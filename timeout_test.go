@@ -0,0 +1,50 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// hangingClient blocks every GetObject until its context is done.
+type hangingClient struct {
+	S3Client
+}
+
+func (c hangingClient) GetObject(ctx context.Context, _ *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	wal := NewS3WAL(base.client, base.bucketName, base.prefix, WithDefaultTimeout(5*time.Second))
+	if _, err := wal.Append(ctx, []byte("data")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if record, err := wal.Read(ctx, 1); err != nil || string(record.Data) != "data" {
+		t.Fatalf("expected to read data, got %q (%v)", record.Data, err)
+	}
+
+	hung := NewS3WAL(hangingClient{base.client}, base.bucketName, base.prefix, WithDefaultTimeout(20*time.Millisecond))
+	if _, err := hung.Read(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// an existing deadline is not shortened
+	deadlineCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := hung.Read(deadlineCtx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected the caller's deadline to apply, returned after %v", elapsed)
+	}
+}
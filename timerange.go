@@ -0,0 +1,161 @@
+package s3log
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// errNotTimeOrdered is returned by searchTimeRange when the log cannot be
+// binary-searched by time.
+var errNotTimeOrdered = errors.New("log is not ordered by timestamp")
+
+// WithTimestamps stores the append time in the header of every record written
+// with Append or AppendWithSchema. It is returned as Record.Timestamp.
+func WithTimestamps() Option {
+	return func(w *S3WAL) {
+		w.timestamps = true
+	}
+}
+
+// readTimestamp fetches only the header of the record at offset and returns
+// its timestamp. The header is not covered by a checksum until the whole
+// record is read, so the result is only used to steer searches.
+func (w *S3WAL) readTimestamp(ctx context.Context, offset uint64) (time.Time, error) {
+	result, err := w.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(w.bucketName),
+		Key:    aws.String(w.getObjectKey(offset)),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", 3+maxChecksumSize+8+8-1)),
+	})
+	var nsk *types.NoSuchKey
+	if errors.As(err, &nsk) {
+		return time.Time{}, errNotTimeOrdered
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get object from s3: %w", err)
+	}
+	defer result.Body.Close()
+
+	header, err := io.ReadAll(result.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read object body: %w", err)
+	}
+	if len(header) < 2 || result.Metadata[metaNoOffsetHeader] != "" || header[0] != frameMagic || frameFlags(header[1])&flagTimestamp == 0 {
+		return time.Time{}, errNotTimeOrdered
+	}
+	// the timestamp follows the prefix, any leading checksum and the offset
+	_, n, pos, err := frameChecksum(header)
+	if err != nil {
+		return time.Time{}, errNotTimeOrdered
+	}
+	if frameFlags(header[1])&flagLeadingChecksum != 0 {
+		pos += n
+	}
+	pos += offsetHeaderSize
+	if len(header) < pos+8 {
+		return time.Time{}, errNotTimeOrdered
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(header[pos:pos+8]))).UTC(), nil
+}
+
+// ReadByTimeRange returns the records whose timestamps fall within [from, to],
+// in time order.
+//
+// Under normal appends offsets are ordered by time, so the boundaries of the
+// range are found by binary search over record headers, reading only the
+// records inside the range. If a probe hits a record without a timestamp or a
+// missing offset, or the records found are not in time order, the log is
+// assumed not to be monotonic and ReadByTimeRange falls back to a linear scan
+// of the whole log.
+func (w *S3WAL) ReadByTimeRange(ctx context.Context, from, to time.Time) ([]Record, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("invalid time range: %v is before %v", to, from)
+	}
+	tail, err := w.TailOffset(ctx)
+	if err != nil {
+		return nil, err
+	}
+	records, err := w.searchTimeRange(ctx, from, to, tail)
+	if errors.Is(err, errNotTimeOrdered) {
+		return w.scanTimeRange(ctx, from, to)
+	}
+	return records, err
+}
+
+func (w *S3WAL) searchTimeRange(ctx context.Context, from, to time.Time, tail uint64) ([]Record, error) {
+	// search returns the smallest offset in [lo, tail] for which after
+	// reports true, or tail+1 if there is none
+	search := func(lo uint64, after func(time.Time) bool) (uint64, error) {
+		hi := tail + 1
+		for lo < hi {
+			mid := lo + (hi-lo)/2
+			ts, err := w.readTimestamp(ctx, mid)
+			if err != nil {
+				return 0, err
+			}
+			if after(ts) {
+				hi = mid
+			} else {
+				lo = mid + 1
+			}
+		}
+		return lo, nil
+	}
+	start, err := search(1, func(ts time.Time) bool { return !ts.Before(from) })
+	if err != nil {
+		return nil, err
+	}
+	end, err := search(start, func(ts time.Time) bool { return ts.After(to) })
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for offset := start; offset < end; offset++ {
+		record, err := w.Read(ctx, offset)
+		if err != nil {
+			return nil, err
+		}
+		if record.Timestamp.Before(from) || record.Timestamp.After(to) {
+			return nil, errNotTimeOrdered
+		}
+		if len(records) > 0 && record.Timestamp.Before(records[len(records)-1].Timestamp) {
+			return nil, errNotTimeOrdered
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (w *S3WAL) scanTimeRange(ctx context.Context, from, to time.Time) ([]Record, error) {
+	var records []Record
+	token := ""
+	for {
+		page, next, err := w.ReadPage(ctx, token, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range page {
+			if record.Timestamp.IsZero() || record.Timestamp.Before(from) || record.Timestamp.After(to) {
+				continue
+			}
+			records = append(records, record)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	return records, nil
+}
@@ -0,0 +1,85 @@
+package s3log
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func appendAt(t *testing.T, wal *S3WAL, ts time.Time) uint64 {
+	t.Helper()
+	wal.now = func() time.Time { return ts }
+	offset, err := wal.Append(context.Background(), []byte(ts.Format(time.RFC3339)))
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	return offset
+}
+
+func TestReadByTimeRange(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	WithTimestamps()(wal)
+	ctx := context.Background()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		appendAt(t, wal, base.Add(time.Duration(i)*time.Minute))
+	}
+
+	record, err := wal.Read(ctx, 4)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if !record.Timestamp.Equal(base.Add(3 * time.Minute)) {
+		t.Errorf("unexpected timestamp %v", record.Timestamp)
+	}
+
+	gets := wal.UsageStats().GetRequests
+	records, err := wal.ReadByTimeRange(ctx, base.Add(3*time.Minute), base.Add(6*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to read by time range: %v", err)
+	}
+	if len(records) != 4 || records[0].Offset != 4 || records[3].Offset != 7 {
+		t.Errorf("unexpected records: %+v", records)
+	}
+	// two binary searches over 10 offsets plus the 4 records themselves
+	if n := wal.UsageStats().GetRequests - gets; n > 12 {
+		t.Errorf("expected a binary search, but issued %d gets", n)
+	}
+
+	records, err = wal.ReadByTimeRange(ctx, base.Add(time.Hour), base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to read by time range: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("expected no records, got %d", len(records))
+	}
+}
+
+func TestReadByTimeRangeNonMonotonic(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	WithTimestamps()(wal)
+	ctx := context.Background()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	appendAt(t, wal, base.Add(1*time.Minute))
+	appendAt(t, wal, base.Add(5*time.Minute))
+	// a writer with a skewed clock
+	appendAt(t, wal, base.Add(2*time.Minute))
+	appendAt(t, wal, base.Add(6*time.Minute))
+	appendAt(t, wal, base.Add(3*time.Minute))
+
+	records, err := wal.ReadByTimeRange(ctx, base.Add(2*time.Minute), base.Add(5*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to read by time range: %v", err)
+	}
+	var offsets []uint64
+	for _, record := range records {
+		offsets = append(offsets, record.Offset)
+	}
+	if len(offsets) != 3 || offsets[0] != 3 || offsets[1] != 5 || offsets[2] != 2 {
+		t.Errorf("expected offsets [3 5 2] in time order, got %v", offsets)
+	}
+}
@@ -0,0 +1,132 @@
+package s3log
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// transitionConcurrency bounds the number of CopyObject requests in flight
+// during TransitionStorageClass.
+const transitionConcurrency = 8
+
+// TransitionProgress reports how far TransitionStorageClass has got.
+type TransitionProgress struct {
+	// Total is the number of objects that need to change storage class.
+	Total int
+	// Transitioned is the number of those objects copied so far.
+	Transitioned int
+	// Skipped is the number of objects already in the target class.
+	Skipped int
+}
+
+// TransitionStorageClass moves every object of the log, including the chunks of
+// chunked records, to the given storage class by copying each object onto
+// itself, which is how S3 changes the class of an existing object without
+// rewriting it from the client. Objects already in the target class are
+// skipped, so the operation is idempotent and can be resumed after a failure.
+// If progress is not nil it is called after every copy.
+func (w *S3WAL) TransitionStorageClass(ctx context.Context, class types.StorageClass, progress func(TransitionProgress)) (TransitionProgress, error) {
+	var state TransitionProgress
+	var keys []string
+	collect := func(obj types.Object) {
+		current := string(obj.StorageClass)
+		if current == "" {
+			current = string(types.StorageClassStandard)
+		}
+		if current == string(class) {
+			state.Skipped++
+			return
+		}
+		keys = append(keys, *obj.Key)
+	}
+	err := w.forEachObject(ctx, func(_ uint64, obj types.Object) error {
+		collect(obj)
+		return nil
+	})
+	if err != nil {
+		return state, err
+	}
+	paginator := s3.NewListObjectsV2Paginator(w.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(w.bucketName),
+		Prefix: aws.String(w.prefix + ".chunks/"),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return state, fmt.Errorf("failed to list objects from s3: %w", err)
+		}
+		for _, obj := range output.Contents {
+			collect(obj)
+		}
+	}
+	state.Total = len(keys)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, transitionConcurrency)
+	for _, key := range keys {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := w.copyWithStorageClass(ctx, key, class)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			state.Transitioned++
+			if progress != nil {
+				progress(state)
+			}
+		}(key)
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return state, firstErr
+}
+
+func (w *S3WAL) copyWithStorageClass(ctx context.Context, key string, class types.StorageClass) error {
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(w.bucketName),
+		Key:               aws.String(key),
+		CopySource:        aws.String(w.bucketName + "/" + url.PathEscape(key)),
+		StorageClass:      class,
+		MetadataDirective: types.MetadataDirectiveCopy,
+	}
+	if w.kmsKeyID != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(w.kmsKeyID)
+	}
+	if w.bucketKey {
+		input.BucketKeyEnabled = aws.Bool(true)
+	}
+	if _, err := w.client.CopyObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to copy %s to storage class %s: %w", key, class, err)
+	}
+	return nil
+}
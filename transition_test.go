@@ -0,0 +1,54 @@
+package s3log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestTransitionStorageClass(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := wal.Append(ctx, []byte("aging record")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := wal.AppendMicroBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatalf("failed to append micro-batch: %v", err)
+	}
+
+	var calls int
+	state, err := wal.TransitionStorageClass(ctx, types.StorageClassReducedRedundancy, func(TransitionProgress) { calls++ })
+	if err != nil {
+		t.Fatalf("failed to transition: %v", err)
+	}
+	if state.Total != 6 || state.Transitioned != 6 || state.Skipped != 0 || calls != 6 {
+		t.Errorf("unexpected progress: %+v after %d callbacks", state, calls)
+	}
+
+	stats, err := wal.Stats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.BytesByStorageClass[string(types.StorageClassReducedRedundancy)] != stats.Bytes {
+		t.Errorf("not every object was transitioned: %v", stats.BytesByStorageClass)
+	}
+
+	// the copy keeps the micro-batch metadata
+	record, err := wal.Read(ctx, 7)
+	if err != nil || string(record.Data) != "b" {
+		t.Errorf("failed to read transitioned micro-batch record: %q (%v)", record.Data, err)
+	}
+
+	state, err = wal.TransitionStorageClass(ctx, types.StorageClassReducedRedundancy, nil)
+	if err != nil {
+		t.Fatalf("failed to transition again: %v", err)
+	}
+	if state.Total != 0 || state.Skipped != 6 {
+		t.Errorf("expected transition to be idempotent, got %+v", state)
+	}
+}
@@ -0,0 +1,80 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// truncationMarker is the name of the object holding the first offset kept
+// by Truncate, stored under the log prefix. It does not end in digits, so
+// listings never take it for a record.
+const truncationMarker = "truncated"
+
+// truncationRecheckInterval is how long truncationError trusts its last read
+// of the truncation marker, so that readers polling for offsets past the tail
+// do not pay for a marker request on every miss.
+const truncationRecheckInterval = time.Second
+
+func (w *S3WAL) truncationKey() string {
+	return w.keyPrefix() + truncationMarker
+}
+
+// noteTruncation raises the cached first kept offset to before.
+func (w *S3WAL) noteTruncation(before uint64) {
+	for {
+		current := w.truncatedBefore.Load()
+		if current >= before || w.truncatedBefore.CompareAndSwap(current, before) {
+			return
+		}
+	}
+}
+
+// truncationError turns err, the failure of reading offset, into an
+// ErrTruncated error if err is a NoSuchKey and Truncate has deleted offset.
+// Unless offset is already known to be truncated, this costs one request for
+// the marker written by Truncate, at most once per
+// truncationRecheckInterval, so a truncation by another process may take
+// that long to be reported. It must not take w.mu, since Read is called with
+// it held while appending.
+func (w *S3WAL) truncationError(ctx context.Context, offset uint64, err error) error {
+	var nsk *types.NoSuchKey
+	if !errors.As(err, &nsk) {
+		return err
+	}
+	checkedAt := time.Unix(0, w.truncationCheckedAt.Load())
+	if offset >= w.truncatedBefore.Load() && time.Since(checkedAt) >= truncationRecheckInterval {
+		before, _, found, markerErr := w.getMarker(ctx, w.truncationKey())
+		if markerErr != nil {
+			w.logger.Debug("s3log: failed to read truncation marker", "error", markerErr)
+			return err
+		}
+		w.truncationCheckedAt.Store(time.Now().UnixNano())
+		if !found {
+			return err
+		}
+		w.noteTruncation(before)
+	}
+	if before := w.truncatedBefore.Load(); offset < before {
+		return fmt.Errorf("%w: offset %d is below the first kept offset %d", ErrTruncated, offset, before)
+	}
+	return err
+}
+
+// truncatedTail returns the last offset deleted by Truncate, from the marker,
+// or ErrWALEmpty if the log was never truncated. It is the tail of a log that
+// Truncate has emptied, which appends must continue after.
+func (w *S3WAL) truncatedTail(ctx context.Context) (uint64, error) {
+	before, _, found, err := w.getMarker(ctx, w.truncationKey())
+	if err != nil {
+		return 0, err
+	}
+	if !found || before <= 1 {
+		return 0, ErrWALEmpty
+	}
+	w.noteTruncation(before)
+	return before - 1, nil
+}
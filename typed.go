@@ -0,0 +1,58 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxReadAllRecords is the largest number of records ReadAllTyped loads. It
+// guards against accidentally loading a large log into memory.
+const MaxReadAllRecords = 1 << 20
+
+// ReadAllTyped reads every record of w in offset order and decodes each
+// payload with decode. An empty log yields an empty slice. Decoding failures
+// wrap ErrCodec and name the offset that failed.
+//
+// Only the WAL interface is used: records are read one at a time from the
+// first offset up to the offset of LastRecord. The first offset is 1, or that
+// of FirstRecord if w has such a method, as S3WAL does. A log holding more
+// than MaxReadAllRecords offsets is refused before anything is read.
+func ReadAllTyped[T any](ctx context.Context, w WAL, decode func([]byte) (T, error)) ([]T, error) {
+	last, err := w.LastRecord(ctx)
+	if errors.Is(err, ErrWALEmpty) {
+		return []T{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	first := uint64(1)
+	if f, ok := w.(interface {
+		FirstRecord(ctx context.Context) (Record, error)
+	}); ok {
+		record, err := f.FirstRecord(ctx)
+		if err != nil {
+			return nil, err
+		}
+		first = record.Offset
+	}
+	if n := last.Offset - first + 1; n > MaxReadAllRecords {
+		return nil, fmt.Errorf("log holds %d records, more than the %d ReadAllTyped loads", n, MaxReadAllRecords)
+	}
+
+	values := make([]T, 0, last.Offset-first+1)
+	for offset := first; offset <= last.Offset; offset++ {
+		record := last
+		if offset != last.Offset {
+			if record, err = w.Read(ctx, offset); err != nil {
+				return nil, err
+			}
+		}
+		value, err := decode(record.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to decode record %d: %w", ErrCodec, offset, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,54 @@
+package s3log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testEvent struct {
+	Name string `json:"name"`
+}
+
+func decodeTestEvent(data []byte) (testEvent, error) {
+	var e testEvent
+	err := json.Unmarshal(data, &e)
+	return e, err
+}
+
+func TestReadAllTyped(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	events, err := ReadAllTyped(ctx, wal, decodeTestEvent)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected no events from an empty log, got %v (%v)", events, err)
+	}
+
+	for _, name := range []string{"dropped", "created", "updated"} {
+		if _, err := wal.Append(ctx, []byte(`{"name":"`+name+`"}`)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if err := wal.deleteObject(ctx, 1); err != nil {
+		t.Fatalf("failed to delete record: %v", err)
+	}
+	events, err = ReadAllTyped(ctx, wal, decodeTestEvent)
+	if err != nil {
+		t.Fatalf("failed to read events: %v", err)
+	}
+	if len(events) != 2 || events[0].Name != "created" || events[1].Name != "updated" {
+		t.Errorf("expected created and updated, got %v", events)
+	}
+
+	if _, err := wal.Append(ctx, []byte("not json")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	_, err = ReadAllTyped(ctx, wal, decodeTestEvent)
+	if !errors.Is(err, ErrCodec) || !strings.Contains(err.Error(), "record 4") {
+		t.Errorf("expected ErrCodec naming record 4, got %v", err)
+	}
+}
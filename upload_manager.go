@@ -0,0 +1,93 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithUploadManager uploads records whose frame is larger than partSize, and
+// every record appended with AppendStream, with the SDK's manager.Uploader,
+// which sends up to concurrency parts at a time and retries failed parts.
+// Smaller records keep using a single PutObject. The uploaded bytes are the
+// same frame Append would write, so the checksum still covers the offset
+// header and the payload. For AppendStream, partSize replaces the part size
+// set by WithMultipartPartSize. WithUploadManager panics if partSize is below the
+// 5 MiB minimum part size.
+func WithUploadManager(concurrency int, partSize int64) Option {
+	if partSize < manager.MinUploadPartSize {
+		panic(fmt.Sprintf("s3log: upload manager part size %d is below the minimum of %d", partSize, manager.MinUploadPartSize))
+	}
+	return func(w *S3WAL) {
+		w.uploader = manager.NewUploader(w.client, func(u *manager.Uploader) {
+			u.Concurrency = concurrency
+			u.PartSize = partSize
+		})
+	}
+}
+
+// useUploader reports whether a frame of size bytes is uploaded with the
+// upload manager.
+func (w *S3WAL) useUploader(size int) bool {
+	return w.uploader != nil && int64(size) > w.uploader.PartSize
+}
+
+// upload writes input with the upload manager.
+func (w *S3WAL) upload(ctx context.Context, input *s3.PutObjectInput) error {
+	if _, err := w.uploader.Upload(ctx, input); err != nil {
+		return fmt.Errorf("failed to upload object to S3: %w", err)
+	}
+	return nil
+}
+
+// appendStreamUploader is AppendStream for WithUploadManager. It must be
+// called with mu held.
+func (w *S3WAL) appendStreamUploader(ctx context.Context, offset uint64, header []byte, r io.Reader) (uint64, error) {
+	key := w.getObjectKey(offset)
+	ifNoneMatch, err := w.ifNoneMatch(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	h := sha256.New()
+	h.Write(header)
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(w.bucketName),
+		Key:         aws.String(key),
+		Body:        io.MultiReader(bytes.NewReader(header), io.TeeReader(r, h), &checksumTrailer{hash: h}),
+		IfNoneMatch: ifNoneMatch,
+	}
+	w.applyPutOptions(input)
+	if err := w.upload(ctx, input); err != nil {
+		return 0, err
+	}
+	w.length = offset
+	w.updateIndex(ctx, offset)
+	w.bloomAdd(offset, offset)
+	return offset, nil
+}
+
+// checksumTrailer yields the sum of hash once everything before it in a
+// stream has been read through the hash.
+type checksumTrailer struct {
+	hash hash.Hash
+	sum  []byte
+}
+
+func (t *checksumTrailer) Read(p []byte) (int, error) {
+	if t.sum == nil {
+		t.sum = t.hash.Sum(nil)
+	}
+	if len(t.sum) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, t.sum)
+	t.sum = t.sum[n:]
+	return n, nil
+}
@@ -0,0 +1,58 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestWithUploadManager(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+	WithUploadManager(3, minMultipartPartSize)(wal)
+
+	small := []byte("small record")
+	large, _ := io.ReadAll(&patternReader{n: 2*minMultipartPartSize + 1000})
+	puts := wal.UsageStats().PutRequests
+	if _, err := wal.Append(ctx, small); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if n := wal.UsageStats().PutRequests - puts; n != 1 {
+		t.Errorf("expected a single put for a small record, got %d", n)
+	}
+	puts = wal.UsageStats().PutRequests
+	if _, err := wal.Append(ctx, large); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	// create, three parts and complete
+	if n := wal.UsageStats().PutRequests - puts; n != 5 {
+		t.Errorf("expected a multipart upload for a large record, got %d puts", n)
+	}
+	if _, err := wal.AppendStream(ctx, &patternReader{n: len(large)}); err != nil {
+		t.Fatalf("failed to append stream: %v", err)
+	}
+
+	for offset, expected := range map[uint64][]byte{1: small, 2: large, 3: large} {
+		record, err := wal.Read(ctx, offset)
+		if err != nil {
+			t.Fatalf("failed to read offset %d: %v", offset, err)
+		}
+		if !bytes.Equal(record.Data, expected) {
+			t.Errorf("offset %d: data mismatch, got %d bytes", offset, len(record.Data))
+		}
+	}
+
+	wal.length = 1
+	if _, err := wal.Append(ctx, large); err == nil {
+		t.Error("expected error when uploading to an existing offset, got nil")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a part size below the minimum to panic")
+		}
+	}()
+	WithUploadManager(1, minMultipartPartSize-1)
+}
@@ -0,0 +1,53 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+)
+
+// ReadSummary is the result of ReadOrderedVerified.
+type ReadSummary struct {
+	// Records is the number of valid records passed to the handler.
+	Records int
+	// Failures lists the corrupt records, in offset order.
+	Failures []Anomaly
+}
+
+// ReadOrderedVerified is ReadOrdered that doubles as an integrity sweep of
+// [start, end], so that a full replay does not need a separate
+// VerifyIntegrity pass. Read already validates every record it downloads;
+// here a record that fails validation does not stop the read but is
+// reported as an AnomalyCorruptRecord to onFailure, if set, and in the
+// returned summary, and handler only sees valid records. If onFailure
+// returns an error the read stops with it. Other errors, such as a missing
+// offset, stop the read as in ReadOrdered.
+func (w *S3WAL) ReadOrderedVerified(ctx context.Context, start, end uint64, handler func(Record) error, onFailure func(Anomaly) error) (ReadSummary, error) {
+	var summary ReadSummary
+	count := func(record Record) error {
+		summary.Records++
+		return handler(record)
+	}
+	report := func(offset uint64, err error) error {
+		if !isCorruption(err) {
+			return err
+		}
+		anomaly := Anomaly{
+			Kind:   AnomalyCorruptRecord,
+			Offset: offset,
+			Keys:   []string{w.getObjectKey(offset)},
+			Err:    err,
+		}
+		summary.Failures = append(summary.Failures, anomaly)
+		if onFailure != nil {
+			return onFailure(anomaly)
+		}
+		return nil
+	}
+	err := w.readOrdered(ctx, start, end, count, report)
+	return summary, err
+}
+
+// isCorruption reports whether err is a record failing validation.
+func isCorruption(err error) bool {
+	return errors.Is(err, ErrInvalidRecord) || errors.Is(err, ErrChecksumMismatch) || errors.Is(err, ErrBrokenChain)
+}
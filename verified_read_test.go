@@ -0,0 +1,63 @@
+package s3log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestReadOrderedVerified(t *testing.T) {
+	wal, cleanup := getWAL(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		if _, err := wal.Append(ctx, []byte(fmt.Sprintf("record %d", i))); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	body, _ := prepareBody(3, []byte("three"))
+	body[len(body)-1] ^= 0xff
+	_, err := wal.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(wal.bucketName),
+		Key:    aws.String(wal.getObjectKey(3)),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		t.Fatalf("failed to corrupt record: %v", err)
+	}
+
+	if err := wal.ReadOrdered(ctx, 1, 5, func(Record) error { return nil }); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ReadOrdered to fail with ErrChecksumMismatch, got %v", err)
+	}
+
+	var handled []uint64
+	var reported []uint64
+	summary, err := wal.ReadOrderedVerified(ctx, 1, 5, func(r Record) error {
+		handled = append(handled, r.Offset)
+		return nil
+	}, func(a Anomaly) error {
+		reported = append(reported, a.Offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if fmt.Sprint(handled) != "[1 2 4 5]" || fmt.Sprint(reported) != "[3]" {
+		t.Errorf("expected records [1 2 4 5] and failure [3], got %v and %v", handled, reported)
+	}
+	if summary.Records != 4 || len(summary.Failures) != 1 || summary.Failures[0].Kind != AnomalyCorruptRecord || !errors.Is(summary.Failures[0].Err, ErrChecksumMismatch) {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	stop := errors.New("stop")
+	summary, err = wal.ReadOrderedVerified(ctx, 1, 5, func(Record) error { return nil }, func(Anomaly) error { return stop })
+	if !errors.Is(err, stop) || summary.Records != 2 {
+		t.Errorf("expected the read to stop at the failure, got %v after %d records", err, summary.Records)
+	}
+}
@@ -1,10 +1,107 @@
 package s3log
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidRecord is returned when a stored object is not a well-formed
+	// record, e.g. because it is too short or its header is malformed.
+	ErrInvalidRecord = errors.New("invalid record")
+	// ErrChecksumMismatch is returned when a record's stored checksum does
+	// not match its contents, i.e. the stored data is corrupt.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrTruncatedRead is returned when fewer bytes were received than the
+	// object's ContentLength, i.e. the body was cut short in transit.
+	ErrTruncatedRead = errors.New("truncated read")
+	// ErrUnexpectedLength is returned when more bytes were received than
+	// the object's ContentLength, e.g. because a proxy appended garbage.
+	ErrUnexpectedLength = errors.New("unexpected length")
+	// ErrUnknownSchema is returned when appending with a schema ID that is
+	// not in the allowlist configured by WithAllowedSchemas.
+	ErrUnknownSchema = errors.New("unknown schema")
+	// ErrMetadataTooLarge is returned when user metadata exceeds the S3
+	// limit of 2 KB per object.
+	ErrMetadataTooLarge = errors.New("metadata too large")
+	// ErrCodec is wrapped by errors from encoding or decoding payloads in
+	// the typed append and read helpers.
+	ErrCodec = errors.New("codec error")
+	// ErrDuplicateOffset is returned when listing the log finds two keys
+	// holding the same offset, e.g. in different key formats.
+	ErrDuplicateOffset = errors.New("duplicate offset")
+	// ErrClosed is returned when appending to a WAL after Close or Abort.
+	ErrClosed = errors.New("wal closed")
+	// ErrAccessDenied is returned when S3 rejects a request for lack of
+	// permission, e.g. because of a wrong IAM policy or bucket policy.
+	ErrAccessDenied = errors.New("access denied")
+	// ErrBrokenChain is returned by ValidateChain when a record does not
+	// reference the checksum of the record before it.
+	ErrBrokenChain = errors.New("broken hash chain")
+	// ErrWriteNotConfirmed is returned by AppendWithConcern when a record was
+	// uploaded but could not be confirmed at the requested WriteConcern.
+	ErrWriteNotConfirmed = errors.New("write not confirmed")
+	// ErrCompactionMismatch is returned by CompactAndVerify when a segment
+	// read back after writing does not hold the records merged into it.
+	ErrCompactionMismatch = errors.New("compacted segment does not match its sources")
+	// ErrRecordTooSmall and ErrRecordTooLarge are returned when appending a
+	// record outside the bounds set by WithRecordSizeBounds.
+	ErrRecordTooSmall = errors.New("record too small")
+	ErrRecordTooLarge = errors.New("record too large")
+	// ErrWALEmpty is returned when looking up the first or last record of a
+	// log that has none, e.g. by FirstRecord, LastRecord and TailOffset.
+	ErrWALEmpty = errors.New("WAL is empty")
+	// ErrSinkFailed is returned by appends when the sink set with
+	// WithAppendSink in strict mode fails on a record that was written.
+	ErrSinkFailed = errors.New("append sink failed")
+	// ErrSealed is returned by appends to a log sealed with Seal.
+	ErrSealed = errors.New("wal sealed")
+	// ErrUnresolvedDependency is returned by MergeCausal when a record's
+	// dependencies can never be satisfied.
+	ErrUnresolvedDependency = errors.New("unresolved dependency")
+	// ErrNoSnapshot is returned by LatestSnapshot when no snapshot has been
+	// appended.
+	ErrNoSnapshot = errors.New("no snapshot")
+	// ErrInvalidCursor is returned for a ReadPage token that is malformed or
+	// fails its signature check.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrTruncated is returned by Read for an offset deleted by Truncate.
+	ErrTruncated = errors.New("offset truncated")
+	// ErrUnsupportedChecksum is returned, along with ErrInvalidRecord, for a
+	// record written with a checksum algorithm the reader does not know, and
+	// by Read for one written with another algorithm than the configured one.
+	ErrUnsupportedChecksum = errors.New("unsupported checksum algorithm")
+	// ErrLeaseHeld is returned by Compact while another process holds the
+	// compaction lease, and by a pass whose lease was taken over.
+	ErrLeaseHeld = errors.New("lease held by another process")
+	// ErrSpillPending is returned by AppendMicroBatch and AppendStream while
+	// records spilled by WithLocalSpill are waiting to be drained, since they
+	// cannot be spilled themselves.
+	ErrSpillPending = errors.New("spilled records pending")
+)
 
 type Record struct {
-	Offset uint64
-	Data   []byte
+	Offset   uint64
+	Data     []byte
+	SchemaID string
+	// Timestamp is the append time, or zero if the record was written
+	// without WithTimestamps.
+	Timestamp time.Time
+	// Metadata is the user metadata attached with AppendWithMetadata.
+	Metadata map[string]string
+	// LastModified is the time S3 reports the record's object was last
+	// written, with second granularity, or zero if it is unknown, e.g.
+	// because the record was repaired from a replica.
+	LastModified time.Time
+	// PartitionKey is the key attached with AppendWithPartitionKey, if any.
+	PartitionKey string
+	// DependsOn lists the dependencies attached with AppendWithDependencies,
+	// sorted by log.
+	DependsOn []Dependency
+	// SnapshotOf is the offset covered by a snapshot appended with
+	// AppendSnapshot, or 0 if the record is not a snapshot.
+	SnapshotOf uint64
 }
 
 type WAL interface {
@@ -0,0 +1,79 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WriteConcern selects how much AppendWithConcern verifies before returning.
+type WriteConcern int
+
+const (
+	// WriteFast returns as soon as S3 has accepted the upload, like Append.
+	WriteFast WriteConcern = iota
+	// WriteConfirmed also reads the record back and checks that it matches
+	// what was written.
+	WriteConfirmed
+	// WriteReplicated is WriteConfirmed that also waits for the record to
+	// show up, intact, on the replica configured with WithReplica.
+	WriteReplicated
+)
+
+func (c WriteConcern) String() string {
+	switch c {
+	case WriteFast:
+		return "fast"
+	case WriteConfirmed:
+		return "confirmed"
+	case WriteReplicated:
+		return "replicated"
+	default:
+		return fmt.Sprintf("WriteConcern(%d)", int(c))
+	}
+}
+
+// AppendWithConcern appends data and verifies it according to concern.
+// WriteReplicated polls the replica until the record arrives, since
+// replication is asynchronous, so ctx should carry a deadline. If the record
+// was uploaded but could not be verified, its offset is returned along with
+// an error wrapping ErrWriteNotConfirmed: the record is in the log, possibly
+// corrupt or not yet replicated, and appending it again would duplicate it.
+func (w *S3WAL) AppendWithConcern(ctx context.Context, data []byte, concern WriteConcern) (uint64, error) {
+	if concern == WriteReplicated && w.replica == nil {
+		return 0, fmt.Errorf("write concern %s requires WithReplica", concern)
+	}
+	offset, err := w.Append(ctx, data)
+	if err != nil || concern == WriteFast {
+		return offset, err
+	}
+	if err := w.readBack(ctx, offset, data); err != nil {
+		return offset, fmt.Errorf("%w: %s: %w", ErrWriteNotConfirmed, concern, err)
+	}
+	if concern == WriteReplicated {
+		if err := w.awaitReplica(ctx, offset, data); err != nil {
+			return offset, fmt.Errorf("%w: %s: %w", ErrWriteNotConfirmed, concern, err)
+		}
+	}
+	return offset, nil
+}
+
+// awaitReplica waits until the replica holds the record at offset and checks
+// that it matches data.
+func (w *S3WAL) awaitReplica(ctx context.Context, offset uint64, data []byte) error {
+	for {
+		err := w.replica.readBack(ctx, offset, data)
+		var nsk *types.NoSuchKey
+		if !errors.As(err, &nsk) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("record not replicated: %w", ctx.Err())
+		case <-time.After(defaultPollInterval):
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package s3log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAppendWithConcern(t *testing.T) {
+	base, cleanup := getWAL(t)
+	defer cleanup()
+	replica, cleanupReplica := getWAL(t)
+	defer cleanupReplica()
+	ctx := context.Background()
+
+	defer func(d time.Duration) { defaultPollInterval = d }(defaultPollInterval)
+	defaultPollInterval = 10 * time.Millisecond
+
+	client := &corruptingClient{S3Client: setupMinioClient()}
+	wal := NewS3WAL(client, base.bucketName, base.prefix)
+	if _, err := wal.AppendWithConcern(ctx, []byte("r"), WriteReplicated); err == nil {
+		t.Error("expected WriteReplicated to require a replica")
+	}
+	for i, concern := range []WriteConcern{WriteFast, WriteConfirmed} {
+		offset, err := wal.AppendWithConcern(ctx, []byte(concern.String()), concern)
+		if err != nil || offset != uint64(i+1) {
+			t.Errorf("%s: expected offset %d, got %d (%v)", concern, i+1, offset, err)
+		}
+	}
+
+	client.corrupt = true
+	if offset, err := wal.AppendWithConcern(ctx, []byte("fast"), WriteFast); err != nil || offset != 3 {
+		t.Errorf("expected a fast write to skip verification, got %d (%v)", offset, err)
+	}
+	offset, err := wal.AppendWithConcern(ctx, []byte("confirmed"), WriteConfirmed)
+	if !errors.Is(err, ErrWriteNotConfirmed) || !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrWriteNotConfirmed for a corrupted upload, got %v", err)
+	}
+	if offset != 4 {
+		t.Errorf("expected the offset of the stored record, got %d", offset)
+	}
+	client.corrupt = false
+
+	WithReplica(replica.client, replica.bucketName, replica.prefix)(wal)
+	go func() {
+		// replicate offset 5 after a while
+		time.Sleep(50 * time.Millisecond)
+		replica.length = 4
+		if _, err := replica.Append(ctx, []byte("replicated")); err != nil {
+			t.Errorf("failed to replicate: %v", err)
+		}
+	}()
+	if offset, err := wal.AppendWithConcern(ctx, []byte("replicated"), WriteReplicated); err != nil || offset != 5 {
+		t.Errorf("expected a replicated write, got %d (%v)", offset, err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := wal.AppendWithConcern(timeout, []byte("never replicated"), WriteReplicated); !errors.Is(err, ErrWriteNotConfirmed) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected ErrWriteNotConfirmed once the deadline passes, got %v", err)
+	}
+}